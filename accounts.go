@@ -0,0 +1,664 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"log/slog"
+	"maps"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pandorasNox/lettr/pkg/middleware"
+)
+
+var (
+	ErrInvalidEmail      = errors.New("invalid email address")
+	ErrInvalidLoginToken = errors.New("invalid or expired login token")
+)
+
+// ACCOUNT_COOKIE_NAME keeps a browser logged in, other than the session it
+// survives SESSION_MAX_AGE_IN_SECONDS.
+const ACCOUNT_COOKIE_NAME = "account"
+const ACCOUNT_LOGIN_MAX_AGE_IN_SECONDS = 30 * 24 * 60 * 60
+
+const (
+	MAGIC_LINK_TTL       = 15 * time.Minute
+	ACCOUNT_STORE_FILE   = "accounts.json"
+	MAX_PENDING_LOGINS   = 1000 // magic links waiting to be used, the oldest get dropped
+	accountTokenByteSize = 32
+)
+
+// mailer sends the magic links. The implementations are meant for local and
+// small setups, a real mail service can be plugged in by implementing Send.
+type mailer interface {
+	Send(to string, subject string, body string) error
+}
+
+// consoleMailer writes the mails to w, e.g. the server log.
+type consoleMailer struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (cm *consoleMailer) Send(to string, subject string, body string) error {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	_, err := fmt.Fprintf(cm.w, "To: %s\nSubject: %s\n\n%s\n", to, subject, body)
+	return err
+}
+
+// fileMailer writes every mail as its own .eml file into dir.
+type fileMailer struct {
+	dir string
+}
+
+func (fm fileMailer) Send(to string, subject string, body string) error {
+	name := fmt.Sprintf("%s-%s.eml", time.Now().UTC().Format("20060102T150405"), uuid.NewString())
+	content := fmt.Sprintf("To: %s\r\nSubject: %s\r\nContent-Type: text/plain; charset=utf-8\r\n\r\n%s\r\n", to, subject, body)
+
+	if err := writeFileAtomic(fm.dir, name, []byte(content)); err != nil {
+		return fmt.Errorf("fileMailer send failed: %s", err)
+	}
+
+	return nil
+}
+
+func newMailer(kind string, dir string) (mailer, error) {
+	switch kind {
+	case "", "console":
+		return &consoleMailer{w: os.Stdout}, nil
+	case "file":
+		if dir == "" {
+			return nil, fmt.Errorf("file mailer requires MAIL_DIR")
+		}
+		return fileMailer{dir: dir}, nil
+	default:
+		return nil, fmt.Errorf("unknown mailer: '%s'", kind)
+	}
+}
+
+// publicBaseURL is scheme and host of links leaving the page, like magic
+// links and puzzle links. They are never built from request headers, a forged
+// Host would let anyone send working login links pointing elsewhere. Only the
+// console mailer (local development) gets along without PUBLIC_BASE_URL.
+func publicBaseURL(e env) (string, error) {
+	if e.publicBaseURL == "" {
+		if e.mailer != "" && e.mailer != "console" {
+			return "", fmt.Errorf("PUBLIC_BASE_URL is required by mailer '%s'", e.mailer)
+		}
+
+		return "http://localhost:" + e.port, nil
+	}
+
+	u, err := url.Parse(e.publicBaseURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || strings.Trim(u.Path, "/") != "" || u.RawQuery != "" || u.Fragment != "" {
+		return "", fmt.Errorf("PUBLIC_BASE_URL must be scheme and host only, e.g. 'https://lettr.example', got '%s'", e.publicBaseURL)
+	}
+
+	return u.Scheme + "://" + u.Host, nil
+}
+
+// account is the optional login of a player. History and statistics of
+// logged in sessions follow the account across devices.
+type account struct {
+	ID        string       `json:"id"`
+	Email     string       `json:"email"`
+	PlayerID  string       `json:"playerId"` // leaderboard identity, see PLAYER_COOKIE_NAME
+	CreatedAt time.Time    `json:"createdAt"`
+	UpdatedAt time.Time    `json:"updatedAt"`
+	History   []gameRecord `json:"history"`
+	Stats     statistics   `json:"stats"`
+}
+
+type accountLogin struct {
+	AccountID string    `json:"accountId"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+type pendingLogin struct {
+	email     string
+	expiresAt time.Time
+}
+
+type accountsState struct {
+	Accounts map[string]account      `json:"accounts"`
+	Logins   map[string]accountLogin `json:"logins"` // token hash -> login
+}
+
+// accounts keeps the accounts and their logins. Tokens are only stored as
+// hashes. With a dir the state is written to ACCOUNT_STORE_FILE on every
+// change, magic links always live in memory only.
+type accounts struct {
+	mu      sync.Mutex
+	file    jsonFile
+	state   accountsState
+	pending map[string]pendingLogin // token hash -> login waiting for its magic link
+}
+
+func newAccounts(dir string) (*accounts, error) {
+	accs := &accounts{
+		file: jsonFile{dir, ACCOUNT_STORE_FILE},
+		state: accountsState{
+			Accounts: make(map[string]account),
+			Logins:   make(map[string]accountLogin),
+		},
+		pending: make(map[string]pendingLogin),
+	}
+	if err := accs.file.Load(&accs.state); err != nil {
+		return nil, fmt.Errorf("accounts load failed: %s", err)
+	}
+
+	return accs, nil
+}
+
+// save writes the state to disk, see jsonFile. The caller must hold mu.
+func (accs *accounts) save() error {
+	if err := accs.file.Save(accs.state); err != nil {
+		return fmt.Errorf("accounts save failed: %s", err)
+	}
+
+	return nil
+}
+
+func newAccountToken() (token string, hash string, err error) {
+	b := make([]byte, accountTokenByteSize)
+	if _, err := io.ReadFull(rand.Reader, b); err != nil {
+		return "", "", fmt.Errorf("failed generating token: %s", err)
+	}
+
+	token = base64.RawURLEncoding.EncodeToString(b)
+	return token, hashAccountToken(token), nil
+}
+
+func hashAccountToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func cleanEmail(email string) (string, error) {
+	addr, err := mail.ParseAddress(strings.TrimSpace(email))
+	if err != nil || addr.Name != "" {
+		return "", ErrInvalidEmail
+	}
+
+	return strings.ToLower(addr.Address), nil
+}
+
+// RequestLogin hands out the token of a magic link for email. Unknown email
+// addresses get their account once the link is used.
+func (accs *accounts) RequestLogin(email string, now time.Time) (string, error) {
+	email, err := cleanEmail(email)
+	if err != nil {
+		return "", err
+	}
+
+	token, hash, err := newAccountToken()
+	if err != nil {
+		return "", err
+	}
+
+	accs.mu.Lock()
+	defer accs.mu.Unlock()
+
+	for h, pl := range accs.pending {
+		if now.After(pl.expiresAt) {
+			delete(accs.pending, h)
+		}
+	}
+	if len(accs.pending) >= MAX_PENDING_LOGINS {
+		oldest := ""
+		for h, pl := range accs.pending {
+			if oldest == "" || pl.expiresAt.Before(accs.pending[oldest].expiresAt) {
+				oldest = h
+			}
+		}
+		delete(accs.pending, oldest)
+	}
+
+	accs.pending[hash] = pendingLogin{email: email, expiresAt: now.Add(MAGIC_LINK_TTL)}
+
+	return token, nil
+}
+
+// VerifyLogin uses up the token of a magic link. The account of its email is
+// created if needed, the returned token keeps the browser logged in.
+func (accs *accounts) VerifyLogin(token string, now time.Time) (account, string, error) {
+	accs.mu.Lock()
+	defer accs.mu.Unlock()
+
+	hash := hashAccountToken(token)
+	pl, ok := accs.pending[hash]
+	delete(accs.pending, hash)
+	if !ok || now.After(pl.expiresAt) {
+		return account{}, "", ErrInvalidLoginToken
+	}
+
+	acc, ok := accs.byEmail(pl.email)
+	if !ok {
+		acc = account{ID: uuid.NewString(), Email: pl.email, CreatedAt: now, History: []gameRecord{}}
+		accs.state.Accounts[acc.ID] = acc
+	}
+
+	loginToken, loginHash, err := newAccountToken()
+	if err != nil {
+		return account{}, "", err
+	}
+
+	for h, l := range accs.state.Logins {
+		if now.After(l.ExpiresAt) {
+			delete(accs.state.Logins, h)
+		}
+	}
+	accs.state.Logins[loginHash] = accountLogin{AccountID: acc.ID, ExpiresAt: now.Add(ACCOUNT_LOGIN_MAX_AGE_IN_SECONDS * time.Second)}
+
+	return acc, loginToken, accs.save()
+}
+
+// byEmail looks up an account. The caller must hold mu.
+func (accs *accounts) byEmail(email string) (account, bool) {
+	for _, acc := range accs.state.Accounts {
+		if acc.Email == email {
+			return acc, true
+		}
+	}
+
+	return account{}, false
+}
+
+// LoggedIn returns the account of a login token.
+func (accs *accounts) LoggedIn(token string, now time.Time) (account, bool) {
+	accs.mu.Lock()
+	defer accs.mu.Unlock()
+
+	l, ok := accs.state.Logins[hashAccountToken(token)]
+	if !ok || now.After(l.ExpiresAt) {
+		return account{}, false
+	}
+
+	acc, ok := accs.state.Accounts[l.AccountID]
+	return acc, ok
+}
+
+func (accs *accounts) Logout(token string) error {
+	accs.mu.Lock()
+	defer accs.mu.Unlock()
+
+	delete(accs.state.Logins, hashAccountToken(token))
+
+	return accs.save()
+}
+
+func (accs *accounts) Get(id string) (account, bool) {
+	accs.mu.Lock()
+	defer accs.mu.Unlock()
+
+	acc, ok := accs.state.Accounts[id]
+	return acc, ok
+}
+
+// SetPlayerID links the leaderboard identity once, later logins adopt it.
+func (accs *accounts) SetPlayerID(id string, playerID string) (account, error) {
+	accs.mu.Lock()
+	defer accs.mu.Unlock()
+
+	acc, ok := accs.state.Accounts[id]
+	if !ok || acc.PlayerID != "" {
+		return acc, nil
+	}
+
+	acc.PlayerID = playerID
+	accs.state.Accounts[id] = acc
+
+	return acc, accs.save()
+}
+
+// Push merges the history of the session into its account and takes over
+// its statistics. Nothing is written if nothing changed.
+func (accs *accounts) Push(s session, now time.Time) (account, error) {
+	accs.mu.Lock()
+	defer accs.mu.Unlock()
+
+	acc, ok := accs.state.Accounts[s.accountID]
+	if !ok {
+		return account{}, ErrInvalidLoginToken
+	}
+
+	history := mergeHistory(acc.History, s.history)
+	if len(history) == len(acc.History) && lastRecordID(history) == lastRecordID(acc.History) && s.stats.equal(acc.Stats) {
+		return acc, nil
+	}
+
+	acc.History = history
+	acc.Stats = s.stats
+	acc.UpdatedAt = now
+	accs.state.Accounts[acc.ID] = acc
+
+	return acc, accs.save()
+}
+
+// claim attaches the session to acc. The anonymous history is kept, the
+// statistics of both add up.
+func (s *session) claim(acc account) {
+	s.accountID = acc.ID
+	s.history = mergeHistory(acc.History, s.history)
+	s.stats = acc.Stats.merge(s.stats)
+	s.accountSyncedAt = time.Time{}
+}
+
+// pull takes over history and statistics changed on other devices.
+func (s *session) pull(acc account) {
+	if !acc.UpdatedAt.After(s.accountSyncedAt) {
+		return
+	}
+
+	s.history = mergeHistory(acc.History, s.history)
+	s.stats = acc.Stats
+	s.accountSyncedAt = acc.UpdatedAt
+}
+
+// logout detaches the session from its account, nothing of the account stays behind.
+func (s *session) logout() {
+	s.accountID = ""
+	s.accountSyncedAt = time.Time{}
+	s.history = nil
+	s.stats = statistics{}
+}
+
+// mergeHistory joins two histories by record id, oldest first and bounded by MAX_HISTORY.
+func mergeHistory(a []gameRecord, b []gameRecord) []gameRecord {
+	merged := slices.Clone(a)
+	for _, gr := range b {
+		if !slices.ContainsFunc(merged, func(m gameRecord) bool { return m.ID == gr.ID }) {
+			merged = append(merged, gr)
+		}
+	}
+
+	slices.SortStableFunc(merged, func(x, y gameRecord) int {
+		return x.FinishedAt.Compare(y.FinishedAt)
+	})
+	if len(merged) > MAX_HISTORY {
+		merged = merged[len(merged)-MAX_HISTORY:]
+	}
+
+	return merged
+}
+
+func lastRecordID(h []gameRecord) string {
+	if len(h) == 0 {
+		return ""
+	}
+
+	return h[len(h)-1].ID
+}
+
+func (st statistics) equal(o statistics) bool {
+	return st.TimedPlayed == o.TimedPlayed &&
+		st.TimedSolved == o.TimedSolved &&
+		st.TimedFastest == o.TimedFastest &&
+		st.SpeedrunRuns == o.SpeedrunRuns &&
+		maps.Equal(st.SpeedrunBest, o.SpeedrunBest)
+}
+
+func (st statistics) merge(o statistics) statistics {
+	merged := statistics{
+		TimedPlayed:  st.TimedPlayed + o.TimedPlayed,
+		TimedSolved:  st.TimedSolved + o.TimedSolved,
+		TimedFastest: st.TimedFastest,
+		SpeedrunRuns: st.SpeedrunRuns + o.SpeedrunRuns,
+		SpeedrunBest: maps.Clone(st.SpeedrunBest),
+	}
+
+	if merged.TimedFastest == 0 || (o.TimedFastest != 0 && o.TimedFastest < merged.TimedFastest) {
+		merged.TimedFastest = o.TimedFastest
+	}
+
+	for minutes, best := range o.SpeedrunBest {
+		if merged.SpeedrunBest == nil {
+			merged.SpeedrunBest = make(map[int]int)
+		}
+		merged.SpeedrunBest[minutes] = max(merged.SpeedrunBest[minutes], best)
+	}
+
+	return merged
+}
+
+func accountCookie(token string, maxAge int) *http.Cookie {
+	return &http.Cookie{
+		Name:     ACCOUNT_COOKIE_NAME,
+		Value:    token,
+		Path:     "/",
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteLaxMode,
+	}
+}
+
+// accountSync keeps logged in sessions and their accounts in step. Before a
+// request the session takes over changes of other devices (and gets logged
+// in again by the account cookie after it expired), afterwards its changes
+// get pushed to the account. Anonymous sessions pass through untouched.
+func accountSync(h http.Handler, ss *sessions, accs *accounts) http.Handler {
+	sessionOf := func(r *http.Request) (session, bool) {
+		c, err := r.Cookie(SESSION_COOKIE_NAME)
+		if err != nil {
+			return session{}, false
+		}
+
+		i := slices.IndexFunc(*ss, func(s session) bool {
+			return s.id == c.Value
+		})
+		if i == -1 {
+			return session{}, false
+		}
+
+		return (*ss)[i], true
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s, ok := sessionOf(r); ok {
+			if s.accountID == "" {
+				if c, err := r.Cookie(ACCOUNT_COOKIE_NAME); err == nil {
+					if acc, ok := accs.LoggedIn(c.Value, time.Now()); ok {
+						s.claim(acc)
+					}
+				}
+			}
+
+			if acc, ok := accs.Get(s.accountID); ok {
+				s.pull(acc)
+				ss.save(r.Context(), s)
+			}
+		}
+
+		h.ServeHTTP(w, r)
+
+		if s, ok := sessionOf(r); ok && s.accountID != "" {
+			acc, err := accs.Push(s, time.Now())
+			if err != nil {
+				slog.ErrorContext(r.Context(), "pushing session to account failed", "err", err)
+				return
+			}
+
+			s.accountSyncedAt = acc.UpdatedAt
+			ss.save(r.Context(), s)
+		}
+	})
+}
+
+type accountData struct {
+	Locale  string
+	Email   string // of the logged in account
+	Sent    string // email a magic link was just sent to
+	Error   string
+	Records int
+}
+
+func (ad accountData) LinkMinutes() int {
+	return int(MAGIC_LINK_TTL.Minutes())
+}
+
+func newAccountData(s session, accs *accounts) accountData {
+	data := accountData{Locale: s.uiLocale, Records: len(s.history)}
+	if acc, ok := accs.Get(s.accountID); ok {
+		data.Email = acc.Email
+	}
+
+	return data
+}
+
+// accountVerifyData is the page behind magic links. It only confirms, mail
+// scanners prefetching the link must not use up the single use token.
+type accountVerifyData struct {
+	Locale      string
+	Token       string
+	FaviconPath string
+	CSPNonce    string
+	CSRFToken   string
+}
+
+// registerAccountRoutes wires the login routes. Magic links point to baseURL,
+// see publicBaseURL. sessionFor resolves the session of a request,
+// saveSession stores changes of it and csrfToken issues the token of the
+// verify page.
+func registerAccountRoutes(
+	mux *http.ServeMux,
+	t *template.Template,
+	accs *accounts,
+	m mailer,
+	baseURL string,
+	sessionFor func(w http.ResponseWriter, r *http.Request) session,
+	saveSession func(s session),
+	csrfToken func(sessionID string) string,
+) {
+	render := func(w http.ResponseWriter, r *http.Request, data accountData, route string) {
+		err := t.ExecuteTemplate(w, "account", data)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "executing template failed", "route", route, "err", err)
+		}
+	}
+
+	mux.HandleFunc("GET /account", func(w http.ResponseWriter, r *http.Request) {
+		s := sessionFor(w, r)
+		render(w, r, newAccountData(s, accs), "/account")
+	})
+
+	mux.HandleFunc("POST /account/login", func(w http.ResponseWriter, r *http.Request) {
+		s := sessionFor(w, r)
+		data := newAccountData(s, accs)
+
+		token, err := accs.RequestLogin(r.FormValue("email"), time.Now())
+		if errors.Is(err, ErrInvalidEmail) {
+			w.WriteHeader(422)
+			data.Error = tr(s.uiLocale, "account.invalid_email")
+			render(w, r, data, "/account/login")
+			return
+		}
+		if err != nil {
+			slog.ErrorContext(r.Context(), "requesting login failed", "err", err)
+			w.WriteHeader(500)
+			return
+		}
+
+		email, _ := cleanEmail(r.FormValue("email"))
+		link := fmt.Sprintf("%s/account/verify?token=%s", baseURL, url.QueryEscape(token))
+		err = m.Send(email, tr(s.uiLocale, "account.mail.subject"), tr(s.uiLocale, "account.mail.body", link, data.LinkMinutes()))
+		if err != nil {
+			slog.ErrorContext(r.Context(), "sending magic link failed", "err", err)
+			w.WriteHeader(500)
+			w.Write([]byte(tr(s.uiLocale, "account.mail_failed")))
+			return
+		}
+
+		data.Sent = email
+		render(w, r, data, "/account/login")
+	})
+
+	mux.HandleFunc("GET /account/verify", func(w http.ResponseWriter, r *http.Request) {
+		s := sessionFor(w, r)
+
+		data := accountVerifyData{
+			Locale:      s.uiLocale,
+			Token:       r.FormValue("token"),
+			FaviconPath: FaviconPath,
+			CSPNonce:    middleware.CSPNonce(r.Context()),
+			CSRFToken:   csrfToken(s.id),
+		}
+		err := t.ExecuteTemplate(w, "account-verify.html.tmpl", data)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "executing template failed", "route", "/account/verify", "err", err)
+		}
+	})
+
+	mux.HandleFunc("POST /account/verify", func(w http.ResponseWriter, r *http.Request) {
+		s := sessionFor(w, r)
+
+		acc, loginToken, err := accs.VerifyLogin(r.FormValue("token"), time.Now())
+		if errors.Is(err, ErrInvalidLoginToken) {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(tr(s.uiLocale, "account.invalid_link")))
+			return
+		}
+		if err != nil {
+			slog.ErrorContext(r.Context(), "verifying login failed", "err", err)
+			w.WriteHeader(500)
+			return
+		}
+
+		// the leaderboard identity follows the account, the first device brings it in
+		if acc.PlayerID == "" {
+			acc, err = accs.SetPlayerID(acc.ID, playerID(w, r, true))
+			if err != nil {
+				slog.ErrorContext(r.Context(), "linking player to account failed", "err", err)
+			}
+		}
+		if acc.PlayerID != "" {
+			http.SetCookie(w, playerCookie(acc.PlayerID))
+		}
+
+		s.claim(acc)
+		saveSession(s)
+
+		http.SetCookie(w, accountCookie(loginToken, ACCOUNT_LOGIN_MAX_AGE_IN_SECONDS))
+		if r.Header.Get("HX-Request") == "true" {
+			w.Header().Set("HX-Redirect", "/")
+			w.WriteHeader(204)
+			return
+		}
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+	})
+
+	mux.HandleFunc("POST /account/logout", func(w http.ResponseWriter, r *http.Request) {
+		s := sessionFor(w, r)
+
+		if c, err := r.Cookie(ACCOUNT_COOKIE_NAME); err == nil {
+			if err := accs.Logout(c.Value); err != nil {
+				slog.ErrorContext(r.Context(), "logging out failed", "err", err)
+			}
+		}
+
+		s.logout()
+		saveSession(s)
+
+		// the device leaves the leaderboard identity of the account behind as well
+		http.SetCookie(w, accountCookie("", -1))
+		c := playerCookie("")
+		c.MaxAge = -1
+		http.SetCookie(w, c)
+		render(w, r, newAccountData(s, accs), "/account/logout")
+	})
+}
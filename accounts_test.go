@@ -0,0 +1,243 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_accounts_login(t *testing.T) {
+	dir := t.TempDir()
+	accs, _ := newAccounts(dir)
+	now := time.Now()
+
+	if _, err := accs.RequestLogin("not an address", now); !errors.Is(err, ErrInvalidEmail) {
+		t.Errorf("RequestLogin() error = %v, want %v", err, ErrInvalidEmail)
+	}
+
+	token, err := accs.RequestLogin(" Me@Example.com ", now)
+	if err != nil {
+		t.Fatalf("RequestLogin() failed: %s", err)
+	}
+
+	acc, login, err := accs.VerifyLogin(token, now.Add(time.Minute))
+	if err != nil || acc.Email != "me@example.com" || login == "" {
+		t.Fatalf("VerifyLogin() = %v, %s, %v", acc, login, err)
+	}
+
+	if _, _, err := accs.VerifyLogin(token, now.Add(time.Minute)); !errors.Is(err, ErrInvalidLoginToken) {
+		t.Errorf("VerifyLogin() must not accept a magic link twice, error = %v", err)
+	}
+
+	expired, _ := accs.RequestLogin("me@example.com", now)
+	if _, _, err := accs.VerifyLogin(expired, now.Add(MAGIC_LINK_TTL+time.Second)); !errors.Is(err, ErrInvalidLoginToken) {
+		t.Errorf("VerifyLogin() must not accept expired magic links, error = %v", err)
+	}
+
+	again, _ := accs.RequestLogin("ME@example.com", now)
+	if same, _, _ := accs.VerifyLogin(again, now); same.ID != acc.ID {
+		t.Errorf("VerifyLogin() should log into the existing account %s, got %s", acc.ID, same.ID)
+	}
+
+	reloaded, err := newAccounts(dir)
+	if err != nil {
+		t.Fatalf("newAccounts() reload failed: %s", err)
+	}
+	if got, ok := reloaded.LoggedIn(login, now); !ok || got.ID != acc.ID {
+		t.Errorf("LoggedIn() after reload = %v, %v", got, ok)
+	}
+	if _, ok := reloaded.LoggedIn(login, now.Add(ACCOUNT_LOGIN_MAX_AGE_IN_SECONDS*time.Second+2*time.Minute)); ok {
+		t.Errorf("LoggedIn() must not accept expired logins")
+	}
+
+	b, _ := os.ReadFile(dir + "/" + ACCOUNT_STORE_FILE)
+	if strings.Contains(string(b), login) {
+		t.Errorf("the account store must only contain hashed tokens")
+	}
+
+	reloaded.Logout(login)
+	if _, ok := reloaded.LoggedIn(login, now); ok {
+		t.Errorf("LoggedIn() after Logout() should fail")
+	}
+}
+
+func Test_publicBaseURL(t *testing.T) {
+	tests := []struct {
+		name    string
+		e       env
+		want    string
+		wantErr bool
+	}{
+		{"console mailer defaults to localhost", env{port: "9026"}, "http://localhost:9026", false},
+		{"configured", env{mailer: "file", publicBaseURL: "https://lettr.example/"}, "https://lettr.example", false},
+		{"required by real mailers", env{mailer: "file"}, "", true},
+		{"path", env{publicBaseURL: "https://lettr.example/app"}, "", true},
+		{"no scheme", env{publicBaseURL: "lettr.example"}, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := publicBaseURL(tt.e)
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("publicBaseURL() = %s, %v, want %s, wantErr %v", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_accountRoutes_verify(t *testing.T) {
+	accs, _ := newAccounts("")
+	var mail bytes.Buffer
+	tmpl := template.Must(template.New("index.html.tmpl").Funcs(funcMap).Funcs(translateFuncs(translations, false)).ParseFS(fs, templateFiles...))
+
+	s := session{id: "sid", uiLocale: "en"}
+	mux := http.NewServeMux()
+	registerAccountRoutes(mux, tmpl, accs, &consoleMailer{w: &mail}, "https://lettr.example", func(w http.ResponseWriter, r *http.Request) session {
+		return s
+	}, func(saved session) { s = saved }, func(sessionID string) string { return "csrf" })
+
+	req := httptest.NewRequest(http.MethodPost, "/account/login", strings.NewReader("email=me@example.com"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Host = "evil.example"
+	mux.ServeHTTP(httptest.NewRecorder(), req)
+
+	_, link, found := strings.Cut(mail.String(), "https://lettr.example/account/verify?token=")
+	if !found {
+		t.Fatalf("magic link must point to the public base url, mail: %s", mail.String())
+	}
+	token, _, _ := strings.Cut(link, "\n")
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/account/verify?token="+token, nil))
+	if w.Code != http.StatusOK || w.Header().Get("Set-Cookie") != "" {
+		t.Errorf("GET /account/verify = %d %v, want a confirm page without logging in", w.Code, w.Header())
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/account/verify", strings.NewReader("token="+token))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	if w.Code != http.StatusSeeOther || s.accountID == "" {
+		t.Errorf("POST /account/verify = %d, accountID %q, want a login", w.Code, s.accountID)
+	}
+}
+
+func Test_mergeHistory(t *testing.T) {
+	at := func(min int) time.Time {
+		return time.Date(2024, 1, 1, 0, min, 0, 0, time.UTC)
+	}
+
+	a := []gameRecord{{ID: "1", FinishedAt: at(1)}, {ID: "3", FinishedAt: at(3)}}
+	b := []gameRecord{{ID: "2", FinishedAt: at(2)}, {ID: "3", FinishedAt: at(3)}}
+
+	got := mergeHistory(a, b)
+	if len(got) != 3 || got[0].ID != "1" || got[1].ID != "2" || got[2].ID != "3" {
+		t.Errorf("mergeHistory() = %v, want records 1, 2, 3", got)
+	}
+
+	long := []gameRecord{}
+	for i := range MAX_HISTORY + 5 {
+		long = append(long, gameRecord{ID: string(rune('a' + i)), FinishedAt: at(10 + i)})
+	}
+	if got := mergeHistory(a, long); len(got) != MAX_HISTORY || got[len(got)-1].ID != long[len(long)-1].ID {
+		t.Errorf("mergeHistory() should keep the newest %d records, got %d", MAX_HISTORY, len(got))
+	}
+}
+
+func Test_statistics_merge(t *testing.T) {
+	a := statistics{TimedPlayed: 2, TimedSolved: 1, TimedFastest: time.Minute, SpeedrunRuns: 1, SpeedrunBest: map[int]int{3: 4}}
+	b := statistics{TimedPlayed: 1, TimedSolved: 1, TimedFastest: 30 * time.Second, SpeedrunRuns: 2, SpeedrunBest: map[int]int{3: 2, 5: 6}}
+
+	got := a.merge(b)
+	want := statistics{TimedPlayed: 3, TimedSolved: 2, TimedFastest: 30 * time.Second, SpeedrunRuns: 3, SpeedrunBest: map[int]int{3: 4, 5: 6}}
+	if !got.equal(want) {
+		t.Errorf("merge() = %+v, want %+v", got, want)
+	}
+	if a.SpeedrunBest[5] != 0 {
+		t.Errorf("merge() must not modify the merged statistics")
+	}
+
+	if got := (statistics{}).merge(a); !got.equal(a) {
+		t.Errorf("merge() into empty statistics = %+v, want %+v", got, a)
+	}
+}
+
+func Test_mailers(t *testing.T) {
+	buf := &bytes.Buffer{}
+	cm := &consoleMailer{w: buf}
+	if err := cm.Send("me@example.com", "subject", "body http://link"); err != nil || !strings.Contains(buf.String(), "http://link") {
+		t.Errorf("consoleMailer.Send() wrote %q, %v", buf.String(), err)
+	}
+
+	dir := t.TempDir()
+	if err := (fileMailer{dir: dir}).Send("me@example.com", "subject", "body"); err != nil {
+		t.Fatalf("fileMailer.Send() failed: %s", err)
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 || !strings.HasSuffix(entries[0].Name(), ".eml") {
+		t.Errorf("fileMailer.Send() should write one .eml file, got %v", entries)
+	}
+
+	if _, err := newMailer("file", ""); err == nil {
+		t.Errorf("newMailer() should require a dir for the file mailer")
+	}
+	if _, err := newMailer("smtp", ""); err == nil {
+		t.Errorf("newMailer() should fail for unknown mailers")
+	}
+}
+
+func Test_accountSync(t *testing.T) {
+	accs, _ := newAccounts("")
+	token, _ := accs.RequestLogin("me@example.com", time.Now())
+	acc, login, _ := accs.VerifyLogin(token, time.Now())
+
+	ss := sessions{
+		{id: "anonymous"},
+		{id: "device-a", accountID: acc.ID},
+		{id: "device-b"},
+	}
+
+	// every request finishes a game
+	h := accountSync(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c, _ := r.Cookie(SESSION_COOKIE_NAME)
+		for i := range ss {
+			if ss[i].id == c.Value {
+				ss[i].recordGame(OUTCOME_SOLVED)
+			}
+		}
+	}), &ss, accs)
+
+	request := func(sessionID string, accountToken string) {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.AddCookie(&http.Cookie{Name: SESSION_COOKIE_NAME, Value: sessionID})
+		if accountToken != "" {
+			r.AddCookie(&http.Cookie{Name: ACCOUNT_COOKIE_NAME, Value: accountToken})
+		}
+		h.ServeHTTP(httptest.NewRecorder(), r)
+	}
+
+	request("anonymous", "")
+	request("device-a", "")
+	if got, _ := accs.Get(acc.ID); len(got.History) != 1 {
+		t.Fatalf("accountSync() should push the history to the account, got %d records", len(got.History))
+	}
+
+	request("device-b", login)
+	if ss[2].accountID != acc.ID || len(ss[2].history) != 2 {
+		t.Errorf("accountSync() should log device b in by its cookie and take over the history, got %d records", len(ss[2].history))
+	}
+
+	request("device-a", "")
+	if len(ss[1].history) != 3 {
+		t.Errorf("accountSync() should pull the games of device b, got %d records", len(ss[1].history))
+	}
+
+	if ss[0].accountID != "" || len(ss[0].history) != 1 {
+		t.Errorf("accountSync() must not touch anonymous sessions, got %+v", ss[0])
+	}
+}
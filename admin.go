@@ -0,0 +1,495 @@
+package main
+
+import (
+	"bufio"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pandorasNox/lettr/pkg/middleware"
+)
+
+// MAX_PENDING_SUGGESTIONS caps WC_SUGGESTED per language until an admin
+// curates them.
+const MAX_PENDING_SUGGESTIONS = 100
+
+var (
+	ErrAlreadyInWordList  = errors.New("already in wordlist")
+	ErrAlreadySuggested   = errors.New("already suggested")
+	ErrTooManySuggestions = errors.New("too many pending suggestions")
+	ErrNotSuggested       = errors.New("not suggested")
+)
+
+// wordListDelta are the curated changes of a collection over the word lists
+// shipped in configs/, so later updates of those still show up.
+type wordListDelta struct {
+	added   map[word]bool
+	removed map[word]bool
+}
+
+// wordListStore persists curated changes on disk. Every collection gets its
+// own file named "<lang>.<collection>.delta.txt", in the format of
+// configs/*.txt (metadata line + one word per line), but every word is
+// prefixed by "+" (added) or "-" (removed).
+type wordListStore struct {
+	mu     sync.Mutex
+	dir    string
+	deltas map[language]map[wordCollection]wordListDelta
+}
+
+func (s *wordListStore) fileName(l language, c wordCollection) string {
+	return fmt.Sprintf("%s.%s.delta.txt", l, c)
+}
+
+// delta returns the delta of a collection, the caller must hold mu.
+func (s *wordListStore) delta(l language, c wordCollection) wordListDelta {
+	if s.deltas == nil {
+		s.deltas = make(map[language]map[wordCollection]wordListDelta)
+	}
+	if _, ok := s.deltas[l]; !ok {
+		s.deltas[l] = make(map[wordCollection]wordListDelta)
+	}
+
+	d, ok := s.deltas[l][c]
+	if !ok {
+		d = wordListDelta{added: make(map[word]bool), removed: make(map[word]bool)}
+		s.deltas[l][c] = d
+	}
+
+	return d
+}
+
+// Load applies the deltas of the store to wdb, which holds the shipped lists.
+func (s *wordListStore) Load(wdb wordDatabase) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return fmt.Errorf("wordListStore load failed reading dir: %s", err)
+	}
+
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+
+		parts := strings.Split(e.Name(), ".")
+		if len(parts) != 4 || parts[2] != "delta" || parts[3] != "txt" {
+			continue
+		}
+
+		l, err := NewLang(parts[0])
+		if err != nil {
+			slog.Warn("wordListStore load skipped file with unknown language", "file", e.Name())
+			continue
+		}
+
+		c, err := NewWordCollection(parts[1])
+		if err != nil {
+			slog.Warn("wordListStore load skipped file with unknown collection", "file", e.Name())
+			continue
+		}
+
+		if err := s.readDelta(e.Name(), l, c); err != nil {
+			return fmt.Errorf("wordListStore load failed: %s", err)
+		}
+
+		d := s.delta(l, c)
+		for w := range d.added {
+			wdb.Add(l, c, w)
+		}
+		for w := range d.removed {
+			wdb.Remove(l, c, w)
+		}
+	}
+
+	return nil
+}
+
+// readDelta reads a file of the store into the delta of its collection, the
+// caller must hold mu. Later lines win, e.g. "-gamer" after "+gamer".
+func (s *wordListStore) readDelta(name string, l language, c wordCollection) error {
+	f, err := os.Open(filepath.Join(s.dir, name))
+	if err != nil {
+		return fmt.Errorf("failed when opening file: %s", err)
+	}
+	defer f.Close()
+
+	n := languages.config(l).normaliser()
+	d := s.delta(l, c)
+
+	scanner := bufio.NewScanner(f)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if line == 1 { // skip first metadata line
+			continue
+		}
+
+		text := scanner.Text()
+		if text == "" {
+			continue
+		}
+
+		op := text[0]
+		if op != '+' && op != '-' {
+			slog.Warn("wordListStore load skipped line without +/- prefix", "file", name, "line", line)
+			continue
+		}
+
+		w, err := n.Word(text[1:])
+		if err != nil {
+			slog.Warn("wordListStore load skipped word", "file", name, "line", line, "err", err)
+			continue
+		}
+
+		if op == '+' {
+			delete(d.removed, w)
+			d.added[w] = true
+		} else {
+			delete(d.added, w)
+			d.removed[w] = true
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed scanning file with: name='%s', err=%s", name, err)
+	}
+
+	return nil
+}
+
+// wordListHeader is the metadata line of every file of the store.
+func wordListHeader() string {
+	return fmt.Sprintf("// curated via lettr admin, last change %s\n", time.Now().UTC().Format(time.RFC3339))
+}
+
+// Record stores that w got added to or removed from a collection, the file
+// of the collection is rewritten, see writeFileAtomic.
+func (s *wordListStore) Record(l language, c wordCollection, w word, added bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	d := s.delta(l, c)
+	w = w.ToLower()
+	if added {
+		delete(d.removed, w)
+		d.added[w] = true
+	} else {
+		delete(d.added, w)
+		d.removed[w] = true
+	}
+
+	lines := make([]string, 0, len(d.added)+len(d.removed))
+	for w := range d.added {
+		lines = append(lines, "+"+w.String())
+	}
+	for w := range d.removed {
+		lines = append(lines, "-"+w.String())
+	}
+	slices.SortFunc(lines, func(a, b string) int {
+		return strings.Compare(a[1:], b[1:])
+	})
+
+	var out strings.Builder
+	out.WriteString(wordListHeader())
+	for _, line := range lines {
+		out.WriteString(line)
+		out.WriteByte('\n')
+	}
+
+	if err := writeFileAtomic(s.dir, s.fileName(l, c), []byte(out.String())); err != nil {
+		return fmt.Errorf("wordListStore record failed: %s", err)
+	}
+
+	return nil
+}
+
+// Append stores that w got added to a collection without rewriting its file,
+// e.g. for player suggestions.
+func (s *wordListStore) Append(l language, c wordCollection, w word) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := os.OpenFile(filepath.Join(s.dir, s.fileName(l, c)), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("wordListStore append failed opening file: %s", err)
+	}
+	defer f.Close()
+
+	fInfo, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("wordListStore append failed obtaining stat: %s", err)
+	}
+
+	line := "+" + w.String() + "\n"
+	if fInfo.Size() == 0 {
+		line = wordListHeader() + line
+	}
+
+	if _, err := f.WriteString(line); err != nil {
+		return fmt.Errorf("wordListStore append failed writing: %s", err)
+	}
+
+	d := s.delta(l, c)
+	delete(d.removed, w.ToLower())
+	d.added[w.ToLower()] = true
+
+	return f.Close()
+}
+
+// wordCurator applies word list changes live to the wordDatabase and persists
+// them in the store, if one is configured.
+type wordCurator struct {
+	wdb   wordDatabase
+	store *wordListStore
+}
+
+type curationAction string
+
+const (
+	CURATE_ACCEPT  curationAction = "accept"
+	CURATE_REJECT  curationAction = "reject"
+	CURATE_PROMOTE curationAction = "promote" // WC_ALL -> WC_COMMON
+	CURATE_DEMOTE  curationAction = "demote"  // WC_COMMON -> WC_ALL
+	CURATE_BAN     curationAction = "ban"
+	CURATE_UNBAN   curationAction = "unban"
+)
+
+// Suggest adds a pending suggestion, the store only appends it. Suggestions
+// are deduplicated and capped by MAX_PENDING_SUGGESTIONS.
+func (wc wordCurator) Suggest(l language, w word) error {
+	if wc.wdb.Exists(l, w) {
+		return ErrAlreadyInWordList
+	}
+
+	if err := wc.wdb.addSuggestion(l, w); err != nil {
+		return err
+	}
+
+	if wc.store == nil {
+		return nil
+	}
+
+	if err := wc.store.Append(l, WC_SUGGESTED, w); err != nil {
+		wc.wdb.Remove(l, WC_SUGGESTED, w)
+		return err
+	}
+
+	return nil
+}
+
+// addSuggestion adds w to WC_SUGGESTED unless it's pending already or there
+// are MAX_PENDING_SUGGESTIONS.
+func (wdb wordDatabase) addSuggestion(l language, w word) error {
+	defer wdb.lock()()
+
+	if _, ok := wdb.db[l]; !ok {
+		wdb.db[l] = make(map[wordCollection]map[word]bool)
+	}
+
+	pending, ok := wdb.db[l][WC_SUGGESTED]
+	if !ok {
+		pending = make(map[word]bool)
+		wdb.db[l][WC_SUGGESTED] = pending
+	}
+
+	if pending[w.ToLower()] {
+		return ErrAlreadySuggested
+	}
+	if len(pending) >= MAX_PENDING_SUGGESTIONS {
+		return ErrTooManySuggestions
+	}
+
+	pending[w.ToLower()] = true
+
+	return nil
+}
+
+func (wc wordCurator) Apply(a curationAction, l language, w word) error {
+	switch a {
+	case CURATE_ACCEPT:
+		if !wc.wdb.Has(l, WC_SUGGESTED, w) {
+			return ErrNotSuggested
+		}
+		if err := wc.remove(l, WC_SUGGESTED, w); err != nil {
+			return err
+		}
+		return wc.add(l, WC_ALL, w)
+	case CURATE_REJECT:
+		return wc.remove(l, WC_SUGGESTED, w)
+	case CURATE_PROMOTE:
+		if !wc.wdb.Exists(l, w) {
+			return ErrNotInWordList
+		}
+		return wc.add(l, WC_COMMON, w)
+	case CURATE_DEMOTE:
+		return wc.remove(l, WC_COMMON, w)
+	case CURATE_BAN:
+		return wc.add(l, WC_BANNED, w)
+	case CURATE_UNBAN:
+		return wc.remove(l, WC_BANNED, w)
+	default:
+		return fmt.Errorf("unknown curation action: '%s'", a)
+	}
+}
+
+// add puts w into a collection, live and in the store if one is configured.
+func (wc wordCurator) add(l language, c wordCollection, w word) error {
+	wc.wdb.Add(l, c, w)
+	if wc.store == nil {
+		return nil
+	}
+
+	return wc.store.Record(l, c, w, true)
+}
+
+// remove takes w out of a collection, live and in the store if one is configured.
+func (wc wordCurator) remove(l language, c wordCollection, w word) error {
+	wc.wdb.Remove(l, c, w)
+	if wc.store == nil {
+		return nil
+	}
+
+	return wc.store.Record(l, c, w, false)
+}
+
+type adminWordEntry struct {
+	Word      word
+	IsCommon  bool
+	IsBanned  bool
+	IsPending bool
+}
+
+type adminData struct {
+	Language    language
+	Languages   []language
+	Query       string
+	Results     []adminWordEntry
+	Suggestions []word
+	Banned      []word
+	Message     string
+	Revision    string
+	FaviconPath string
+	CSPNonce    string
+	CSRFToken   string
+}
+
+func (wc wordCurator) entries(l language, ws []word) []adminWordEntry {
+	out := make([]adminWordEntry, 0, len(ws))
+	for _, w := range ws {
+		out = append(out, adminWordEntry{
+			Word:      w,
+			IsCommon:  wc.wdb.Has(l, WC_COMMON, w),
+			IsBanned:  wc.wdb.Has(l, WC_BANNED, w),
+			IsPending: wc.wdb.Has(l, WC_SUGGESTED, w),
+		})
+	}
+
+	return out
+}
+
+func (wc wordCurator) adminData(l language, query string) adminData {
+	return adminData{
+		Language:    l,
+		Languages:   languages.codes(),
+		Query:       query,
+		Results:     wc.entries(l, wc.wdb.Search(l, query, 50)),
+		Suggestions: wc.wdb.Words(l, WC_SUGGESTED),
+		Banned:      wc.wdb.Words(l, WC_BANNED),
+		Revision:    Revision,
+		FaviconPath: FaviconPath,
+	}
+}
+
+// withBasicAuth protects admin routes. Without a configured password the
+// admin area is disabled entirely.
+func withBasicAuth(user string, password string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if password == "" {
+			http.NotFound(w, r)
+			return
+		}
+
+		u, p, ok := r.BasicAuth()
+		validUser := subtle.ConstantTimeCompare([]byte(u), []byte(user)) == 1
+		validPassword := subtle.ConstantTimeCompare([]byte(p), []byte(password)) == 1
+		if !ok || !validUser || !validPassword {
+			w.Header().Set("WWW-Authenticate", `Basic realm="lettr admin", charset="UTF-8"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+// registerAdminRoutes wires the word curation routes. csrfTokenFor issues the
+// csrf token for the session of a request, the admin page posts with it.
+func registerAdminRoutes(mux *http.ServeMux, t *template.Template, envCfg env, curator wordCurator, csrfTokenFor func(w http.ResponseWriter, r *http.Request) string) {
+	auth := func(h http.HandlerFunc) http.HandlerFunc {
+		return withBasicAuth(envCfg.adminUser, envCfg.adminPassword, h)
+	}
+
+	langFromReq := func(r *http.Request) language {
+		l, _ := NewLang(r.FormValue("lang"))
+		return l
+	}
+
+	mux.HandleFunc("GET /admin", auth(func(w http.ResponseWriter, r *http.Request) {
+		data := curator.adminData(langFromReq(r), r.FormValue("q"))
+		data.CSPNonce = middleware.CSPNonce(r.Context())
+		data.CSRFToken = csrfTokenFor(w, r)
+
+		err := t.ExecuteTemplate(w, "admin.html.tmpl", data)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "executing template failed", "route", "/admin", "err", err)
+		}
+	}))
+
+	mux.HandleFunc("GET /admin/search", auth(func(w http.ResponseWriter, r *http.Request) {
+		data := curator.adminData(langFromReq(r), r.FormValue("q"))
+
+		err := t.ExecuteTemplate(w, "admin-curation", data)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "executing template failed", "route", "/admin/search", "err", err)
+		}
+	}))
+
+	mux.HandleFunc("POST /admin/words", auth(func(w http.ResponseWriter, r *http.Request) {
+		l := langFromReq(r)
+
+		wo, err := languages.config(l).normaliser().Word(r.FormValue("word"))
+		if err != nil {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			w.Write([]byte(fmt.Sprintf("invalid word: %s", err)))
+			return
+		}
+
+		action := curationAction(r.FormValue("action"))
+		data := curator.adminData(l, r.FormValue("q"))
+
+		err = curator.Apply(action, l, wo)
+		if err != nil {
+			slog.WarnContext(r.Context(), "admin curation failed", "action", action, "lang", l, "err", err)
+			data.Message = fmt.Sprintf("%s '%s' failed: %s", action, wo, err)
+		} else {
+			data = curator.adminData(l, r.FormValue("q"))
+			data.Message = fmt.Sprintf("%s '%s' done", action, wo)
+		}
+
+		err = t.ExecuteTemplate(w, "admin-curation", data)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "executing template failed", "route", "/admin/words", "err", err)
+		}
+	}))
+}
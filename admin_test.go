@@ -0,0 +1,241 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func newTestWordDatabase() wordDatabase {
+	wdb := wordDatabase{}
+	wdb.Init(nil, nil)
+	wdb.Replace(LANG_EN, WC_ALL, []word{{'r', 'o', 'a', 't', 'e'}, {'m', 'a', 't', 'c', 'h'}})
+	wdb.Replace(LANG_EN, WC_COMMON, []word{{'r', 'o', 'a', 't', 'e'}})
+
+	return wdb
+}
+
+func Test_wordCurator_Apply(t *testing.T) {
+	gamer := word{'g', 'a', 'm', 'e', 'r'}
+	match := word{'m', 'a', 't', 'c', 'h'}
+
+	tests := []struct {
+		name       string
+		prepare    func(wc wordCurator)
+		action     curationAction
+		word       word
+		wantErr    bool
+		collection wordCollection
+		want       []word
+	}{
+		{
+			name:       "accept moves suggestion into wc_all",
+			prepare:    func(wc wordCurator) { wc.Suggest(LANG_EN, gamer) },
+			action:     CURATE_ACCEPT,
+			word:       gamer,
+			collection: WC_ALL,
+			want:       []word{gamer, match, {'r', 'o', 'a', 't', 'e'}},
+		},
+		{
+			name:       "accept of word not suggested fails",
+			prepare:    func(wc wordCurator) {},
+			action:     CURATE_ACCEPT,
+			word:       gamer,
+			wantErr:    true,
+			collection: WC_ALL,
+			want:       []word{match, {'r', 'o', 'a', 't', 'e'}},
+		},
+		{
+			name:       "reject drops suggestion",
+			prepare:    func(wc wordCurator) { wc.Suggest(LANG_EN, gamer) },
+			action:     CURATE_REJECT,
+			word:       gamer,
+			collection: WC_SUGGESTED,
+			want:       []word{},
+		},
+		{
+			name:       "promote adds to wc_common",
+			prepare:    func(wc wordCurator) {},
+			action:     CURATE_PROMOTE,
+			word:       match,
+			collection: WC_COMMON,
+			want:       []word{match, {'r', 'o', 'a', 't', 'e'}},
+		},
+		{
+			name:       "promote of unknown word fails",
+			prepare:    func(wc wordCurator) {},
+			action:     CURATE_PROMOTE,
+			word:       gamer,
+			wantErr:    true,
+			collection: WC_COMMON,
+			want:       []word{{'r', 'o', 'a', 't', 'e'}},
+		},
+		{
+			name:       "demote removes from wc_common",
+			prepare:    func(wc wordCurator) {},
+			action:     CURATE_DEMOTE,
+			word:       word{'r', 'o', 'a', 't', 'e'},
+			collection: WC_COMMON,
+			want:       []word{},
+		},
+		{
+			name:       "ban adds to wc_banned",
+			prepare:    func(wc wordCurator) {},
+			action:     CURATE_BAN,
+			word:       match,
+			collection: WC_BANNED,
+			want:       []word{match},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wc := wordCurator{wdb: newTestWordDatabase(), store: &wordListStore{dir: t.TempDir()}}
+			tt.prepare(wc)
+
+			err := wc.Apply(tt.action, LANG_EN, tt.word)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("wordCurator.Apply() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if got := wc.wdb.Words(LANG_EN, tt.collection); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("wordCurator.Apply() collection = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_wordListStore_roundtrip(t *testing.T) {
+	store := &wordListStore{dir: t.TempDir()}
+	wc := wordCurator{wdb: newTestWordDatabase(), store: store}
+
+	gamer := word{'g', 'a', 'm', 'e', 'r'}
+	if err := wc.Suggest(LANG_EN, gamer); err != nil {
+		t.Fatalf("Suggest() failed: %s", err)
+	}
+	if err := wc.Apply(CURATE_ACCEPT, LANG_EN, gamer); err != nil {
+		t.Fatalf("Apply() failed: %s", err)
+	}
+	if err := wc.Apply(CURATE_BAN, LANG_EN, gamer); err != nil {
+		t.Fatalf("Apply() failed: %s", err)
+	}
+
+	reloaded := newTestWordDatabase()
+	if err := store.Load(reloaded); err != nil {
+		t.Fatalf("Load() failed: %s", err)
+	}
+
+	if !reloaded.Exists(LANG_EN, gamer) {
+		t.Errorf("reloaded db misses accepted word '%s'", gamer)
+	}
+	if !reloaded.Has(LANG_EN, WC_BANNED, gamer) {
+		t.Errorf("reloaded db misses banned word '%s'", gamer)
+	}
+	if got := reloaded.Words(LANG_EN, WC_SUGGESTED); len(got) != 0 {
+		t.Errorf("reloaded db still has pending suggestions: %v", got)
+	}
+}
+
+func Test_wordListStore_keepsShippedUpdates(t *testing.T) {
+	dir := t.TempDir()
+	wc := wordCurator{wdb: newTestWordDatabase(), store: &wordListStore{dir: dir}}
+
+	roate := word{'r', 'o', 'a', 't', 'e'}
+	match := word{'m', 'a', 't', 'c', 'h'}
+	if err := wc.Apply(CURATE_DEMOTE, LANG_EN, roate); err != nil {
+		t.Fatalf("Apply() failed: %s", err)
+	}
+	if err := wc.Apply(CURATE_PROMOTE, LANG_EN, match); err != nil {
+		t.Fatalf("Apply() failed: %s", err)
+	}
+
+	// a later release ships another common word
+	house := word{'h', 'o', 'u', 's', 'e'}
+	shipped := newTestWordDatabase()
+	shipped.Add(LANG_EN, WC_ALL, house)
+	shipped.Add(LANG_EN, WC_COMMON, house)
+
+	if err := (&wordListStore{dir: dir}).Load(shipped); err != nil {
+		t.Fatalf("Load() failed: %s", err)
+	}
+	if got, want := shipped.Words(LANG_EN, WC_COMMON), []word{house, match}; !reflect.DeepEqual(got, want) {
+		t.Errorf("reloaded common words = %v, want %v", got, want)
+	}
+	if got, want := shipped.Words(LANG_EN, WC_ALL), []word{house, match, roate}; !reflect.DeepEqual(got, want) {
+		t.Errorf("reloaded words = %v, want %v", got, want)
+	}
+}
+
+func Test_wordCurator_Suggest(t *testing.T) {
+	store := &wordListStore{dir: t.TempDir()}
+	wc := wordCurator{wdb: newTestWordDatabase(), store: store}
+
+	gamer := word{'g', 'a', 'm', 'e', 'r'}
+	if err := wc.Suggest(LANG_EN, gamer); err != nil {
+		t.Fatalf("Suggest() failed: %s", err)
+	}
+	if err := wc.Suggest(LANG_EN, gamer); err != ErrAlreadySuggested {
+		t.Errorf("Suggest() of pending word error = %v, want %v", err, ErrAlreadySuggested)
+	}
+	if err := wc.Suggest(LANG_EN, word{'m', 'a', 't', 'c', 'h'}); err != ErrAlreadyInWordList {
+		t.Errorf("Suggest() of known word error = %v, want %v", err, ErrAlreadyInWordList)
+	}
+
+	reloaded := newTestWordDatabase()
+	if err := store.Load(reloaded); err != nil {
+		t.Fatalf("Load() failed: %s", err)
+	}
+	if got := reloaded.Words(LANG_EN, WC_SUGGESTED); !reflect.DeepEqual(got, []word{gamer}) {
+		t.Errorf("reloaded suggestions = %v, want %v", got, []word{gamer})
+	}
+
+	for i := 1; i < MAX_PENDING_SUGGESTIONS; i++ {
+		wc.wdb.Add(LANG_EN, WC_SUGGESTED, word{'a', 'a', rune('a' + i/26/26%26), rune('a' + i/26%26), rune('a' + i%26)})
+	}
+	if err := wc.Suggest(LANG_EN, word{'z', 'z', 'z', 'z', 'z'}); err != ErrTooManySuggestions {
+		t.Errorf("Suggest() beyond the cap error = %v, want %v", err, ErrTooManySuggestions)
+	}
+}
+
+func Test_wordDatabase_RandomPick_skipsBanned(t *testing.T) {
+	wdb := newTestWordDatabase()
+	wdb.Add(LANG_EN, WC_BANNED, word{'r', 'o', 'a', 't', 'e'})
+
+	_, err := wdb.RandomPick(LANG_EN, []word{}, 0)
+	if err == nil {
+		t.Errorf("RandomPick() picked banned word, expected error as only banned words are available")
+	}
+}
+
+func Test_withBasicAuth(t *testing.T) {
+	ok := func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) }
+
+	tests := []struct {
+		name     string
+		password string
+		user     string
+		pass     string
+		setAuth  bool
+		want     int
+	}{
+		{"disabled without password", "", "admin", "", true, http.StatusNotFound},
+		{"missing credentials", "secret", "", "", false, http.StatusUnauthorized},
+		{"wrong password", "secret", "admin", "nope", true, http.StatusUnauthorized},
+		{"valid credentials", "secret", "admin", "secret", true, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", "/admin", nil)
+			if tt.setAuth {
+				req.SetBasicAuth(tt.user, tt.pass)
+			}
+
+			withBasicAuth("admin", tt.password, ok)(rec, req)
+
+			if rec.Code != tt.want {
+				t.Errorf("withBasicAuth() status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
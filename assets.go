@@ -0,0 +1,270 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	iofs "io/fs"
+	"log/slog"
+	"mime"
+	"net/http"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const STATIC_IMMUTABLE_CACHE_CONTROL = "public, max-age=31536000, immutable"
+
+// STATIC_MANIFEST maps the files of its dir to fingerprinted names, written
+// by `npm run manifest` (see web/app/manifest.mjs) like webpack-assets-manifest:
+// {"main.js": "main.1a2b3c4d.js"}. Without one (dev builds) names get hashed
+// on start.
+const STATIC_MANIFEST = "generated/manifest.json"
+
+type precompressed struct {
+	encoding string
+	ext      string
+}
+
+// precompressedEncodings are the variants served if present next to a file,
+// e.g. "main.js.br", in order of preference.
+var precompressedEncodings = []precompressed{
+	{"br", ".br"},
+	{"gzip", ".gz"},
+}
+
+// npmAssets are copied from web/node_modules to web/static/npm by
+// `npm run copy-npm-assets` (see web/package.json), there is no CDN to fall
+// back to.
+var npmAssets = []string{
+	"npm/htmx.min.js",
+	"npm/response-targets.js",
+	"npm/sse.js",
+}
+
+var staticFiles = mustLoadStaticAssets(fs, "web/static")
+
+func mustLoadStaticAssets(fsys iofs.FS, dir string) *staticAssets {
+	sa, err := newStaticAssets(fsys, dir)
+	if err != nil {
+		panic(err)
+	}
+
+	return sa
+}
+
+type staticFile struct {
+	name      string   // in the fs
+	etag      string   // hash of the content
+	integrity string   // subresource integrity, "sha384-<base64>"
+	immutable bool     // fingerprinted names never change their content
+	encodings []string // precompressed variants, see precompressedEncodings
+}
+
+// staticAssets serves the static files under names containing a hash of
+// their content, these never change and are cached for good. Templates get
+// the names via the "static" func.
+type staticAssets struct {
+	fsys   iofs.FS
+	hashed map[string]string     // "generated/main.js" -> "generated/main.1a2b3c4d.js"
+	files  map[string]staticFile // by url path, plain and hashed names
+}
+
+func newStaticAssets(fsys iofs.FS, dir string) (*staticAssets, error) {
+	sub, err := iofs.Sub(fsys, dir)
+	if err != nil {
+		return nil, fmt.Errorf("static assets failed opening '%s': %s", dir, err)
+	}
+
+	sa := &staticAssets{fsys: sub, hashed: make(map[string]string), files: make(map[string]staticFile)}
+	err = iofs.WalkDir(sub, ".", func(p string, d iofs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || isPrecompressed(p) {
+			return err
+		}
+
+		b, err := iofs.ReadFile(sub, p)
+		if err != nil {
+			return err
+		}
+
+		sum := sha256.Sum256(b)
+		sri := sha512.Sum384(b)
+		f := staticFile{
+			name:      p,
+			etag:      `"` + hex.EncodeToString(sum[:8]) + `"`,
+			integrity: "sha384-" + base64.StdEncoding.EncodeToString(sri[:]),
+		}
+		for _, pc := range precompressedEncodings {
+			if _, err := iofs.Stat(sub, p+pc.ext); err == nil {
+				f.encodings = append(f.encodings, pc.encoding)
+			}
+		}
+		// a fingerprinted file of the manifest may equal the hashed name of its original
+		if _, ok := sa.files[p]; !ok {
+			sa.files[p] = f
+		}
+
+		ext := path.Ext(p)
+		h := strings.TrimSuffix(p, ext) + "." + hex.EncodeToString(sum[:4]) + ext
+		f.immutable = true
+		sa.hashed[p] = h
+		sa.files[h] = f
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("static assets failed hashing '%s': %s", dir, err)
+	}
+
+	if err := sa.loadManifest(STATIC_MANIFEST); err != nil {
+		return nil, err
+	}
+
+	return sa, nil
+}
+
+func isPrecompressed(name string) bool {
+	return slices.ContainsFunc(precompressedEncodings, func(pc precompressed) bool {
+		return strings.HasSuffix(name, pc.ext)
+	})
+}
+
+// loadManifest takes over the fingerprinted names of the build. Without a
+// manifest, or for outdated entries, the names hashed on start stay.
+func (sa *staticAssets) loadManifest(name string) error {
+	b, err := iofs.ReadFile(sa.fsys, name)
+	if errors.Is(err, iofs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("static assets failed reading manifest '%s': %s", name, err)
+	}
+
+	entries := map[string]string{}
+	if err := json.Unmarshal(b, &entries); err != nil {
+		return fmt.Errorf("static assets failed parsing manifest '%s': %s", name, err)
+	}
+
+	dir := path.Dir(name)
+	for logical, fingerprinted := range entries {
+		p := path.Join(dir, fingerprinted)
+		f, ok := sa.files[p]
+		if !ok {
+			slog.Warn("asset of manifest missing", "manifest", name, "asset", logical, "file", fingerprinted)
+			continue
+		}
+
+		// e.g. tsc rebuilt main.js in dev after a manifest build, keep the name hashed on start
+		if orig, ok := sa.files[path.Join(dir, logical)]; ok && orig.etag != f.etag {
+			slog.Warn("asset of manifest outdated", "manifest", name, "asset", logical, "file", fingerprinted)
+			continue
+		}
+
+		f.immutable = true
+		sa.files[p] = f
+		sa.hashed[path.Join(dir, logical)] = p
+	}
+
+	return nil
+}
+
+// Path returns the url of an asset, e.g. "generated/main.js".
+func (sa *staticAssets) Path(name string) string {
+	if h, ok := sa.hashed[name]; ok {
+		return "/static/" + h
+	}
+
+	return "/static/" + name
+}
+
+// Integrity returns the subresource integrity of an asset for the
+// integrity attribute, e.g. of "npm/htmx.min.js". It's empty for unknown assets.
+func (sa *staticAssets) Integrity(name string) string {
+	return sa.files[name].integrity
+}
+
+// ServeHTTP serves hashed names with immutable caching, plain names (e.g.
+// favicons referenced by the webmanifest) get revalidated via their ETag.
+// Precompressed variants are preferred if the client accepts them.
+func (sa *staticAssets) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f, ok := sa.files[strings.TrimPrefix(r.URL.Path, "/")]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	name, etag := f.name, f.etag
+	if len(f.encodings) > 0 {
+		w.Header().Add("Vary", "Accept-Encoding")
+	}
+	for _, pc := range precompressedEncodings {
+		if slices.Contains(f.encodings, pc.encoding) && acceptsEncoding(r, pc.encoding) {
+			name = f.name + pc.ext
+			etag = strings.TrimSuffix(f.etag, `"`) + "-" + pc.encoding + `"`
+			w.Header().Set("Content-Encoding", pc.encoding)
+			break
+		}
+	}
+
+	b, err := iofs.ReadFile(sa.fsys, name)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	if f.immutable {
+		w.Header().Set("Cache-Control", STATIC_IMMUTABLE_CACHE_CONTROL)
+	} else {
+		w.Header().Set("Cache-Control", "no-cache")
+	}
+	w.Header().Set("ETag", etag)
+	// by the uncompressed name, ServeContent would sniff the compressed bytes
+	if ct := mime.TypeByExtension(path.Ext(f.name)); ct != "" {
+		w.Header().Set("Content-Type", ct)
+	}
+
+	http.ServeContent(w, r, f.name, time.Time{}, bytes.NewReader(b))
+}
+
+// acceptsEncoding reports whether Accept-Encoding allows the encoding, "q=0"
+// rules it out.
+func acceptsEncoding(r *http.Request, encoding string) bool {
+	for _, part := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		enc, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if !strings.EqualFold(strings.TrimSpace(enc), encoding) {
+			continue
+		}
+
+		q, found := strings.CutPrefix(strings.TrimSpace(params), "q=")
+		if !found {
+			return true
+		}
+		weight, err := strconv.ParseFloat(q, 64)
+		return err == nil && weight > 0
+	}
+
+	return false
+}
+
+// checkNPMAssets fails if an npm asset is missing in the build, pages would
+// be served without htmx.
+func (sa *staticAssets) checkNPMAssets() error {
+	missing := []string{}
+	for _, name := range npmAssets {
+		if _, ok := sa.hashed[name]; !ok {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("static assets missing npm assets %s, run `npm ci && npm run copy-npm-assets` in web/", strings.Join(missing, ", "))
+	}
+
+	return nil
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func Test_staticAssets(t *testing.T) {
+	sa, err := newStaticAssets(fstest.MapFS{
+		"web/static/generated/main.js":    {Data: []byte("console.log('lettr')")},
+		"web/static/npm/htmx.min.js":      {Data: []byte("htmx")},
+		"web/static/assets/favicon.png":   {Data: []byte("png")},
+		"web/static/generated/output.css": {Data: []byte("body{}")},
+	}, "web/static")
+	if err != nil {
+		t.Fatalf("newStaticAssets() error = %v", err)
+	}
+
+	mainJS := sa.Path("generated/main.js")
+	if !regexp.MustCompile(`^/static/generated/main\.[0-9a-f]{8}\.js$`).MatchString(mainJS) {
+		t.Errorf("Path() = %s, want a hashed name", mainJS)
+	}
+	if got, want := sa.Path("npm/sse.js"), "/static/npm/sse.js"; got != want {
+		t.Errorf("Path() of missing npm asset = %s, want %s", got, want)
+	}
+	if got, want := sa.Path("unknown.js"), "/static/unknown.js"; got != want {
+		t.Errorf("Path() of unknown asset = %s, want %s", got, want)
+	}
+	if err := sa.checkNPMAssets(); err == nil || !strings.Contains(err.Error(), "npm/response-targets.js, npm/sse.js") {
+		t.Errorf("checkNPMAssets() error = %v, want the missing ones", err)
+	}
+	// sha384 of "htmx", as printed by `openssl dgst -sha384 -binary | openssl base64 -A`
+	if got, want := sa.Integrity("npm/htmx.min.js"), "sha384-FWR/LAdkT++VdU6dmP2wF5ueISQhhtAlg6mv8Z0fDQ2Gb59dXnNvSY048ZSSdas5"; got != want {
+		t.Errorf("Integrity() = %s, want %s", got, want)
+	}
+	if got := sa.Integrity("npm/sse.js"); got != "" {
+		t.Errorf("Integrity() of missing npm asset = %s, want none", got)
+	}
+
+	h := http.StripPrefix("/static", sa)
+	tests := []struct {
+		name             string
+		path             string
+		wantCode         int
+		wantCacheControl string
+		wantBody         string
+	}{
+		{"hashed", mainJS, http.StatusOK, STATIC_IMMUTABLE_CACHE_CONTROL, "console.log('lettr')"},
+		{"plain", "/static/assets/favicon.png", http.StatusOK, "no-cache", "png"},
+		{"outdated hash", "/static/generated/main.00000000.js", http.StatusNotFound, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			if w.Code != tt.wantCode || w.Header().Get("Cache-Control") != tt.wantCacheControl {
+				t.Errorf("got %d %q, want %d %q", w.Code, w.Header().Get("Cache-Control"), tt.wantCode, tt.wantCacheControl)
+			}
+			if tt.wantBody != "" && w.Body.String() != tt.wantBody {
+				t.Errorf("body = %q, want %q", w.Body.String(), tt.wantBody)
+			}
+		})
+	}
+
+	all, _ := newStaticAssets(fstest.MapFS{
+		"web/static/npm/htmx.min.js":         {Data: []byte("htmx")},
+		"web/static/npm/response-targets.js": {Data: []byte("rt")},
+		"web/static/npm/sse.js":              {Data: []byte("sse")},
+	}, "web/static")
+	if err := all.checkNPMAssets(); err != nil {
+		t.Errorf("checkNPMAssets() error = %v, want nil once copied", err)
+	}
+}
+
+func Test_staticAssets_manifest(t *testing.T) {
+	js := []byte("console.log('lettr')")
+	sa, err := newStaticAssets(fstest.MapFS{
+		"web/static/generated/main.js":             {Data: js},
+		"web/static/generated/main.abcdef01.js":    {Data: js},
+		"web/static/generated/main.abcdef01.js.br": {Data: []byte("brotli")},
+		"web/static/generated/main.abcdef01.js.gz": {Data: []byte("gzip")},
+		"web/static/generated/output.css":          {Data: []byte("body{}")},
+		"web/static/generated/output.00000000.css": {Data: []byte("old")},
+		"web/static/generated/manifest.json":       {Data: []byte(`{"main.js": "main.abcdef01.js", "output.css": "output.00000000.css", "gone.css": "gone.12345678.css"}`)},
+	}, "web/static")
+	if err != nil {
+		t.Fatalf("newStaticAssets() error = %v", err)
+	}
+
+	if got, want := sa.Path("generated/main.js"), "/static/generated/main.abcdef01.js"; got != want {
+		t.Errorf("Path() = %s, want %s of the manifest", got, want)
+	}
+	if got, want := sa.Path("generated/gone.css"), "/static/generated/gone.css"; got != want {
+		t.Errorf("Path() of missing manifest asset = %s, want %s", got, want)
+	}
+	if got := sa.Path("generated/output.css"); !regexp.MustCompile(`^/static/generated/output\.[0-9a-f]{8}\.css$`).MatchString(got) || got == "/static/generated/output.00000000.css" {
+		t.Errorf("Path() of outdated manifest asset = %s, want the name hashed on start", got)
+	}
+
+	h := http.StripPrefix("/static", sa)
+	tests := []struct {
+		name             string
+		path             string
+		acceptEncoding   string
+		ifNoneMatch      string
+		wantCode         int
+		wantCacheControl string
+		wantEncoding     string
+		wantBody         string
+	}{
+		{"identity", "/static/generated/main.abcdef01.js", "", "", http.StatusOK, STATIC_IMMUTABLE_CACHE_CONTROL, "", string(js)},
+		{"brotli preferred", "/static/generated/main.abcdef01.js", "gzip, deflate, br", "", http.StatusOK, STATIC_IMMUTABLE_CACHE_CONTROL, "br", "brotli"},
+		{"gzip", "/static/generated/main.abcdef01.js", "gzip;q=0.5, br;q=0", "", http.StatusOK, STATIC_IMMUTABLE_CACHE_CONTROL, "gzip", "gzip"},
+		{"plain name", "/static/generated/main.js", "", "", http.StatusOK, "no-cache", "", string(js)},
+		{"not modified", "/static/generated/main.js", "", "", http.StatusNotModified, "no-cache", "", ""},
+		{"manifest stays uncompressed", "/static/generated/manifest.json", "br", "", http.StatusOK, "no-cache", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			r.Header.Set("Accept-Encoding", tt.acceptEncoding)
+			if tt.wantCode == http.StatusNotModified {
+				w := httptest.NewRecorder()
+				h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+				r.Header.Set("If-None-Match", w.Header().Get("ETag"))
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if w.Code != tt.wantCode || w.Header().Get("Cache-Control") != tt.wantCacheControl || w.Header().Get("Content-Encoding") != tt.wantEncoding {
+				t.Errorf("got %d %q %q, want %d %q %q", w.Code, w.Header().Get("Cache-Control"), w.Header().Get("Content-Encoding"), tt.wantCode, tt.wantCacheControl, tt.wantEncoding)
+			}
+			if tt.wantBody != "" && w.Body.String() != tt.wantBody {
+				t.Errorf("body = %q, want %q", w.Body.String(), tt.wantBody)
+			}
+			if w.Code == http.StatusOK && w.Header().Get("ETag") == "" {
+				t.Errorf("ETag missing")
+			}
+			if tt.wantEncoding != "" && w.Header().Get("Content-Type") != "text/javascript; charset=utf-8" {
+				t.Errorf("Content-Type = %q of the compressed variant", w.Header().Get("Content-Type"))
+			}
+		})
+	}
+}
+
+func Test_acceptsEncoding(t *testing.T) {
+	tests := []struct {
+		acceptEncoding string
+		encoding       string
+		want           bool
+	}{
+		{"", "gzip", false},
+		{"gzip, deflate, br", "br", true},
+		{"GZIP", "gzip", true},
+		{"br;q=0", "br", false},
+		{"br; q=0.0, gzip", "br", false},
+		{"br;q=0.1", "br", true},
+		{"*", "br", false},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("Accept-Encoding", tt.acceptEncoding)
+		if got := acceptsEncoding(r, tt.encoding); got != tt.want {
+			t.Errorf("acceptsEncoding(%q, %q) = %v, want %v", tt.acceptEncoding, tt.encoding, got, tt.want)
+		}
+	}
+}
@@ -2,109 +2,144 @@ package main
 
 import (
 	"encoding/json"
+	"flag"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
 	"os"
+	"path/filepath"
 	"strings"
 	"time"
+
+	"github.com/pandorasNox/lettr/pkg/wordset"
 )
 
-// {
-//     "largely": {
-//         "word": "largely",
-//         "wordset_id": "54bd55df7265742391cf0000",
-//         "meanings": [{
-//             "id": "54bd55df7265742391d10000",
-//             "def": "in large part",
-//             "speech_part": "adverb",
-//             "synonyms": ["mostly", "for the most part"]
-//         }, {
-//             "id": "54bd55df7265742391d20000",
-//             "def": "on a large scale",
-//             "example": "the sketch was so largely drawn that you could see it from the back row",
-//             "speech_part": "adverb"
-//         }]
-//     }
-// }
-
-type Entries map[string]Entry
-
-type Entry struct {
-	Word string
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprintln(os.Stderr, "Usage: wordset [fetch|definitions] [flags]")
+		os.Exit(2)
+	}
+
+	switch os.Args[1] {
+	case "fetch":
+		fetchDataSets(os.Args[2:])
+	case "definitions":
+		convertDefinitions(os.Args[2:])
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command: '%s'\n", os.Args[1])
+		os.Exit(2)
+	}
 }
 
-func main() {
-	urlFormat := "https://raw.githubusercontent.com/wordset/wordset-dictionary/master/data/%s.json"
-	dataSetNames := []string{"a", "b", "c", "d", "e", "f", "g", "h", "i", "j", "k", "l", "m", "misc", "n", "o", "p", "q", "r", "s", "t", "u", "v", "w", "x", "y", "z"}
-	// dataSetNames := []string{"z"}
+// fetchDataSets downloads all wordset data files into a local dir, which then
+// serves as reproducible input for `lettr wordlist build -source <dir>` and
+// the definitions conversion.
+//
+//	go run ./bin/wordset fetch -out ./tmp/wordset-data
+func fetchDataSets(args []string) {
+	fs := flag.NewFlagSet("fetch", flag.ExitOnError)
+	outDir := fs.String("out", "", "dir to write the wordset data files to")
+	fs.Parse(args)
+
+	if *outDir == "" {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	err := os.MkdirAll(*outDir, 0755)
+	if err != nil {
+		log.Fatalf("failed creating out dir: %s", err)
+	}
 
-	all5LetterWords := []string{}
-	for _, dsn := range dataSetNames {
-		url := fmt.Sprintf(urlFormat, dsn)
+	for _, dsn := range wordset.DataSetNames {
+		url := fmt.Sprintf(wordset.DataURLFormat, dsn)
 
-		b := fetch(url)
-		es := parseBody(b)
+		b, err := fetch(url)
+		if err != nil {
+			log.Fatalf("failed fetching '%s': %s", url, err)
+		}
 
-		for _, e := range es {
-			if len(e.Word) == 5 && (!strings.ContainsAny(e.Word, " '-")) {
-				all5LetterWords = append(all5LetterWords, e.Word)
-			}
+		path := filepath.Join(*outDir, dsn+".json")
+		err = os.WriteFile(path, b, 0644)
+		if err != nil {
+			log.Fatalf("failed writing file: %s", err)
 		}
 
-		// fmt.Printf("enties:\n%v\n", es)
+		fmt.Printf("fetched %s\n", path)
+	}
+}
+
+// convertDefinitions turns pre-fetched wordset data files (a.json, b.json, ...)
+// into the definition dataset read by the lettr server (see DEFINITIONS_DIR).
+//
+//	go run ./bin/wordset definitions -in ./tmp/wordset-data -out ./tmp/definitions/en.json
+func convertDefinitions(args []string) {
+	fs := flag.NewFlagSet("definitions", flag.ExitOnError)
+	inDir := fs.String("in", "", "dir containing the pre-fetched wordset data files")
+	outPath := fs.String("out", "", "path of the definition dataset to write")
+	fs.Parse(args)
+
+	if *inDir == "" || *outPath == "" {
+		fs.Usage()
+		os.Exit(2)
 	}
 
-	fmt.Printf("len(all5LetterWords):\n%d\n", len(all5LetterWords))
-	fmt.Println("")
-	fmt.Printf("all5LetterWords:\n%v\n", all5LetterWords)
+	ds := wordset.Dataset{Source: "generated from https://github.com/wordset/wordset-dictionary data set"}
+	for _, dsn := range wordset.DataSetNames {
+		path := filepath.Join(*inDir, dsn+".json")
+		f, err := os.Open(path)
+		if err != nil {
+			log.Fatalf("failed opening wordset data file: %s", err)
+		}
 
-	out := ""
-	for _, w := range all5LetterWords {
-		out += w + "\n"
+		es, err := wordset.ParseEntries(f)
+		f.Close()
+		if err != nil {
+			log.Fatalf("failed parsing '%s': %s", path, err)
+		}
+
+		ds.Add(es, isFiveLetterWord)
+	}
+
+	b, err := json.Marshal(ds)
+	if err != nil {
+		log.Fatalf("failed encoding definition dataset: %s", err)
 	}
-	err := os.WriteFile("/tmp/dat1", []byte(out), 0644)
+
+	err = os.WriteFile(*outPath, b, 0644)
 	if err != nil {
-		log.Fatalf("failed writing file: %s", &err)
+		log.Fatalf("failed writing file: %s", err)
 	}
+
+	fmt.Printf("wrote %d definitions to '%s'\n", len(ds.Entries), *outPath)
+}
+
+func isFiveLetterWord(w string) bool {
+	return len(w) == 5 && (!strings.ContainsAny(w, " '-"))
 }
 
-func fetch(url string) (body []byte) {
+func fetch(url string) (body []byte, err error) {
 	client := http.Client{
-		Timeout: time.Second * 2, // Timeout after 2 seconds
+		Timeout: time.Second * 30,
 	}
 
 	req, err := http.NewRequest(http.MethodGet, url, nil)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 
 	req.Header.Set("User-Agent", "golang-commandline-tool")
 
-	res, getErr := client.Do(req)
-	if getErr != nil {
-		log.Fatal(getErr)
-	}
-
-	if res.Body != nil {
-		defer res.Body.Close()
-	}
-
-	body, readErr := io.ReadAll(res.Body)
-	if readErr != nil {
-		log.Fatal(readErr)
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, err
 	}
+	defer res.Body.Close()
 
-	return body
-}
-
-func parseBody(body []byte) Entries {
-	e := Entries{}
-	jsonErr := json.Unmarshal(body, &e)
-	if jsonErr != nil {
-		log.Fatal(jsonErr)
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status: %d", res.StatusCode)
 	}
 
-	return e
+	return io.ReadAll(res.Body)
 }
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"slices"
+)
+
+// boardCounts are the multi board sizes players can pick from.
+var boardCounts = []int{2, 4, 8}
+
+// board is one of the grids of MODE_MULTI. Every guess is evaluated against
+// all boards which aren't solved yet.
+type board struct {
+	Solution word
+	Guesses  []wordGuess
+	Attempts int // rows of the grid, shared by all boards
+}
+
+func (b board) IsSolved() bool {
+	return len(b.Guesses) > 0 && b.Guesses[len(b.Guesses)-1].isSolved()
+}
+
+// Rows pads the guesses with empty rows up to the number of attempts.
+func (b board) Rows() []wordGuess {
+	rows := make([]wordGuess, max(b.Attempts, len(b.Guesses)))
+	copy(rows, b.Guesses)
+
+	return rows
+}
+
+func (b board) letterGuesses() []letterGuess {
+	lgs := []letterGuess{}
+	for _, wg := range b.Guesses {
+		lgs = append(lgs, wg.letterGuesses()...)
+	}
+
+	return lgs
+}
+
+type boards []board
+
+// multiBoardAttempts gives one more attempt than solutions to find plus five,
+// like the well known 2 board (7), 4 board (9) and 8 board (13) variants.
+func multiBoardAttempts(count int) int {
+	return count + 5
+}
+
+// newBoards picks count distinct solutions, avoiding the already played and
+// banned words. It fails if there aren't enough words left.
+func newBoards(count int, l language, avoidList []word, wdb wordDatabase) (boards, error) {
+	candidates := wdb.Words(l, WC_COMMON)
+	if len(candidates) == 0 {
+		candidates = wdb.Words(l, WC_ALL)
+	}
+	candidates = slices.DeleteFunc(candidates, func(w word) bool {
+		return wdb.Has(l, WC_BANNED, w) || slices.ContainsFunc(avoidList, w.isEqual)
+	})
+
+	if len(candidates) < count {
+		return nil, fmt.Errorf("newBoards with lang '%s' needs %d words, only %d left", l, count, len(candidates))
+	}
+
+	rand.Shuffle(len(candidates), func(i, j int) {
+		candidates[i], candidates[j] = candidates[j], candidates[i]
+	})
+
+	bs := make(boards, 0, count)
+	for _, w := range candidates[:count] {
+		bs = append(bs, board{Solution: w.ToLower(), Guesses: []wordGuess{}, Attempts: multiBoardAttempts(count)})
+	}
+
+	return bs, nil
+}
+
+// Rounds counts the guesses made so far.
+func (bs boards) Rounds() int {
+	r := 0
+	for _, b := range bs {
+		r = max(r, len(b.Guesses))
+	}
+
+	return r
+}
+
+func (bs boards) isSolved() bool {
+	if len(bs) == 0 {
+		return false
+	}
+
+	for _, b := range bs {
+		if !b.IsSolved() {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (bs boards) isLoose() bool {
+	return len(bs) > 0 && !bs.isSolved() && bs.Rounds() >= multiBoardAttempts(len(bs))
+}
+
+// guess evaluates w against every unsolved board. It returns new boards, the
+// guesses of bs stay untouched (see session).
+func (bs boards) guess(n normaliser, w word) boards {
+	out := make(boards, len(bs))
+	for i, b := range bs {
+		b.Guesses = slices.Clone(b.Guesses)
+		if !b.IsSolved() {
+			b.Guesses = append(b.Guesses, evaluateGuessedWord(n, w, b.Solution))
+		}
+
+		out[i] = b
+	}
+
+	return out
+}
+
+// InputCells enumerates the letter cells of the guess input, one per letter of a word.
+func (bs boards) InputCells() []int {
+	cells := make([]int, len(word{}))
+	for i := range cells {
+		cells[i] = i
+	}
+
+	return cells
+}
+
+func (bs boards) solutions() []word {
+	ws := make([]word, len(bs))
+	for i, b := range bs {
+		ws[i] = b.Solution
+	}
+
+	return ws
+}
+
+func (bs boards) letterGuesses() [][]letterGuess {
+	lgs := make([][]letterGuess, len(bs))
+	for i, b := range bs {
+		lgs[i] = b.letterGuesses()
+	}
+
+	return lgs
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/pandorasNox/lettr/pkg/wordlist"
+)
+
+func Test_boards_guess(t *testing.T) {
+	match := word{'m', 'a', 't', 'c', 'h'}
+	house := word{'h', 'o', 'u', 's', 'e'}
+
+	bs := boards{
+		{Solution: match, Guesses: []wordGuess{}, Attempts: multiBoardAttempts(2)},
+		{Solution: house, Guesses: []wordGuess{}, Attempts: multiBoardAttempts(2)},
+	}
+	before := bs
+
+	bs = bs.guess(normaliser{}, match)
+	if !bs[0].IsSolved() || bs[1].IsSolved() || bs.isSolved() || bs.Rounds() != 1 {
+		t.Fatalf("guess() should solve only the first board, got %v", bs)
+	}
+	if len(before[0].Guesses) != 0 {
+		t.Errorf("guess() must not modify the given boards")
+	}
+
+	bs = bs.guess(normaliser{}, house)
+	if len(bs[0].Guesses) != 1 {
+		t.Errorf("guess() must not add guesses to solved boards, got %v", bs[0].Guesses)
+	}
+	if !bs.isSolved() || bs.isLoose() {
+		t.Errorf("guess() should solve all boards, got %v", bs)
+	}
+	if rows := bs[0].Rows(); len(rows) != 7 || rows[0].isSolved() != true || rows[1].isFilled() {
+		t.Errorf("Rows() should pad the guesses to the attempts, got %v", rows)
+	}
+}
+
+func Test_boards_isLoose(t *testing.T) {
+	bs := boards{
+		{Solution: word{'m', 'a', 't', 'c', 'h'}, Guesses: []wordGuess{}, Attempts: multiBoardAttempts(2)},
+		{Solution: word{'h', 'o', 'u', 's', 'e'}, Guesses: []wordGuess{}, Attempts: multiBoardAttempts(2)},
+	}
+
+	for range multiBoardAttempts(len(bs)) - 1 {
+		bs = bs.guess(normaliser{}, word{'g', 'a', 'm', 'e', 's'})
+	}
+	if bs.isLoose() {
+		t.Fatalf("isLoose() should leave one attempt, got %d rounds", bs.Rounds())
+	}
+
+	bs = bs.guess(normaliser{}, word{'g', 'a', 'm', 'e', 's'})
+	if !bs.isLoose() {
+		t.Errorf("isLoose() = false after %d rounds", bs.Rounds())
+	}
+}
+
+func Test_newBoards(t *testing.T) {
+	wdb := wordDatabase{}
+	wdb.Init(nil, nil)
+	wdb.Replace(LANG_EN, WC_ALL, []word{{'m', 'a', 't', 'c', 'h'}, {'h', 'o', 'u', 's', 'e'}, {'g', 'a', 'm', 'e', 's'}, {'r', 'o', 'a', 't', 'e'}})
+
+	bs, err := newBoards(4, LANG_EN, []word{}, wdb)
+	if err != nil || len(bs) != 4 || bs[0].Attempts != 9 {
+		t.Fatalf("newBoards() = %v, %v", bs, err)
+	}
+
+	seen := map[word]bool{}
+	for _, w := range bs.solutions() {
+		if seen[w] {
+			t.Errorf("newBoards() picked '%s' twice", w)
+		}
+		seen[w] = true
+	}
+
+	bs, err = newBoards(2, LANG_EN, []word{{'m', 'a', 't', 'c', 'h'}, {'h', 'o', 'u', 's', 'e'}}, wdb)
+	if err != nil || len(bs) != 2 {
+		t.Fatalf("newBoards() with avoided words = %v, %v", bs, err)
+	}
+	for _, w := range bs.solutions() {
+		if w.String() == "match" || w.String() == "house" {
+			t.Errorf("newBoards() picked avoided '%s'", w)
+		}
+	}
+
+	if bs, err := newBoards(4, LANG_EN, []word{{'r', 'o', 'a', 't', 'e'}}, wdb); err == nil {
+		t.Errorf("newBoards() = %v, want an error without enough words", bs)
+	}
+}
+
+func Test_keyboard_InitBoards(t *testing.T) {
+	lgsPerBoard := [][]letterGuess{
+		{{'a', MatchExact}},
+		{{'a', MatchVague}},
+		{},
+	}
+
+	kb := keyboard{}
+	kb.InitBoards("qwerty", wordlist.UMLAUTS_DROP, lgsPerBoard)
+
+	// qwerty "A" is the first key of the second row
+	if kb.KeyGrid[1][0].Key != "A" {
+		t.Fatalf("qwerty expects 'A' as first key of the second row, got '%s'", kb.KeyGrid[1][0].Key)
+	}
+	got := kb.BoardMatch[1][0]
+	if len(got) != 3 || got[0] != MatchExact || got[1] != MatchVague || got[2] != 0 {
+		t.Errorf("BoardMatch of A = %v", got)
+	}
+	if kb.BoardMatch[1][9] != nil {
+		t.Errorf("BoardMatch of Enter should stay empty, got %v", kb.BoardMatch[1][9])
+	}
+}
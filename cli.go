@@ -0,0 +1,181 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/pandorasNox/lettr/pkg/corpora"
+	"github.com/pandorasNox/lettr/pkg/wordlist"
+)
+
+const cliUsage = `Usage: lettr [command]
+
+Without a command the web server is started.
+
+Commands:
+  wordlist build    build a word list from raw sources and diff it against configs/
+  corpora export    export word lists from Leipzig corpora word dumps
+`
+
+// runCommand executes the cli commands of the lettr binary and returns the exit code.
+func runCommand(args []string, stdout io.Writer, stderr io.Writer) int {
+	switch strings.Join(args[:min(2, len(args))], " ") {
+	case "wordlist build":
+		return runWordlistBuild(args[2:], stdout, stderr)
+	case "corpora export":
+		return runCorporaExport(args[2:], stdout, stderr)
+	default:
+		fmt.Fprint(stderr, cliUsage)
+		return 2
+	}
+}
+
+type stringsFlag []string
+
+func (sf *stringsFlag) String() string { return strings.Join(*sf, ",") }
+
+func (sf *stringsFlag) Set(v string) error {
+	*sf = append(*sf, v)
+	return nil
+}
+
+func runWordlistBuild(args []string, stdout io.Writer, stderr io.Writer) int {
+	fs := flag.NewFlagSet("wordlist build", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+
+	var sources stringsFlag
+	fs.Var(&sources, "source", "local file, dir or http(s) url to read candidates from (repeatable)")
+	lang := fs.String("lang", string(LANG_EN), "language of the list, selects the alphabet from configs/languages.json")
+	length := fs.Int("length", len(word{}), "word length to keep")
+	umlauts := fs.String("umlauts", "", "umlaut handling: keep, transliterate or drop (default from configs/languages.json)")
+	dedupe := fs.Bool("dedupe", true, "remove duplicate words")
+	blocklistPath := fs.String("blocklist", "", "file with words to exclude, one per line")
+	outPath := fs.String("out", "", "path to write the list to, omit for a dry run only printing the diff")
+	comparePath := fs.String("compare", "", "existing list to diff against (default configs/<lang>-<lang>.words.v2.txt)")
+
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	if len(sources) == 0 {
+		fmt.Fprintln(stderr, "at least one -source is required")
+		fs.Usage()
+		return 2
+	}
+
+	lc, ok := languages.get(language(*lang))
+	if !ok {
+		fmt.Fprintf(stderr, "language not found in registry: '%s'\n", *lang)
+		return 2
+	}
+
+	if *umlauts == "" {
+		*umlauts = string(lc.Umlauts)
+	}
+
+	umlautPolicy, err := wordlist.NewUmlautPolicy(*umlauts)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+
+	filter := wordlist.Filter{Length: *length, Alphabet: lc.Alphabet, Umlauts: umlautPolicy, Dedupe: *dedupe}
+	if *blocklistPath != "" {
+		filter.Blocklist, err = wordlist.Load(context.Background(), nil, *blocklistPath)
+		if err != nil {
+			fmt.Fprintf(stderr, "loading blocklist failed: %s\n", err)
+			return 1
+		}
+	}
+
+	candidates := []string{}
+	for _, src := range sources {
+		ws, err := wordlist.Load(context.Background(), nil, src)
+		if err != nil {
+			fmt.Fprintf(stderr, "loading source failed: %s\n", err)
+			return 1
+		}
+
+		candidates = append(candidates, ws...)
+	}
+
+	words := filter.Apply(candidates)
+
+	if *comparePath == "" {
+		*comparePath = fmt.Sprintf("configs/%s-%s.words.v2.txt", *lang, *lang)
+	}
+
+	existing, err := wordlist.Load(context.Background(), nil, *comparePath)
+	if err != nil {
+		fmt.Fprintf(stderr, "loading list to compare failed: %s\n", err)
+		return 1
+	}
+
+	added, removed := wordlist.Diff(existing, words)
+	for _, w := range removed {
+		fmt.Fprintf(stdout, "-%s\n", w)
+	}
+	for _, w := range added {
+		fmt.Fprintf(stdout, "+%s\n", w)
+	}
+	fmt.Fprintf(stdout, "%s: %d words, %d added, %d removed\n", *comparePath, len(words), len(added), len(removed))
+
+	if *outPath == "" {
+		return 0
+	}
+
+	f, err := os.Create(*outPath)
+	if err != nil {
+		fmt.Fprintf(stderr, "creating output failed: %s\n", err)
+		return 1
+	}
+	defer f.Close()
+
+	header := fmt.Sprintf("generated by `lettr wordlist build` from %s (lang=%s, %s)", strings.Join(sources, ", "), *lang, filter)
+	err = wordlist.Write(f, header, words)
+	if err != nil {
+		fmt.Fprintf(stderr, "writing output failed: %s\n", err)
+		return 1
+	}
+
+	return 0
+}
+
+func runCorporaExport(args []string, stdout io.Writer, stderr io.Writer) int {
+	fs := flag.NewFlagSet("corpora export", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+
+	datasetsDir := fs.String("datasets", "", "dir containing the extracted corpora, e.g. <dir>/eng_news_2023_10K/eng_news_2023_10K-words.txt")
+	exportDir := fs.String("out", "configs", "dir to write the corpora-*-export.txt files to")
+	length := fs.Int("length", corpora.DefaultFilter.Length, "word length to keep")
+	minFreq := fs.Int("min-freq", corpora.DefaultFilter.MinFreq, "minimum frequency (inclusive) to keep a word")
+
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	if *datasetsDir == "" {
+		fmt.Fprintln(stderr, "-datasets is required")
+		fs.Usage()
+		return 2
+	}
+
+	filter := corpora.DefaultFilter
+	filter.Length = *length
+	filter.MinFreq = *minFreq
+
+	written, err := corpora.ExportDir(*datasetsDir, *exportDir, filter)
+	for _, path := range written {
+		fmt.Fprintf(stdout, "exported %s\n", path)
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "corpora export failed: %s\n", err)
+		return 1
+	}
+
+	return 0
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_runCommand_wordlistBuild(t *testing.T) {
+	dir := t.TempDir()
+	comparePath := filepath.Join(dir, "existing.txt")
+	outPath := filepath.Join(dir, "out.txt")
+
+	err := os.WriteFile(comparePath, []byte("// existing list\nbread\nbroth\n"), 0644)
+	if err != nil {
+		t.Fatalf("writing fixture failed: %s", err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	code := runCommand([]string{
+		"wordlist", "build",
+		"-source", "pkg/wordlist/testdata/wordset-b.json",
+		"-blocklist", "pkg/wordlist/testdata/blocklist.txt",
+		"-compare", comparePath,
+		"-out", outPath,
+	}, &stdout, &stderr)
+	if code != 0 {
+		t.Fatalf("runCommand() exit code = %d, stderr:\n%s", code, stderr.String())
+	}
+
+	wantDiff := "-broth\n+break\n" + comparePath + ": 2 words, 1 added, 1 removed\n"
+	if stdout.String() != wantDiff {
+		t.Errorf("runCommand() diff = %q, want %q", stdout.String(), wantDiff)
+	}
+
+	words, err := readWordList(os.DirFS(dir), "out.txt", languages.config(LANG_EN).normaliser())
+	if err != nil {
+		t.Fatalf("written list is not readable as word list: %s", err)
+	}
+	if len(words) != 2 {
+		t.Errorf("written list has %d words, want 2", len(words))
+	}
+}
+
+func Test_runCommand_unknown(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if code := runCommand([]string{"nope"}, &stdout, &stderr); code != 2 {
+		t.Errorf("runCommand() exit code = %d, want 2", code)
+	}
+}
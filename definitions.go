@@ -0,0 +1,301 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/pandorasNox/lettr/pkg/wordset"
+)
+
+var ErrNoDefinition = errors.New("no definition found")
+
+// DefinitionProvider looks up the meanings of a word, e.g. to show them once a game is over.
+type DefinitionProvider interface {
+	Define(ctx context.Context, l language, w word) ([]wordset.Meaning, error)
+}
+
+// offlineDefinitions serves definitions from pre-fetched datasets,
+// converted via `go run ./bin/wordset definitions`.
+type offlineDefinitions struct {
+	byLang map[language]map[string][]wordset.Meaning
+}
+
+// newOfflineDefinitions loads every "<lang>.json" dataset found in dir.
+func newOfflineDefinitions(dir string) (offlineDefinitions, error) {
+	od := offlineDefinitions{byLang: make(map[language]map[string][]wordset.Meaning)}
+
+	for _, l := range languages.codes() {
+		path := filepath.Join(dir, fmt.Sprintf("%s.json", l))
+		f, err := os.Open(path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return od, fmt.Errorf("offline definitions failed opening dataset: %s", err)
+		}
+
+		ds, err := wordset.ParseDataset(f)
+		f.Close()
+		if err != nil {
+			return od, fmt.Errorf("offline definitions failed with '%s': %s", path, err)
+		}
+
+		od.byLang[l] = ds.Entries
+	}
+
+	return od, nil
+}
+
+func (od offlineDefinitions) Define(_ context.Context, l language, w word) ([]wordset.Meaning, error) {
+	ms, ok := od.byLang[l][w.ToLower().String()]
+	if !ok || len(ms) == 0 {
+		return nil, ErrNoDefinition
+	}
+
+	return ms, nil
+}
+
+// httpDefinitions fetches wordset-dictionary style data files (one per first letter)
+// from a remote location, e.g. wordset.DataURLFormat. It only knows a single language.
+// A data file is several MB, so it's fetched once per letter, detached from the
+// request asking for it, and kept parsed. Failed fetches are retried after
+// failureBackoff.
+type httpDefinitions struct {
+	client         *http.Client
+	urlFormat      string
+	language       language
+	maxBytes       int64
+	failureBackoff time.Duration
+
+	mu      sync.Mutex
+	letters map[rune]*letterFetch
+}
+
+// letterFetch is the outcome of fetching one data file, usable once done is closed.
+type letterFetch struct {
+	done      chan struct{}
+	meanings  map[string][]wordset.Meaning
+	err       error
+	fetchedAt time.Time
+}
+
+func newHTTPDefinitions(client *http.Client, urlFormat string, l language) *httpDefinitions {
+	return &httpDefinitions{
+		client:         client,
+		urlFormat:      urlFormat,
+		language:       l,
+		maxBytes:       64 << 20, // data files are several MB each
+		failureBackoff: 5 * time.Minute,
+		letters:        make(map[rune]*letterFetch),
+	}
+}
+
+func (hd *httpDefinitions) Define(ctx context.Context, l language, w word) ([]wordset.Meaning, error) {
+	if l != hd.language {
+		return nil, ErrNoDefinition
+	}
+
+	lw := w.ToLower()
+	lf := hd.letter(lw[0])
+
+	select {
+	case <-lf.done:
+	case <-ctx.Done():
+		return nil, fmt.Errorf("http definitions still fetching '%c': %s", lw[0], ctx.Err())
+	}
+
+	if lf.err != nil {
+		return nil, lf.err
+	}
+
+	ms, ok := lf.meanings[lw.String()]
+	if !ok {
+		return nil, ErrNoDefinition
+	}
+
+	return ms, nil
+}
+
+// letter returns the fetch of the data file of letter, starting it if there is
+// none yet or the last one failed more than failureBackoff ago.
+func (hd *httpDefinitions) letter(letter rune) *letterFetch {
+	hd.mu.Lock()
+	defer hd.mu.Unlock()
+
+	if lf, ok := hd.letters[letter]; ok {
+		select {
+		case <-lf.done:
+			if lf.err == nil || errors.Is(lf.err, ErrNoDefinition) || time.Since(lf.fetchedAt) < hd.failureBackoff {
+				return lf
+			}
+		default:
+			return lf
+		}
+	}
+
+	lf := &letterFetch{done: make(chan struct{})}
+	hd.letters[letter] = lf
+
+	go func() {
+		lf.meanings, lf.err = hd.fetch(letter)
+		lf.fetchedAt = time.Now()
+		if lf.err != nil && !errors.Is(lf.err, ErrNoDefinition) {
+			slog.Warn("http definitions fetch failed", "letter", string(letter), "err", lf.err)
+		}
+		close(lf.done)
+	}()
+
+	return lf
+}
+
+// fetch downloads and parses the data file of letter, keeping only words that
+// fit a puzzle.
+func (hd *httpDefinitions) fetch(letter rune) (map[string][]wordset.Meaning, error) {
+	url := fmt.Sprintf(hd.urlFormat, string(letter))
+
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("http definitions failed creating request: %s", err)
+	}
+
+	req.Header.Set("User-Agent", "lettr")
+
+	res, err := hd.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("http definitions request failed: %s", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusNotFound {
+		return nil, ErrNoDefinition
+	}
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("http definitions request failed with status: %d", res.StatusCode)
+	}
+
+	body := &io.LimitedReader{R: res.Body, N: hd.maxBytes + 1}
+	es, err := wordset.ParseEntries(body)
+	if body.N <= 0 {
+		return nil, fmt.Errorf("http definitions data file '%s' exceeds %d bytes", url, hd.maxBytes)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("http definitions failed: %s", err)
+	}
+
+	ds := wordset.Dataset{}
+	ds.Add(es, func(w string) bool { return utf8.RuneCountInString(w) == len(word{}) })
+
+	return ds.Entries, nil
+}
+
+// chainedDefinitions asks every provider in order and returns the first hit.
+type chainedDefinitions []DefinitionProvider
+
+func (cd chainedDefinitions) Define(ctx context.Context, l language, w word) ([]wordset.Meaning, error) {
+	var errs []error
+	for _, dp := range cd {
+		ms, err := dp.Define(ctx, l, w)
+		if err == nil {
+			return ms, nil
+		}
+
+		if !errors.Is(err, ErrNoDefinition) {
+			errs = append(errs, err)
+		}
+	}
+
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	return nil, ErrNoDefinition
+}
+
+type definitionCacheKey struct {
+	l language
+	w word
+}
+
+type definitionCacheEntry struct {
+	meanings []wordset.Meaning
+	err      error // only ErrNoDefinition gets cached, other errors are retried
+}
+
+// cachedDefinitions remembers lookups (hits and misses) of the wrapped provider.
+type cachedDefinitions struct {
+	mu         sync.Mutex
+	provider   DefinitionProvider
+	maxEntries int
+	entries    map[definitionCacheKey]definitionCacheEntry
+}
+
+func newCachedDefinitions(dp DefinitionProvider, maxEntries int) *cachedDefinitions {
+	return &cachedDefinitions{
+		provider:   dp,
+		maxEntries: maxEntries,
+		entries:    make(map[definitionCacheKey]definitionCacheEntry),
+	}
+}
+
+func (cd *cachedDefinitions) Define(ctx context.Context, l language, w word) ([]wordset.Meaning, error) {
+	key := definitionCacheKey{l, w.ToLower()}
+
+	cd.mu.Lock()
+	e, ok := cd.entries[key]
+	cd.mu.Unlock()
+	if ok {
+		return e.meanings, e.err
+	}
+
+	ms, err := cd.provider.Define(ctx, l, w)
+	if err != nil && !errors.Is(err, ErrNoDefinition) {
+		return nil, err
+	}
+
+	cd.mu.Lock()
+	defer cd.mu.Unlock()
+
+	if len(cd.entries) >= cd.maxEntries {
+		// keep it simple, a full reset is cheap compared to bookkeeping an LRU
+		cd.entries = make(map[definitionCacheKey]definitionCacheEntry)
+	}
+	cd.entries[key] = definitionCacheEntry{ms, err}
+
+	return ms, err
+}
+
+// newDefinitionProvider builds the configured provider chain: offline datasets
+// first, the optional remote lookup second, all of it cached.
+func newDefinitionProvider(envCfg env) DefinitionProvider {
+	chain := chainedDefinitions{}
+
+	if envCfg.definitionsDir != "" {
+		od, err := newOfflineDefinitions(envCfg.definitionsDir)
+		if err != nil {
+			slog.Warn("offline definitions disabled", "err", err)
+		} else {
+			chain = append(chain, od)
+		}
+	}
+
+	if envCfg.definitionsURLFormat != "" {
+		chain = append(chain, newHTTPDefinitions(&http.Client{Timeout: time.Minute}, envCfg.definitionsURLFormat, LANG_EN))
+	}
+
+	return newCachedDefinitions(chain, 2000)
+}
+
+type definitionData struct {
+	Word     word
+	Meanings []wordset.Meaning
+	Locale   string
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pandorasNox/lettr/pkg/wordset"
+)
+
+const wordsetFixtureM = `{
+	"match": {
+		"word": "match",
+		"wordset_id": "1",
+		"meanings": [{"id": "2", "def": "a formal contest", "speech_part": "noun"}]
+	},
+	"matches": {
+		"word": "matches",
+		"wordset_id": "3",
+		"meanings": [{"id": "4", "def": "plural of match", "speech_part": "noun"}]
+	}
+}`
+
+func newWordsetStandInServer(t *testing.T, requests *atomic.Int32) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+
+		if r.URL.Path != "/data/m.json" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		w.Write([]byte(wordsetFixtureM))
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+func Test_httpDefinitions_Define(t *testing.T) {
+	requests := &atomic.Int32{}
+	srv := newWordsetStandInServer(t, requests)
+
+	hd := newHTTPDefinitions(&http.Client{Timeout: time.Second}, srv.URL+"/data/%s.json", LANG_EN)
+
+	tests := []struct {
+		name    string
+		l       language
+		w       word
+		want    []wordset.Meaning
+		wantErr error
+	}{
+		{"known word", LANG_EN, word{'M', 'A', 'T', 'C', 'H'}, []wordset.Meaning{{Def: "a formal contest", SpeechPart: "noun"}}, nil},
+		{"unknown word in existing data file", LANG_EN, word{'m', 'a', 'g', 'i', 'c'}, nil, ErrNoDefinition},
+		{"missing data file", LANG_EN, word{'r', 'o', 'a', 't', 'e'}, nil, ErrNoDefinition},
+		{"unsupported language", LANG_DE, word{'m', 'a', 't', 'c', 'h'}, nil, ErrNoDefinition},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := hd.Define(context.Background(), tt.l, tt.w)
+			if err != tt.wantErr {
+				t.Errorf("httpDefinitions.Define() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("httpDefinitions.Define() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if got := requests.Load(); got != 2 {
+		t.Errorf("httpDefinitions.Define() did %d upstream requests, want one per letter", got)
+	}
+}
+
+func Test_httpDefinitions_Define_limits(t *testing.T) {
+	failing := &atomic.Bool{}
+	failing.Store(true)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failing.Load() {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+
+		w.Write([]byte(wordsetFixtureM))
+	}))
+	t.Cleanup(srv.Close)
+
+	hd := newHTTPDefinitions(&http.Client{Timeout: time.Second}, srv.URL+"/data/%s.json", LANG_EN)
+	match := word{'m', 'a', 't', 'c', 'h'}
+
+	if _, err := hd.Define(context.Background(), LANG_EN, match); err == nil {
+		t.Fatalf("httpDefinitions.Define() expected error while upstream fails")
+	}
+
+	failing.Store(false)
+	if _, err := hd.Define(context.Background(), LANG_EN, match); err == nil {
+		t.Errorf("httpDefinitions.Define() expected the failure to be kept within the backoff")
+	}
+
+	hd.failureBackoff = 0
+	if _, err := hd.Define(context.Background(), LANG_EN, match); err != nil {
+		t.Errorf("httpDefinitions.Define() expected a retry after the backoff, got %v", err)
+	}
+
+	hd = newHTTPDefinitions(&http.Client{Timeout: time.Second}, srv.URL+"/data/%s.json", LANG_EN)
+	hd.maxBytes = int64(len(wordsetFixtureM) - 1)
+	if _, err := hd.Define(context.Background(), LANG_EN, match); err == nil || err == ErrNoDefinition {
+		t.Errorf("httpDefinitions.Define() error = %v, want data file too large", err)
+	}
+}
+
+func Test_cachedDefinitions_Define(t *testing.T) {
+	requests := &atomic.Int32{}
+	srv := newWordsetStandInServer(t, requests)
+
+	cd := newCachedDefinitions(newHTTPDefinitions(&http.Client{Timeout: time.Second}, srv.URL+"/data/%s.json", LANG_EN), 10)
+
+	for i := 0; i < 3; i++ {
+		cd.Define(context.Background(), LANG_EN, word{'m', 'a', 't', 'c', 'h'})
+		cd.Define(context.Background(), LANG_EN, word{'r', 'o', 'a', 't', 'e'})
+	}
+
+	if got := requests.Load(); got != 2 {
+		t.Errorf("cachedDefinitions.Define() did %d upstream requests, want 2", got)
+	}
+}
+
+func Test_offlineDefinitions_Define(t *testing.T) {
+	dir := t.TempDir()
+	err := os.WriteFile(
+		filepath.Join(dir, "en.json"),
+		[]byte(`{"source": "test", "entries": {"roate": [{"def": "a made up word", "speech_part": "verb"}]}}`),
+		0644,
+	)
+	if err != nil {
+		t.Fatalf("writing fixture failed: %s", err)
+	}
+
+	od, err := newOfflineDefinitions(dir)
+	if err != nil {
+		t.Fatalf("newOfflineDefinitions() failed: %s", err)
+	}
+
+	got, err := chainedDefinitions{od}.Define(context.Background(), LANG_EN, word{'R', 'O', 'A', 'T', 'E'})
+	want := []wordset.Meaning{{Def: "a made up word", SpeechPart: "verb"}}
+	if err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("offlineDefinitions.Define() = %v, %v; want %v", got, err, want)
+	}
+
+	_, err = od.Define(context.Background(), LANG_DE, word{'r', 'o', 'a', 't', 'e'})
+	if err != ErrNoDefinition {
+		t.Errorf("offlineDefinitions.Define() for missing dataset error = %v, want %v", err, ErrNoDefinition)
+	}
+}
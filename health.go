@@ -0,0 +1,159 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"path"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+var ErrStillLoading = errors.New("still loading")
+
+// BuildTime is set at build time, like Revision.
+var BuildTime = ""
+
+type readinessCheck struct {
+	name  string
+	check func() error
+}
+
+// readiness collects the checks behind '/readyz', the instance shouldn't get
+// traffic before all of them pass.
+type readiness struct {
+	mu     sync.Mutex
+	checks []readinessCheck
+}
+
+func (rd *readiness) Add(name string, check func() error) {
+	rd.mu.Lock()
+	defer rd.mu.Unlock()
+
+	rd.checks = append(rd.checks, readinessCheck{name, check})
+}
+
+// Check runs all checks, the result maps their names to "ok" or the error.
+func (rd *readiness) Check() (map[string]string, bool) {
+	rd.mu.Lock()
+	checks := rd.checks
+	rd.mu.Unlock()
+
+	results := make(map[string]string, len(checks))
+	ready := true
+	for _, c := range checks {
+		results[c.name] = "ok"
+		if err := c.check(); err != nil {
+			results[c.name] = err.Error()
+			ready = false
+		}
+	}
+
+	return results, ready
+}
+
+// loadedCheck fails until loaded is set, e.g. by a background init.
+func loadedCheck(loaded *atomic.Bool) func() error {
+	return func() error {
+		if !loaded.Load() {
+			return ErrStillLoading
+		}
+
+		return nil
+	}
+}
+
+type wordListVersion struct {
+	Files []string `json:"files"`
+	Words int      `json:"words"`
+}
+
+type versionInfo struct {
+	Revision  string                                          `json:"revision"`
+	BuildTime string                                          `json:"build_time"`
+	GoVersion string                                          `json:"go_version"`
+	WordLists map[language]map[wordCollection]wordListVersion `json:"word_lists"`
+}
+
+// newVersionInfo lists the word list files of each collection, their names
+// carry the version (e.g. "en-en.words.v2.txt").
+func newVersionInfo(wdb wordDatabase) versionInfo {
+	sizes := wdb.Sizes()
+
+	lists := make(map[language]map[wordCollection]wordListVersion)
+	for l, collections := range languages.filePaths() {
+		lists[l] = make(map[wordCollection]wordListVersion, len(collections))
+		for c, paths := range collections {
+			files := make([]string, 0, len(paths))
+			for _, p := range paths {
+				files = append(files, path.Base(p))
+			}
+
+			lists[l][c] = wordListVersion{Files: files, Words: sizes[l][c]}
+		}
+	}
+
+	return versionInfo{
+		Revision:  Revision,
+		BuildTime: BuildTime,
+		GoVersion: runtime.Version(),
+		WordLists: lists,
+	}
+}
+
+func writeJSON(w http.ResponseWriter, r *http.Request, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		slog.ErrorContext(r.Context(), "encoding json failed", "err", err)
+	}
+}
+
+func registerHealthRoutes(mux *http.ServeMux, rd *readiness, wdb wordDatabase) {
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, r, http.StatusOK, map[string]string{"status": "ok"})
+	})
+
+	mux.HandleFunc("GET /readyz", func(w http.ResponseWriter, r *http.Request) {
+		checks, ready := rd.Check()
+		if !ready {
+			writeJSON(w, r, http.StatusServiceUnavailable, map[string]any{"status": "not ready", "checks": checks})
+			return
+		}
+
+		writeJSON(w, r, http.StatusOK, map[string]any{"status": "ready", "checks": checks})
+	})
+
+	mux.HandleFunc("GET /version", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, r, http.StatusOK, newVersionInfo(wdb))
+	})
+}
+
+// awaitLoaded answers 503 until loaded is set, only the probes and static
+// files are served right away. Games can't start without their words.
+func awaitLoaded(h http.Handler, loaded *atomic.Bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if loaded.Load() || isProbeOrStatic(r.URL.Path) {
+			h.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Set("Retry-After", "1")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte(http.StatusText(http.StatusServiceUnavailable)))
+	})
+}
+
+func isProbeOrStatic(p string) bool {
+	switch p {
+	case "/healthz", "/readyz", "/version", "/metrics":
+		return true
+	}
+
+	return strings.HasPrefix(p, "/static/")
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func Test_readyz(t *testing.T) {
+	loaded := &atomic.Bool{}
+	rd := &readiness{}
+	rd.Add("word_database", loadedCheck(loaded))
+
+	mux := http.NewServeMux()
+	registerHealthRoutes(mux, rd, newWordDatabase())
+
+	get := func() (int, map[string]any) {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+		body := map[string]any{}
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+			t.Fatalf("body '%s' isn't json: %s", w.Body, err)
+		}
+
+		return w.Code, body
+	}
+
+	code, body := get()
+	if code != http.StatusServiceUnavailable {
+		t.Errorf("/readyz before loading = %d, want %d", code, http.StatusServiceUnavailable)
+	}
+	if checks := body["checks"].(map[string]any); checks["word_database"] != ErrStillLoading.Error() {
+		t.Errorf("/readyz checks = %v", checks)
+	}
+
+	loaded.Store(true)
+
+	code, body = get()
+	if code != http.StatusOK || body["status"] != "ready" {
+		t.Errorf("/readyz after loading = %d %v, want %d ready", code, body["status"], http.StatusOK)
+	}
+}
+
+func Test_version(t *testing.T) {
+	wdb := newWordDatabase()
+	if err := wdb.Init(fs, languages.filePaths()); err != nil {
+		t.Fatal(err)
+	}
+
+	mux := http.NewServeMux()
+	registerHealthRoutes(mux, &readiness{}, wdb)
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/version", nil))
+
+	got := versionInfo{}
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatalf("body '%s' isn't json: %s", w.Body, err)
+	}
+
+	if got.Revision != Revision || got.GoVersion == "" {
+		t.Errorf("/version = %+v", got)
+	}
+
+	for l, collections := range languages.filePaths() {
+		for c := range collections {
+			v := got.WordLists[l][c]
+			if len(v.Files) == 0 || v.Words == 0 {
+				t.Errorf("/version word list %s/%s = %+v, want files and words", l, c, v)
+			}
+		}
+	}
+}
+
+func Test_awaitLoaded(t *testing.T) {
+	loaded := &atomic.Bool{}
+	h := awaitLoaded(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}), loaded)
+
+	tests := []struct {
+		name   string
+		path   string
+		loaded bool
+		want   int
+	}{
+		{"game waits", "/", false, http.StatusServiceUnavailable},
+		{"probe passes", "/readyz", false, http.StatusOK},
+		{"static passes", "/static/generated/output.css", false, http.StatusOK},
+		{"game after loading", "/", true, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loaded.Store(tt.loaded)
+
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			if w.Code != tt.want {
+				t.Errorf("awaitLoaded() %s = %d, want %d", tt.path, w.Code, tt.want)
+			}
+		})
+	}
+}
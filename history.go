@@ -0,0 +1,216 @@
+package main
+
+import (
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MAX_HISTORY bounds the game records kept per session, the oldest get dropped.
+const MAX_HISTORY = 50
+
+type gameOutcome string
+
+const (
+	OUTCOME_SOLVED    gameOutcome = "solved"
+	OUTCOME_LOST      gameOutcome = "lost"
+	OUTCOME_ABANDONED gameOutcome = "abandoned" // new game started before the puzzle was finished
+	OUTCOME_TIMEOUT   gameOutcome = "timeout"   // ran out of time in a timed mode
+)
+
+// hint is a help the player revealed during a game.
+type hint string
+
+const (
+	HINT_DUPLICATES hint = "duplicates"
+	HINT_SOLUTION   hint = "solution"
+)
+
+func NewHint(maybeHint string) (hint, bool) {
+	switch hint(maybeHint) {
+	case HINT_DUPLICATES, HINT_SOLUTION:
+		return hint(maybeHint), true
+	default:
+		return "", false
+	}
+}
+
+type gameRecord struct {
+	ID         string
+	Language   language
+	Mode       gameMode
+	Solution   word
+	Guesses    [6]wordGuess
+	StartedAt  time.Time
+	FinishedAt time.Time
+	Outcome    gameOutcome
+	HintsUsed  []hint
+	Boards     boards // MODE_MULTI only, Solution and Guesses stay empty
+}
+
+func (gr gameRecord) LanguageConfig() languageConfig {
+	return languages.config(gr.Language)
+}
+
+// Attempts counts the guessed rows.
+func (gr gameRecord) Attempts() int {
+	if len(gr.Boards) > 0 {
+		return gr.Boards.Rounds()
+	}
+
+	return int(puzzle{Guesses: gr.Guesses}.activeRow())
+}
+
+func (gr gameRecord) Solutions() []word {
+	if len(gr.Boards) > 0 {
+		return gr.Boards.solutions()
+	}
+
+	return []word{gr.Solution}
+}
+
+// IsReplayable tells whether the game can be replayed row by row, multi
+// board games can't.
+func (gr gameRecord) IsReplayable() bool {
+	return len(gr.Boards) == 0
+}
+
+func (gr gameRecord) Duration() time.Duration {
+	return gr.FinishedAt.Sub(gr.StartedAt).Round(time.Second)
+}
+
+// recordGame adds the active game to the history.
+func (s *session) recordGame(o gameOutcome) {
+	appMetrics.gameFinished(s.language, o)
+
+	gr := gameRecord{
+		ID:         uuid.NewString(),
+		Language:   s.language,
+		Mode:       s.Mode(),
+		Solution:   s.activeSolutionWord,
+		Guesses:    s.lastEvaluatedAttempt.Guesses,
+		StartedAt:  s.gameStartedAt,
+		FinishedAt: time.Now(),
+		Outcome:    o,
+		HintsUsed:  slices.Clone(s.hintsUsed),
+	}
+	if s.Mode() == MODE_MULTI {
+		// boards are never modified in place, see boards.guess
+		gr.Solution = word{}
+		gr.Boards = s.boards
+	}
+
+	history := append(slices.Clone(s.history), gr)
+	if len(history) > MAX_HISTORY {
+		history = history[len(history)-MAX_HISTORY:]
+	}
+
+	s.history = history
+}
+
+// startGame resets the per game state for a new solution.
+func (s *session) startGame(solution word) {
+	s.activeSolutionWord = solution
+	s.lastEvaluatedAttempt = puzzle{}
+	s.gameStartedAt = time.Now()
+	s.hintsUsed = nil
+	s.timedOut = false
+	s.customPuzzleID = ""
+}
+
+// hintData is what a hint reveals, it's only rendered by POST /hint once the
+// hint is recorded, never shipped hidden in the page. There is one entry per
+// board, a single one outside MODE_MULTI.
+type hintData struct {
+	Locale        string
+	Solutions     []string
+	HasDuplicates []bool
+}
+
+// revealHint records the hint and returns what it reveals.
+func (s *session) revealHint(h hint) hintData {
+	s.useHint(h)
+
+	solutions := []word{s.activeSolutionWord}
+	if s.Mode() == MODE_MULTI {
+		solutions = s.boards.solutions()
+	}
+
+	hd := hintData{Locale: s.uiLocale}
+	for _, sw := range solutions {
+		switch h {
+		case HINT_SOLUTION:
+			hd.Solutions = append(hd.Solutions, sw.String())
+		case HINT_DUPLICATES:
+			hd.HasDuplicates = append(hd.HasDuplicates, sw.hasDublicateLetters())
+		}
+	}
+
+	return hd
+}
+
+func (s *session) useHint(h hint) {
+	if slices.Contains(s.hintsUsed, h) {
+		return
+	}
+
+	s.hintsUsed = append(slices.Clone(s.hintsUsed), h)
+}
+
+// History returns the game records, newest first.
+func (s session) History() []gameRecord {
+	h := slices.Clone(s.history)
+	slices.Reverse(h)
+
+	return h
+}
+
+func (s session) historyRecord(id string) (gameRecord, bool) {
+	i := slices.IndexFunc(s.history, func(gr gameRecord) bool {
+		return gr.ID == id
+	})
+	if i == -1 {
+		return gameRecord{}, false
+	}
+
+	return s.history[i], true
+}
+
+type historyData struct {
+	Locale  string
+	Records []gameRecord
+}
+
+// replayData shows a recorded game up to row Step.
+type replayData struct {
+	Locale string
+	Record gameRecord
+	Step   int
+}
+
+func newReplayData(locale string, gr gameRecord, step int) replayData {
+	step = max(0, min(step, gr.Attempts()))
+
+	return replayData{
+		Locale: locale,
+		Record: gr,
+		Step:   step,
+	}
+}
+
+func (rd replayData) PrevStep() int {
+	return max(0, rd.Step-1)
+}
+
+func (rd replayData) NextStep() int {
+	return min(rd.Step+1, rd.Record.Attempts())
+}
+
+func (rd replayData) HasPrev() bool {
+	return rd.Step > 0
+}
+
+func (rd replayData) HasNext() bool {
+	return rd.Step < rd.Record.Attempts()
+}
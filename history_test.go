@@ -0,0 +1,118 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func Test_session_recordGame(t *testing.T) {
+	s := session{language: LANG_EN}
+
+	for i := 0; i < MAX_HISTORY+3; i++ {
+		s.startGame(word{'w', 'o', 'r', 'd', rune('a' + i%26)})
+		s.lastEvaluatedAttempt.Guesses[0] = evaluateGuessedWord(normaliser{}, word{'m', 'a', 't', 'c', 'h'}, s.activeSolutionWord)
+		s.useHint(HINT_SOLUTION)
+		s.useHint(HINT_SOLUTION)
+		s.recordGame(OUTCOME_ABANDONED)
+	}
+
+	if len(s.history) != MAX_HISTORY {
+		t.Fatalf("recordGame() should bound the history to %d, got %d", MAX_HISTORY, len(s.history))
+	}
+
+	newest := s.History()[0]
+	if newest.Solution != s.activeSolutionWord || newest.Attempts() != 1 || newest.Outcome != OUTCOME_ABANDONED {
+		t.Errorf("History() should return the newest record first, got %v", newest)
+	}
+	if len(newest.HintsUsed) != 1 {
+		t.Errorf("useHint() should record a hint only once, got %v", newest.HintsUsed)
+	}
+
+	copied := s
+	s.recordGame(OUTCOME_LOST)
+	if copied.History()[0].ID == s.History()[0].ID {
+		t.Errorf("recordGame() must not modify copies of the session")
+	}
+
+	gr, ok := s.historyRecord(newest.ID)
+	if !ok || gr.ID != newest.ID {
+		t.Errorf("historyRecord(%s) = %v, %v", newest.ID, gr, ok)
+	}
+	if _, ok := s.historyRecord("unknown"); ok {
+		t.Errorf("historyRecord() should not find unknown ids")
+	}
+}
+
+func Test_newReplayData(t *testing.T) {
+	gr := gameRecord{}
+	gr.Guesses[0] = evaluateGuessedWord(normaliser{}, word{'r', 'o', 'a', 't', 'e'}, word{'m', 'a', 't', 'c', 'h'})
+	gr.Guesses[1] = evaluateGuessedWord(normaliser{}, word{'m', 'a', 't', 'c', 'h'}, word{'m', 'a', 't', 'c', 'h'})
+
+	tests := []struct {
+		step                   int
+		wantStep               int
+		wantPrev, wantNext     int
+		wantHasPrev, wantHasNe bool
+	}{
+		{-1, 0, 0, 1, false, true},
+		{0, 0, 0, 1, false, true},
+		{1, 1, 0, 2, true, true},
+		{2, 2, 1, 2, true, false},
+		{7, 2, 1, 2, true, false},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("step %d", tt.step), func(t *testing.T) {
+			rd := newReplayData("en", gr, tt.step)
+			if rd.Step != tt.wantStep || rd.PrevStep() != tt.wantPrev || rd.NextStep() != tt.wantNext {
+				t.Errorf("newReplayData() step=%d prev=%d next=%d, want %d %d %d", rd.Step, rd.PrevStep(), rd.NextStep(), tt.wantStep, tt.wantPrev, tt.wantNext)
+			}
+			if rd.HasPrev() != tt.wantHasPrev || rd.HasNext() != tt.wantHasNe {
+				t.Errorf("newReplayData() hasPrev=%t hasNext=%t", rd.HasPrev(), rd.HasNext())
+			}
+		})
+	}
+}
+
+func Test_session_revealHint(t *testing.T) {
+	s := session{uiLocale: "en", activeSolutionWord: word{'h', 'e', 'l', 'l', 'o'}}
+
+	if got, want := s.revealHint(HINT_DUPLICATES), (hintData{Locale: "en", HasDuplicates: []bool{true}}); !reflect.DeepEqual(got, want) {
+		t.Errorf("revealHint(duplicates) = %+v, want %+v without the solution", got, want)
+	}
+	if got, want := s.revealHint(HINT_SOLUTION), (hintData{Locale: "en", Solutions: []string{"hello"}}); !reflect.DeepEqual(got, want) {
+		t.Errorf("revealHint(solution) = %+v, want %+v", got, want)
+	}
+	if len(s.hintsUsed) != 2 {
+		t.Errorf("revealHint() should record the hints before revealing them, got %v", s.hintsUsed)
+	}
+
+	multi := session{uiLocale: "en", mode: MODE_MULTI, activeSolutionWord: word{'h', 'e', 'l', 'l', 'o'}}
+	multi.boards = boards{{Solution: word{'h', 'e', 'l', 'l', 'o'}}, {Solution: word{'m', 'a', 't', 'c', 'h'}}}
+	if got, want := multi.revealHint(HINT_DUPLICATES), (hintData{Locale: "en", HasDuplicates: []bool{true, false}}); !reflect.DeepEqual(got, want) {
+		t.Errorf("revealHint(duplicates) in multi mode = %+v, want one per board %+v", got, want)
+	}
+	if got, want := multi.revealHint(HINT_SOLUTION), (hintData{Locale: "en", Solutions: []string{"hello", "match"}}); !reflect.DeepEqual(got, want) {
+		t.Errorf("revealHint(solution) in multi mode = %+v, want one per board %+v", got, want)
+	}
+}
+
+// the help page must not carry hint values hidden by css, only POST /hint reveals them
+func Test_help_doesNotShipHints(t *testing.T) {
+	tmpl := template.Must(template.New("index.html.tmpl").Funcs(funcMap).Funcs(translateFuncs(translations, false)).ParseFS(fs, templateFiles...))
+
+	s := session{uiLocale: "en", activeSolutionWord: word{'q', 'u', 'i', 'z', 'z'}}
+	p := s.lastEvaluatedAttempt
+	p.Debug = s.activeSolutionWord.String()
+
+	buf := bytes.Buffer{}
+	if err := tmpl.ExecuteTemplate(&buf, "help", FormData{}.New(s, p)); err != nil {
+		t.Fatalf("executing template failed: %s", err)
+	}
+	if strings.Contains(buf.String(), "quizz") {
+		t.Errorf("help renders the solution")
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"html/template"
+	"log/slog"
+
+	"github.com/pandorasNox/lettr/pkg/i18n"
+)
+
+// translations are the ui message catalogues, the ui locale is chosen
+// independently of the puzzle language.
+var translations = mustLoadTranslations()
+
+func mustLoadTranslations() *i18n.Catalogue {
+	c, err := i18n.Load(fs, "configs/i18n", "en")
+	if err != nil {
+		panic(err)
+	}
+
+	return c
+}
+
+// translateFuncs provides the "T" (text) and "TN" (plural) template funcs.
+// Strict funcs fail the template execution on missing keys, which is what
+// the tests use, otherwise missing keys get logged and fall back to english.
+func translateFuncs(c *i18n.Catalogue, strict bool) template.FuncMap {
+	handle := func(text string, err error) (string, error) {
+		if err == nil {
+			return text, nil
+		}
+		if strict {
+			return "", err
+		}
+
+		slog.Warn("translation issue", "err", err)
+		return text, nil
+	}
+
+	return template.FuncMap{
+		"T": func(locale string, key string, args ...any) (string, error) {
+			return handle(c.Translate(locale, key, args...))
+		},
+		"TN": func(locale string, key string, n int, args ...any) (string, error) {
+			return handle(c.TranslatePlural(locale, key, n, args...))
+		},
+	}
+}
+
+// tr translates texts written by handlers directly, e.g. plain text htmx responses.
+func tr(locale string, key string, args ...any) string {
+	text, err := translations.Translate(locale, key, args...)
+	if err != nil {
+		slog.Warn("translation issue", "err", err)
+	}
+
+	return text
+}
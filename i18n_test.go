@@ -0,0 +1,131 @@
+package main
+
+import (
+	"html/template"
+	"io"
+	iofs "io/fs"
+	"regexp"
+	"slices"
+	"testing"
+	"time"
+)
+
+func Test_translations_complete(t *testing.T) {
+	want := translations.Keys(translations.DefaultLocale())
+
+	for _, locale := range translations.Locales() {
+		got := translations.Keys(locale)
+		for _, k := range want {
+			if !slices.Contains(got, k) {
+				t.Errorf("locale '%s' misses key '%s'", locale, k)
+			}
+		}
+		for _, k := range got {
+			if !slices.Contains(want, k) {
+				t.Errorf("locale '%s' has key '%s' unknown to the default locale", locale, k)
+			}
+		}
+	}
+}
+
+func Test_templates_keysExist(t *testing.T) {
+	keyRe := regexp.MustCompile(`\{\{-?\s*TN?\s+\S+\s+"([^"]+)"`)
+
+	for _, f := range templateFiles {
+		b, err := iofs.ReadFile(fs, f)
+		if err != nil {
+			t.Fatalf("reading template failed: %s", err)
+		}
+
+		for _, m := range keyRe.FindAllStringSubmatch(string(b), -1) {
+			for _, locale := range translations.Locales() {
+				if !slices.Contains(translations.Keys(locale), m[1]) {
+					t.Errorf("template '%s' uses key '%s' missing in locale '%s'", f, m[1], locale)
+				}
+			}
+		}
+	}
+}
+
+func Test_templates_render(t *testing.T) {
+	tmpl, err := template.New("index.html.tmpl").Funcs(funcMap).Funcs(translateFuncs(translations, true)).ParseFS(fs, templateFiles...)
+	if err != nil {
+		t.Fatalf("parsing templates failed: %s", err)
+	}
+
+	for _, locale := range translations.Locales() {
+		s := session{language: LANG_DE, uiLocale: locale, pastWords: []word{{'m', 'a', 't', 'c', 'h'}}}
+
+		running := FormData{}.New(s, puzzle{})
+		solved := FormData{}.New(s, puzzle{})
+		solved.IsSolved = true
+		lost := FormData{}.New(s, puzzle{})
+		lost.IsLoose = true
+		timedOut := FormData{}.New(session{mode: MODE_TIMED, uiLocale: locale, timedOut: true, stats: statistics{TimedPlayed: 2, TimedSolved: 1, TimedFastest: time.Minute}}, puzzle{})
+		speedrunning := FormData{}.New(session{mode: MODE_SPEEDRUN, uiLocale: locale, speedrun: newSpeedrun(3, time.Now())}, puzzle{})
+		multi := FormData{}.New(session{mode: MODE_MULTI, uiLocale: locale, boards: boards{{Solution: word{'m', 'a', 't', 'c', 'h'}, Attempts: 7}, {Solution: word{'h', 'o', 'u', 's', 'e'}, Attempts: 7}}}, puzzle{})
+		multiLost := multi
+		multiLost.IsLoose = true
+		speedrunOver := FormData{}.New(session{mode: MODE_SPEEDRUN, uiLocale: locale, speedrun: speedrun{Minutes: 5, Solved: 1, IsOver: true}}, puzzle{})
+
+		renders := []struct {
+			name string
+			data any
+		}{
+			{"index.html.tmpl", running},
+			{"lettr-form", solved},
+			{"lettr-form", lost},
+			{"lettr-form", timedOut},
+			{"lettr-form", speedrunning},
+			{"lettr-form", speedrunOver},
+			{"lettr-form", multi},
+			{"lettr-form", multiLost},
+			{"room-lobby", roomView{Locale: locale}},
+			{"room", roomView{Code: "ABCDE", Locale: locale, Round: 1, Me: roomPlayerView{Name: "me"}}},
+			{"room", roomView{Code: "ABCDE", Locale: locale, Round: 2, Me: roomPlayerView{Name: "me", Rematch: true}, Opponents: []roomPlayerView{{Name: "you", Wins: 1}}, IsOver: true, Winner: "you"}},
+			{"history", historyData{Locale: locale, Records: []gameRecord{{ID: "id", Language: LANG_EN, Mode: MODE_MULTI, Outcome: OUTCOME_SOLVED, Boards: multi.Boards}}}},
+			{"help", running},
+			{"help", FormData{}.New(session{uiLocale: locale}, puzzle{})},
+			{"hint-duplicates", hintData{Locale: locale, HasDuplicates: []bool{true}}},
+			{"hint-solution", hintData{Locale: locale, Solutions: []string{"match"}}},
+			{"not-in-word-list", notInWordListData{word{'g', 'a', 'm', 'e', 'r'}, locale}},
+			{"definition-panel", definitionData{Word: word{'m', 'a', 't', 'c', 'h'}, Locale: locale}},
+			{"history", historyData{Locale: locale}},
+			{"puzzles", puzzlesData{Locale: locale, Language: languages.config(LANG_EN)}},
+			{"account", accountData{Locale: locale}},
+			{"account", accountData{Locale: locale, Error: "error"}},
+			{"account", accountData{Locale: locale, Sent: "me@example.com"}},
+			{"account", accountData{Locale: locale, Email: "me@example.com", Records: 3}},
+			{"account-verify.html.tmpl", accountVerifyData{Locale: locale, Token: "token"}},
+			{"leaderboard", leaderboardData{Locale: locale, Language: languages.config(LANG_EN), Date: "2024-01-02", IsToday: true}},
+			{"leaderboard", leaderboardData{Locale: locale, Language: languages.config(LANG_DE), Error: "error", Date: "2024-01-01", PrevDate: "2023-12-31", NextDate: "2024-01-02",
+				Player: player{Name: "me"}, Played: true, Result: dailyResult{Outcome: OUTCOME_SOLVED, Attempts: 3},
+				Rankings: []groupRanking{
+					{group: group{Key: "team", Name: "Team"}, Entries: []rankingEntry{{Rank: 1, Name: "me", IsMe: true, dailyResult: dailyResult{Outcome: OUTCOME_SOLVED, Attempts: 3, Hints: 1}}, {Rank: 2, Name: "you", dailyResult: dailyResult{Outcome: OUTCOME_LOST, Attempts: 6}}}},
+					{group: group{Key: "empty", Name: "Empty"}},
+				}}},
+			{"puzzles", puzzlesData{Locale: locale, Language: languages.config(LANG_DE), Error: "error", Created: "http://localhost/p/token", Puzzles: []createdPuzzleData{
+				{createdPuzzle: createdPuzzle{customPuzzle: customPuzzle{ID: "id", Language: LANG_DE, Solution: word{'h', 'a', 'u', 's', 'e'}}}},
+				{createdPuzzle: createdPuzzle{customPuzzle: customPuzzle{ID: "id", Language: LANG_EN}}, Results: []customPuzzleResult{{Outcome: OUTCOME_SOLVED}, {Outcome: OUTCOME_ABANDONED}}},
+			}}},
+		}
+		for _, o := range []gameOutcome{OUTCOME_SOLVED, OUTCOME_LOST, OUTCOME_ABANDONED, OUTCOME_TIMEOUT} {
+			gr := gameRecord{ID: "id", Language: LANG_EN, Outcome: o, HintsUsed: []hint{HINT_SOLUTION}}
+			renders = append(renders,
+				struct {
+					name string
+					data any
+				}{"history", historyData{Locale: locale, Records: []gameRecord{gr}}},
+				struct {
+					name string
+					data any
+				}{"replay", newReplayData(locale, gr, 0)},
+			)
+		}
+		for _, r := range renders {
+			if err := tmpl.ExecuteTemplate(io.Discard, r.name, r.data); err != nil {
+				t.Errorf("rendering '%s' for locale '%s' failed: %s", r.name, locale, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	iofs "io/fs"
+	"slices"
+	"strings"
+
+	"github.com/pandorasNox/lettr/pkg/wordlist"
+)
+
+// languageConfig describes everything needed to offer a puzzle language.
+// Adding a language is a change to configs/languages.json, see README.
+type languageConfig struct {
+	Code           language                    `json:"code"`
+	Name           string                      `json:"name"`
+	Flag           string                      `json:"flag"`     // url of the flag icon
+	Alphabet       string                      `json:"alphabet"` // lower case letters allowed in guesses
+	Umlauts        wordlist.UmlautPolicy       `json:"umlauts"`  // how ä, ö, ü and ß map onto the alphabet
+	KeyboardLayout string                      `json:"keyboardLayout"`
+	WordLists      map[wordCollection][]string `json:"wordLists"`
+}
+
+type languageRegistry struct {
+	Languages []languageConfig `json:"languages"`
+}
+
+var languages = mustLoadLanguageRegistry(fs, "configs/languages.json")
+
+func mustLoadLanguageRegistry(fsys iofs.FS, path string) languageRegistry {
+	lr, err := loadLanguageRegistry(fsys, path)
+	if err != nil {
+		panic(err)
+	}
+
+	return lr
+}
+
+func loadLanguageRegistry(fsys iofs.FS, path string) (languageRegistry, error) {
+	b, err := iofs.ReadFile(fsys, path)
+	if err != nil {
+		return languageRegistry{}, fmt.Errorf("language registry failed reading '%s': %s", path, err)
+	}
+
+	lr := languageRegistry{}
+	err = json.Unmarshal(b, &lr)
+	if err != nil {
+		return languageRegistry{}, fmt.Errorf("language registry failed parsing '%s': %s", path, err)
+	}
+
+	return lr, lr.validate()
+}
+
+func (lr languageRegistry) validate() error {
+	if len(lr.Languages) == 0 {
+		return fmt.Errorf("language registry is empty")
+	}
+
+	if _, ok := lr.get(LANG_EN); !ok {
+		return fmt.Errorf("language registry misses the default language: '%s'", LANG_EN)
+	}
+
+	seen := map[language]bool{}
+	for _, lc := range lr.Languages {
+		if lc.Code == "" || seen[lc.Code] {
+			return fmt.Errorf("language registry has empty or duplicate code: '%s'", lc.Code)
+		}
+		seen[lc.Code] = true
+
+		if lc.Name == "" || lc.Alphabet == "" {
+			return fmt.Errorf("language registry entry '%s' misses name or alphabet", lc.Code)
+		}
+
+		if _, err := wordlist.NewUmlautPolicy(string(lc.Umlauts)); err != nil {
+			return fmt.Errorf("language registry entry '%s': %s", lc.Code, err)
+		}
+
+		if _, ok := keyboardLayouts[lc.KeyboardLayout]; !ok {
+			return fmt.Errorf("language registry entry '%s' has unknown keyboard layout: '%s'", lc.Code, lc.KeyboardLayout)
+		}
+
+		if len(lc.WordLists[WC_ALL]) == 0 {
+			return fmt.Errorf("language registry entry '%s' has no '%s' word lists", lc.Code, WC_ALL)
+		}
+
+		for c := range lc.WordLists {
+			if _, err := NewWordCollection(string(c)); err != nil {
+				return fmt.Errorf("language registry entry '%s': %s", lc.Code, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (lr languageRegistry) get(l language) (languageConfig, bool) {
+	i := slices.IndexFunc(lr.Languages, func(lc languageConfig) bool {
+		return lc.Code == l
+	})
+	if i == -1 {
+		return languageConfig{}, false
+	}
+
+	return lr.Languages[i], true
+}
+
+// config returns the config of l, falling back to the default language.
+func (lr languageRegistry) config(l language) languageConfig {
+	lc, ok := lr.get(l)
+	if !ok {
+		lc, _ = lr.get(LANG_EN)
+	}
+
+	return lc
+}
+
+func (lr languageRegistry) codes() []language {
+	return Map(lr.Languages, func(lc languageConfig) language { return lc.Code })
+}
+
+func (lr languageRegistry) filePaths() map[language]map[wordCollection][]string {
+	out := make(map[language]map[wordCollection][]string, len(lr.Languages))
+	for _, lc := range lr.Languages {
+		out[lc.Code] = lc.WordLists
+	}
+
+	return out
+}
+
+// InputPattern is the html input pattern accepting a single letter of the alphabet.
+func (lc languageConfig) InputPattern() string {
+	return fmt.Sprintf("[%s%s]", lc.Alphabet, strings.ToUpper(lc.Alphabet))
+}
+
+// inAlphabet reports whether w only consists of letters of the language.
+func (lc languageConfig) inAlphabet(w word) bool {
+	return wordlist.InAlphabet(w.String(), lc.Alphabet, lc.Umlauts)
+}
+
+// InputExpansions lists letters the client has to expand into several
+// letters before submitting, e.g. "ä:ae ß:ss".
+func (lc languageConfig) InputExpansions() string {
+	if lc.Umlauts != wordlist.UMLAUTS_TRANSLITERATE {
+		return ""
+	}
+
+	out := []string{}
+	for r, t := range wordlist.Transliterations {
+		out = append(out, fmt.Sprintf("%c:%s", r, t))
+	}
+	slices.Sort(out)
+
+	return strings.Join(out, " ")
+}
+
+func (lc languageConfig) normaliser() normaliser {
+	return normaliser{umlauts: lc.Umlauts}
+}
+
+// normaliser maps word list entries and guesses of a language onto the same
+// word, so lookups and evaluation agree on casing and umlauts.
+type normaliser struct {
+	umlauts wordlist.UmlautPolicy
+}
+
+// Word normalises s, e.g. "Spaß" becomes "spass" when transliterating.
+func (n normaliser) Word(s string) (word, error) {
+	ns, ok := wordlist.Normalise(s, n.umlauts)
+	if !ok {
+		return word{}, fmt.Errorf("word not allowed by umlaut policy '%s': '%s'", n.umlauts, s)
+	}
+
+	return toWord(ns)
+}
+
+// Guess normalises a guessed word like Word does list entries. A guess Word
+// rejects, e.g. one a transliteration would make too long, is only lower
+// cased, it is in no list and fails the lookup.
+func (n normaliser) Guess(w word) word {
+	nw, err := n.Word(w.String())
+	if err != nil {
+		return w.ToLower()
+	}
+
+	return nw
+}
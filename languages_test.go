@@ -0,0 +1,234 @@
+package main
+
+import (
+	iofs "io/fs"
+	"reflect"
+	"testing"
+	"testing/fstest"
+
+	"github.com/pandorasNox/lettr/pkg/wordlist"
+)
+
+func Test_loadLanguageRegistry(t *testing.T) {
+	valid := `{"languages": [{"code": "en", "name": "English", "flag": "/f.svg", "alphabet": "abc", "umlauts": "drop", "keyboardLayout": "qwerty", "wordLists": {"wc_all": ["a.txt"]}}]}`
+
+	tests := []struct {
+		name    string
+		content string
+		wantErr bool
+	}{
+		{"valid", valid, false},
+		{"invalid json", `{"languages": [`, true},
+		{"empty", `{"languages": []}`, true},
+		{
+			"missing default language",
+			`{"languages": [{"code": "fr", "name": "Français", "alphabet": "abc", "umlauts": "drop", "keyboardLayout": "qwerty", "wordLists": {"wc_all": ["a.txt"]}}]}`,
+			true,
+		},
+		{
+			"duplicate code",
+			`{"languages": [
+				{"code": "en", "name": "English", "alphabet": "abc", "umlauts": "drop", "keyboardLayout": "qwerty", "wordLists": {"wc_all": ["a.txt"]}},
+				{"code": "en", "name": "English", "alphabet": "abc", "umlauts": "drop", "keyboardLayout": "qwerty", "wordLists": {"wc_all": ["a.txt"]}}
+			]}`,
+			true,
+		},
+		{
+			"unknown umlaut policy",
+			`{"languages": [{"code": "en", "name": "English", "alphabet": "abc", "umlauts": "nope", "keyboardLayout": "qwerty", "wordLists": {"wc_all": ["a.txt"]}}]}`,
+			true,
+		},
+		{
+			"unknown keyboard layout",
+			`{"languages": [{"code": "en", "name": "English", "alphabet": "abc", "umlauts": "drop", "keyboardLayout": "nope", "wordLists": {"wc_all": ["a.txt"]}}]}`,
+			true,
+		},
+		{
+			"no wc_all lists",
+			`{"languages": [{"code": "en", "name": "English", "alphabet": "abc", "umlauts": "drop", "keyboardLayout": "qwerty", "wordLists": {"wc_common": ["a.txt"]}}]}`,
+			true,
+		},
+		{
+			"unknown collection",
+			`{"languages": [{"code": "en", "name": "English", "alphabet": "abc", "umlauts": "drop", "keyboardLayout": "qwerty", "wordLists": {"wc_all": ["a.txt"], "wc_nope": ["a.txt"]}}]}`,
+			true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fsys := fstest.MapFS{"languages.json": {Data: []byte(tt.content)}}
+
+			_, err := loadLanguageRegistry(fsys, "languages.json")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("loadLanguageRegistry() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_languages_embedded(t *testing.T) {
+	for _, lc := range languages.Languages {
+		l, err := NewLang(string(lc.Code))
+		if err != nil || l != lc.Code {
+			t.Errorf("NewLang(%q) = %v, %v", lc.Code, l, err)
+		}
+
+		for c, paths := range lc.WordLists {
+			for _, p := range paths {
+				if _, err := iofs.Stat(fs, p); err != nil {
+					t.Errorf("word list of '%s' (%s) not embedded: %s", lc.Code, c, err)
+				}
+			}
+		}
+	}
+
+	l, err := NewLang("xx")
+	if err == nil || l != LANG_EN {
+		t.Errorf("NewLang(\"xx\") = %v, %v, want fallback to '%s' with error", l, err, LANG_EN)
+	}
+}
+
+func Test_normaliser_Word(t *testing.T) {
+	transliterate := normaliser{umlauts: wordlist.UMLAUTS_TRANSLITERATE}
+	keep := normaliser{umlauts: wordlist.UMLAUTS_KEEP}
+	drop := normaliser{umlauts: wordlist.UMLAUTS_DROP}
+
+	tests := []struct {
+		name    string
+		n       normaliser
+		in      string
+		want    word
+		wantErr bool
+	}{
+		{"plain lower case", transliterate, "tisch", word{'t', 'i', 's', 'c', 'h'}, false},
+		{"upper case", transliterate, "TISCH", word{'t', 'i', 's', 'c', 'h'}, false},
+		{"surrounding space", transliterate, " tisch\n", word{'t', 'i', 's', 'c', 'h'}, false},
+		{"umlaut at start", transliterate, "Übel", word{'u', 'e', 'b', 'e', 'l'}, false},
+		{"upper case umlaut", transliterate, "ÄSTE", word{'a', 'e', 's', 't', 'e'}, false},
+		{"eszett", transliterate, "Spaß", word{'s', 'p', 'a', 's', 's'}, false},
+		{"capital eszett", transliterate, "GROẞ", word{'g', 'r', 'o', 's', 's'}, false},
+		{"transliteration too long", transliterate, "Größe", word{}, true},
+		{"transliteration too short", transliterate, "Bär", word{}, true},
+		{"keep umlauts", keep, "Größe", word{'g', 'r', 'ö', 'ß', 'e'}, false},
+		{"keep upper case umlauts", keep, "GRÜNE", word{'g', 'r', 'ü', 'n', 'e'}, false},
+		{"drop umlauts", drop, "Grüne", word{}, true},
+		{"drop keeps plain words", drop, "Match", word{'m', 'a', 't', 'c', 'h'}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.n.Word(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("normaliser.Word(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("normaliser.Word(%q) = %v, want %v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_normaliser_Guess(t *testing.T) {
+	transliterate := normaliser{umlauts: wordlist.UMLAUTS_TRANSLITERATE}
+	keep := normaliser{umlauts: wordlist.UMLAUTS_KEEP}
+
+	tests := []struct {
+		name string
+		n    normaliser
+		in   word
+		want word
+	}{
+		{"upper case", transliterate, word{'T', 'I', 'S', 'C', 'H'}, word{'t', 'i', 's', 'c', 'h'}},
+		{"kept umlaut", keep, word{'Ö', 'F', 'E', 'N', 'S'}, word{'ö', 'f', 'e', 'n', 's'}},
+		{"too long once transliterated", transliterate, word{'Ü', 'B', 'E', 'L', 'N'}, word{'ü', 'b', 'e', 'l', 'n'}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.n.Guess(tt.in); got != tt.want {
+				t.Errorf("normaliser.Guess(%v) = %v, want %v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_languageConfig_inAlphabet(t *testing.T) {
+	en := languageConfig{Alphabet: "abcdefghijklmnopqrstuvwxyz", Umlauts: wordlist.UMLAUTS_DROP}
+	keep := languageConfig{Alphabet: "abcdefghijklmnopqrstuvwxyz", Umlauts: wordlist.UMLAUTS_KEEP}
+
+	tests := []struct {
+		name string
+		lc   languageConfig
+		in   string
+		want bool
+	}{
+		{"letters", en, "Match", true},
+		{"digits", en, "12345", false},
+		{"markup", en, "<b>x>", false},
+		{"embedded newline", en, "ab\ncd", false},
+		{"kept umlauts", keep, "Größe", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, err := tt.lc.normaliser().Word(tt.in)
+			if err != nil {
+				t.Fatalf("normaliser.Word(%q) error = %v", tt.in, err)
+			}
+			if got := tt.lc.inAlphabet(w); got != tt.want {
+				t.Errorf("inAlphabet(%q) = %v, want %v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_wordDatabase_Exists_normalised(t *testing.T) {
+	wdb := wordDatabase{}
+	wdb.Init(nil, nil)
+	wdb.Replace(LANG_DE, WC_ALL, []word{{'s', 'p', 'a', 's', 's'}, {'u', 'e', 'b', 'e', 'l'}})
+
+	tests := []struct {
+		name string
+		w    word
+		want bool
+	}{
+		{"transliterated", word{'s', 'p', 'a', 's', 's'}, true},
+		{"upper case", word{'U', 'E', 'B', 'E', 'L'}, true},
+		{"raw umlaut can't fit", word{'ü', 'b', 'e', 'l', 0}, false},
+		{"unknown", word{'t', 'i', 's', 'c', 'h'}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := wdb.Exists(LANG_DE, tt.w); got != tt.want {
+				t.Errorf("Exists(%v) = %v, want %v", tt.w, got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_wordDatabase_Init_skipsUnfitWords(t *testing.T) {
+	fs := fstest.MapFS{"de.txt": {Data: []byte("// metadata\ntisch\ngröße\nspaß\n")}}
+
+	wdb := wordDatabase{}
+	if err := wdb.Init(fs, map[language]map[wordCollection][]string{LANG_DE: {WC_ALL: {"de.txt"}}}); err != nil {
+		t.Fatalf("Init() error = %v, want words too long once transliterated to be skipped", err)
+	}
+
+	want := []word{{'s', 'p', 'a', 's', 's'}, {'t', 'i', 's', 'c', 'h'}}
+	if got := wdb.Words(LANG_DE, WC_ALL); !reflect.DeepEqual(got, want) {
+		t.Errorf("Words() = %v, want %v", got, want)
+	}
+}
+
+func Test_evaluateGuessedWord_umlauts(t *testing.T) {
+	n := normaliser{umlauts: wordlist.UMLAUTS_KEEP}
+
+	got := evaluateGuessedWord(n, word{'Ö', 'F', 'E', 'N', 'S'}, word{'f', 'ö', 'h', 'n', 'e'})
+	want := wordGuess{
+		{'ö', MatchVague},
+		{'f', MatchVague},
+		{'e', MatchVague},
+		{'n', MatchExact},
+		{'s', MatchNone},
+	}
+	if got != want {
+		t.Errorf("evaluateGuessedWord() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,600 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrNameRequired     = errors.New("display name required")
+	ErrInvalidName      = errors.New("invalid name")
+	ErrTooManyGroups    = errors.New("too many groups")
+	ErrUnknownPlayer    = errors.New("unknown player")
+	ErrDailyAlreadyDone = errors.New("daily puzzle already played")
+)
+
+// PLAYER_COOKIE_NAME identifies a player across sessions, it outlives the
+// session cookie by far, so daily results keep adding up on the leaderboard.
+const PLAYER_COOKIE_NAME = "player"
+const PLAYER_MAX_AGE_IN_SECONDS = 365 * 24 * 60 * 60
+
+const (
+	MAX_NAME_LENGTH              = 20
+	MAX_PLAYER_GROUPS            = 10
+	DAILY_RESULTS_RETENTION_DAYS = 30 // older results get dropped
+	DAILY_PUZZLE_ID_PREFIX       = "daily/"
+	LEADERBOARD_STORE_FILE       = "leaderboard.json"
+)
+
+type player struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Groups    []string  `json:"groups"` // group keys
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+func (p player) InGroup(key string) bool {
+	return slices.Contains(p.Groups, key)
+}
+
+// group is open to everyone who knows its name, the key is the name folded
+// to lower case.
+type group struct {
+	Key       string    `json:"key"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type dailyResult struct {
+	PlayerID   string        `json:"playerId"`
+	Outcome    gameOutcome   `json:"outcome"`
+	Attempts   int           `json:"attempts"`
+	Hints      int           `json:"hints"`
+	Duration   time.Duration `json:"duration"`
+	FinishedAt time.Time     `json:"finishedAt"`
+}
+
+// compareDailyResults orders solved before lost, then by attempts, hints and time.
+func compareDailyResults(a, b dailyResult) int {
+	if (a.Outcome == OUTCOME_SOLVED) != (b.Outcome == OUTCOME_SOLVED) {
+		if a.Outcome == OUTCOME_SOLVED {
+			return -1
+		}
+		return 1
+	}
+
+	if a.Attempts != b.Attempts {
+		return a.Attempts - b.Attempts
+	}
+
+	if a.Hints != b.Hints {
+		return a.Hints - b.Hints
+	}
+
+	return int(a.Duration.Round(time.Second) - b.Duration.Round(time.Second))
+}
+
+type leaderboardState struct {
+	Players   map[string]player                 `json:"players"`
+	Groups    map[string]group                  `json:"groups"`
+	Solutions map[string]string                 `json:"solutions"` // daily key -> solution
+	Results   map[string]map[string]dailyResult `json:"results"`   // daily key -> player id -> result
+	Started   map[string]map[string]time.Time   `json:"started"`   // daily key -> player id -> start, see Start
+}
+
+// leaderboard keeps players, groups and daily results. With a dir the state
+// is written to LEADERBOARD_STORE_FILE on every change, otherwise it lives
+// in memory only.
+type leaderboard struct {
+	mu    sync.Mutex
+	file  jsonFile
+	state leaderboardState
+}
+
+func newLeaderboard(dir string) (*leaderboard, error) {
+	lb := &leaderboard{file: jsonFile{dir, LEADERBOARD_STORE_FILE}, state: leaderboardState{
+		Players:   make(map[string]player),
+		Groups:    make(map[string]group),
+		Solutions: make(map[string]string),
+		Results:   make(map[string]map[string]dailyResult),
+		Started:   make(map[string]map[string]time.Time),
+	}}
+	if err := lb.file.Load(&lb.state); err != nil {
+		return nil, fmt.Errorf("leaderboard load failed: %s", err)
+	}
+	// files written before starts were tracked
+	if lb.state.Started == nil {
+		lb.state.Started = make(map[string]map[string]time.Time)
+	}
+
+	return lb, nil
+}
+
+// save writes the state to disk, see jsonFile. The caller must hold mu.
+func (lb *leaderboard) save() error {
+	if err := lb.file.Save(lb.state); err != nil {
+		return fmt.Errorf("leaderboard save failed: %s", err)
+	}
+
+	return nil
+}
+
+// cleanName trims and validates display and group names.
+func cleanName(name string) (string, error) {
+	name = strings.Join(strings.Fields(name), " ")
+	if name == "" {
+		return "", ErrNameRequired
+	}
+
+	if len([]rune(name)) > MAX_NAME_LENGTH {
+		return "", ErrInvalidName
+	}
+
+	for _, r := range name {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && !strings.ContainsRune(" -_.", r) {
+			return "", ErrInvalidName
+		}
+	}
+
+	return name, nil
+}
+
+func (lb *leaderboard) Player(id string) (player, bool) {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+
+	p, ok := lb.state.Players[id]
+	return p, ok
+}
+
+// SetName sets the display name of a player, unknown players get created.
+func (lb *leaderboard) SetName(id string, name string) (player, error) {
+	name, err := cleanName(name)
+	if err != nil {
+		return player{}, err
+	}
+
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+
+	p, ok := lb.state.Players[id]
+	if !ok {
+		p = player{ID: id, Groups: []string{}, CreatedAt: time.Now()}
+	}
+	p.Name = name
+	lb.state.Players[id] = p
+
+	return p, lb.save()
+}
+
+// JoinGroup adds the player to the group of the given name, the group is
+// created if it doesn't exist yet. Only named players can join groups.
+func (lb *leaderboard) JoinGroup(id string, name string) (player, error) {
+	name, err := cleanName(name)
+	if err != nil {
+		return player{}, err
+	}
+	key := strings.ToLower(name)
+
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+
+	p, ok := lb.state.Players[id]
+	if !ok || p.Name == "" {
+		return player{}, ErrUnknownPlayer
+	}
+
+	if p.InGroup(key) {
+		return p, nil
+	}
+
+	if len(p.Groups) >= MAX_PLAYER_GROUPS {
+		return player{}, ErrTooManyGroups
+	}
+
+	if _, ok := lb.state.Groups[key]; !ok {
+		lb.state.Groups[key] = group{Key: key, Name: name, CreatedAt: time.Now()}
+	}
+
+	p.Groups = append(slices.Clone(p.Groups), key)
+	lb.state.Players[id] = p
+
+	return p, lb.save()
+}
+
+func (lb *leaderboard) LeaveGroup(id string, key string) (player, error) {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+
+	p, ok := lb.state.Players[id]
+	if !ok {
+		return player{}, ErrUnknownPlayer
+	}
+
+	p.Groups = slices.DeleteFunc(slices.Clone(p.Groups), func(k string) bool {
+		return k == key
+	})
+	lb.state.Players[id] = p
+
+	return p, lb.save()
+}
+
+func (lb *leaderboard) Groups(keys []string) []group {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+
+	gs := make([]group, 0, len(keys))
+	for _, k := range keys {
+		if g, ok := lb.state.Groups[k]; ok {
+			gs = append(gs, g)
+		}
+	}
+
+	return gs
+}
+
+// dailyDate is the day of the daily puzzle, the same for all players around the world.
+func dailyDate(now time.Time) string {
+	return now.UTC().Format(time.DateOnly)
+}
+
+func dailyKey(date string, l language) string {
+	return date + "/" + string(l)
+}
+
+// DailySolution returns the solution of the daily puzzle. It is derived from
+// the date and remembered, later word list curation doesn't change it anymore.
+func (lb *leaderboard) DailySolution(date string, l language, wdb wordDatabase) (word, error) {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+
+	key := dailyKey(date, l)
+	if sol, ok := lb.state.Solutions[key]; ok {
+		return languages.config(l).normaliser().Word(sol)
+	}
+
+	pastSolutions := make([]word, 0, len(lb.state.Solutions))
+	for k, sol := range lb.state.Solutions {
+		if strings.HasSuffix(k, "/"+string(l)) {
+			w, _ := languages.config(l).normaliser().Word(sol)
+			pastSolutions = append(pastSolutions, w)
+		}
+	}
+
+	w := pickDailySolution(key, l, pastSolutions, wdb)
+	lb.state.Solutions[key] = w.String()
+
+	return w, lb.save()
+}
+
+// pickDailySolution picks a word by the hash of the daily key from the common
+// words (all words if there are none), avoiding banned and past daily solutions.
+func pickDailySolution(key string, l language, avoid []word, wdb wordDatabase) word {
+	candidates := wdb.Words(l, WC_COMMON)
+	if len(candidates) == 0 {
+		candidates = wdb.Words(l, WC_ALL)
+	}
+
+	candidates = slices.DeleteFunc(candidates, func(w word) bool {
+		return wdb.Has(l, WC_BANNED, w) || slices.Contains(avoid, w.ToLower())
+	})
+	if len(candidates) == 0 {
+		return wdb.RandomPickWithFallback(l, avoid, 0)
+	}
+
+	h := fnv.New64a()
+	h.Write([]byte(key))
+
+	return candidates[h.Sum64()%uint64(len(candidates))].ToLower()
+}
+
+// Report stores the result of a daily puzzle. Only the first result of a
+// player counts, replaying doesn't improve the rank.
+func (lb *leaderboard) Report(date string, l language, r dailyResult) error {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+
+	key := dailyKey(date, l)
+	byPlayer, ok := lb.state.Results[key]
+	if !ok {
+		byPlayer = make(map[string]dailyResult)
+		lb.state.Results[key] = byPlayer
+	}
+
+	if _, ok := byPlayer[r.PlayerID]; ok {
+		return ErrDailyAlreadyDone
+	}
+	byPlayer[r.PlayerID] = r
+
+	lb.prune(r.FinishedAt)
+
+	return lb.save()
+}
+
+// Start marks the daily puzzle as begun by the player. It returns when the
+// player began it first and whether that's now. A daily begun before, but no
+// longer in the session of the player, was abandoned, see abandonedDaily.
+func (lb *leaderboard) Start(date string, l language, playerID string, now time.Time) (time.Time, bool, error) {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+
+	key := dailyKey(date, l)
+	byPlayer, ok := lb.state.Started[key]
+	if !ok {
+		byPlayer = make(map[string]time.Time)
+		lb.state.Started[key] = byPlayer
+	}
+
+	if startedAt, ok := byPlayer[playerID]; ok {
+		return startedAt, false, nil
+	}
+	byPlayer[playerID] = now
+
+	return now, true, lb.save()
+}
+
+// abandonedDaily is the result of a daily puzzle left unfinished, it counts
+// as lost with every attempt used. Otherwise players could restart the daily
+// until it ranks well.
+func abandonedDaily(playerID string, startedAt time.Time, now time.Time) dailyResult {
+	return dailyResult{
+		PlayerID:   playerID,
+		Outcome:    OUTCOME_LOST,
+		Attempts:   len(puzzle{}.Guesses),
+		Duration:   now.Sub(startedAt),
+		FinishedAt: now,
+	}
+}
+
+// prune drops results older than DAILY_RESULTS_RETENTION_DAYS. The caller must hold mu.
+func (lb *leaderboard) prune(now time.Time) {
+	oldest := dailyDate(now.AddDate(0, 0, -DAILY_RESULTS_RETENTION_DAYS))
+	for key := range lb.state.Results {
+		if date, _, _ := strings.Cut(key, "/"); date < oldest {
+			delete(lb.state.Results, key)
+		}
+	}
+	for key := range lb.state.Started {
+		if date, _, _ := strings.Cut(key, "/"); date < oldest {
+			delete(lb.state.Started, key)
+		}
+	}
+}
+
+func (lb *leaderboard) Result(date string, l language, playerID string) (dailyResult, bool) {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+
+	r, ok := lb.state.Results[dailyKey(date, l)][playerID]
+	return r, ok
+}
+
+type rankingEntry struct {
+	Rank int
+	Name string
+	IsMe bool
+	dailyResult
+}
+
+func (e rankingEntry) Time() time.Duration {
+	return e.Duration.Round(time.Second)
+}
+
+// Ranking ranks the daily results of the members of a group. Equal results
+// share a rank.
+func (lb *leaderboard) Ranking(groupKey string, date string, l language, playerID string) []rankingEntry {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+
+	results := []dailyResult{}
+	for id, r := range lb.state.Results[dailyKey(date, l)] {
+		if lb.state.Players[id].InGroup(groupKey) {
+			results = append(results, r)
+		}
+	}
+
+	slices.SortFunc(results, func(a, b dailyResult) int {
+		if c := compareDailyResults(a, b); c != 0 {
+			return c
+		}
+		if c := a.FinishedAt.Compare(b.FinishedAt); c != 0 {
+			return c
+		}
+		// results come from a map, keep ties in a stable order
+		return strings.Compare(lb.state.Players[a.PlayerID].Name, lb.state.Players[b.PlayerID].Name)
+	})
+
+	entries := make([]rankingEntry, len(results))
+	for i, r := range results {
+		rank := i + 1
+		if i > 0 && compareDailyResults(results[i-1], r) == 0 {
+			rank = entries[i-1].Rank
+		}
+
+		entries[i] = rankingEntry{Rank: rank, Name: lb.state.Players[r.PlayerID].Name, IsMe: r.PlayerID == playerID, dailyResult: r}
+	}
+
+	return entries
+}
+
+// dailyPuzzle returns the custom puzzle of the daily puzzle, its id carries the date.
+func dailyPuzzle(date string, l language, solution word) customPuzzle {
+	return customPuzzle{ID: DAILY_PUZZLE_ID_PREFIX + dailyKey(date, l), Language: l, Solution: solution}
+}
+
+// dailyPuzzleDate returns the date of the daily puzzle played by the session.
+func (s session) dailyPuzzleDate() (string, bool) {
+	key, ok := strings.CutPrefix(s.customPuzzleID, DAILY_PUZZLE_ID_PREFIX)
+	if !ok {
+		return "", false
+	}
+
+	date, _, _ := strings.Cut(key, "/")
+	return date, true
+}
+
+// playerID returns the id of the player cookie. A new id is only handed out
+// if create is set, the cookie gets renewed with every call.
+func playerID(w http.ResponseWriter, r *http.Request, create bool) string {
+	id := ""
+	if c, err := r.Cookie(PLAYER_COOKIE_NAME); err == nil {
+		id = c.Value
+	}
+
+	if _, err := uuid.Parse(id); err != nil {
+		if !create {
+			return ""
+		}
+		id = uuid.NewString()
+	}
+
+	http.SetCookie(w, playerCookie(id))
+
+	return id
+}
+
+func playerCookie(id string) *http.Cookie {
+	return &http.Cookie{
+		Name:     PLAYER_COOKIE_NAME,
+		Value:    id,
+		Path:     "/",
+		MaxAge:   PLAYER_MAX_AGE_IN_SECONDS,
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteLaxMode,
+	}
+}
+
+type groupRanking struct {
+	group
+	Entries []rankingEntry
+}
+
+type leaderboardData struct {
+	Locale   string
+	Error    string
+	Player   player
+	Language languageConfig
+	Date     string
+	PrevDate string
+	NextDate string // empty for today
+	IsToday  bool
+	Result   dailyResult
+	Played   bool
+	Rankings []groupRanking
+}
+
+func newLeaderboardData(lb *leaderboard, s session, playerID string, date string, now time.Time) leaderboardData {
+	today := dailyDate(now)
+	d, err := time.Parse(time.DateOnly, date)
+	if err != nil || date > today {
+		date = today
+		d, _ = time.Parse(time.DateOnly, today)
+	}
+
+	data := leaderboardData{
+		Locale:   s.uiLocale,
+		Language: languages.config(s.language),
+		Date:     date,
+		IsToday:  date == today,
+	}
+
+	if prev := dailyDate(d.AddDate(0, 0, -1)); prev >= dailyDate(now.AddDate(0, 0, -DAILY_RESULTS_RETENTION_DAYS)) {
+		data.PrevDate = prev
+	}
+	if !data.IsToday {
+		data.NextDate = dailyDate(d.AddDate(0, 0, 1))
+	}
+
+	p, ok := lb.Player(playerID)
+	if !ok {
+		return data
+	}
+	data.Player = p
+	data.Result, data.Played = lb.Result(date, s.language, playerID)
+
+	for _, g := range lb.Groups(p.Groups) {
+		data.Rankings = append(data.Rankings, groupRanking{group: g, Entries: lb.Ranking(g.Key, date, s.language, playerID)})
+	}
+
+	return data
+}
+
+func leaderboardError(locale string, err error) string {
+	switch {
+	case errors.Is(err, ErrNameRequired):
+		return tr(locale, "leaderboard.error.name_required")
+	case errors.Is(err, ErrInvalidName):
+		return tr(locale, "leaderboard.error.invalid_name", MAX_NAME_LENGTH)
+	case errors.Is(err, ErrTooManyGroups):
+		return tr(locale, "leaderboard.error.too_many_groups", MAX_PLAYER_GROUPS)
+	case errors.Is(err, ErrUnknownPlayer):
+		return tr(locale, "leaderboard.error.no_name")
+	default:
+		return ""
+	}
+}
+
+// registerLeaderboardRoutes wires the player and group routes, the daily
+// puzzle itself is started via POST /daily like any other game.
+func registerLeaderboardRoutes(mux *http.ServeMux, t *template.Template, lb *leaderboard, sessionFor func(w http.ResponseWriter, r *http.Request) session) {
+	render := func(w http.ResponseWriter, r *http.Request, s session, pid string, errMsg string, route string) {
+		data := newLeaderboardData(lb, s, pid, r.FormValue("date"), time.Now())
+		data.Error = errMsg
+
+		err := t.ExecuteTemplate(w, "leaderboard", data)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "executing template failed", "route", route, "err", err)
+		}
+	}
+
+	update := func(route string, f func(pid string, r *http.Request) error) {
+		mux.HandleFunc("POST "+route, func(w http.ResponseWriter, r *http.Request) {
+			s := sessionFor(w, r)
+			pid := playerID(w, r, true)
+
+			errMsg := ""
+			if err := f(pid, r); err != nil {
+				errMsg = leaderboardError(s.uiLocale, err)
+				if errMsg == "" {
+					slog.ErrorContext(r.Context(), "updating player failed", "route", route, "err", err)
+					w.WriteHeader(http.StatusInternalServerError)
+					return
+				}
+			}
+
+			render(w, r, s, pid, errMsg, route)
+		})
+	}
+
+	mux.HandleFunc("GET /leaderboard", func(w http.ResponseWriter, r *http.Request) {
+		s := sessionFor(w, r)
+		render(w, r, s, playerID(w, r, false), "", "/leaderboard")
+	})
+
+	update("/leaderboard/name", func(pid string, r *http.Request) error {
+		_, err := lb.SetName(pid, r.FormValue("name"))
+		return err
+	})
+
+	update("/leaderboard/groups", func(pid string, r *http.Request) error {
+		_, err := lb.JoinGroup(pid, r.FormValue("group"))
+		return err
+	})
+
+	update("/leaderboard/groups/leave", func(pid string, r *http.Request) error {
+		_, err := lb.LeaveGroup(pid, r.FormValue("group"))
+		return err
+	})
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func Test_cleanName(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		want    string
+		wantErr error
+	}{
+		{"trimmed", "  The   Team ", "The Team", nil},
+		{"umlauts", "Bären_1.b-2", "Bären_1.b-2", nil},
+		{"empty", "   ", "", ErrNameRequired},
+		{"too long", "abcdefghijklmnopqrstu", "", ErrInvalidName},
+		{"markup", "<b>x</b>", "", ErrInvalidName},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := cleanName(tt.in)
+			if got != tt.want || !errors.Is(err, tt.wantErr) {
+				t.Errorf("cleanName(%q) = %q, %v, want %q, %v", tt.in, got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_leaderboard_Ranking(t *testing.T) {
+	lb, _ := newLeaderboard("")
+	date := "2024-01-02"
+
+	if _, err := lb.JoinGroup("anna", "Team"); !errors.Is(err, ErrUnknownPlayer) {
+		t.Errorf("JoinGroup() without a name error = %v, want %v", err, ErrUnknownPlayer)
+	}
+
+	for _, id := range []string{"anna", "ben", "cleo", "dan", "eve"} {
+		lb.SetName(id, id)
+		if id != "eve" {
+			lb.JoinGroup(id, "Team")
+		}
+	}
+	if _, err := lb.JoinGroup("ben", "  TEAM "); err != nil || len(lb.Groups([]string{"team"})) != 1 {
+		t.Errorf("JoinGroup() should fold group names, got %v", err)
+	}
+
+	results := []dailyResult{
+		{PlayerID: "anna", Outcome: OUTCOME_SOLVED, Attempts: 4, Duration: time.Minute},
+		{PlayerID: "ben", Outcome: OUTCOME_LOST, Attempts: 6, Duration: time.Second},
+		{PlayerID: "cleo", Outcome: OUTCOME_SOLVED, Attempts: 3, Hints: 1, Duration: time.Minute},
+		{PlayerID: "dan", Outcome: OUTCOME_SOLVED, Attempts: 4, Duration: time.Minute + 100*time.Millisecond},
+		{PlayerID: "eve", Outcome: OUTCOME_SOLVED, Attempts: 1, Duration: time.Second},
+	}
+	for _, r := range results {
+		r.FinishedAt, _ = time.Parse(time.DateOnly, date)
+		if err := lb.Report(date, LANG_EN, r); err != nil {
+			t.Fatalf("Report() failed: %s", err)
+		}
+	}
+
+	if err := lb.Report(date, LANG_EN, dailyResult{PlayerID: "ben", Outcome: OUTCOME_SOLVED, Attempts: 1}); !errors.Is(err, ErrDailyAlreadyDone) {
+		t.Errorf("Report() of a second result error = %v, want %v", err, ErrDailyAlreadyDone)
+	}
+
+	got := lb.Ranking("team", date, LANG_EN, "dan")
+	want := []struct {
+		rank int
+		name string
+	}{{1, "cleo"}, {2, "anna"}, {2, "dan"}, {4, "ben"}}
+	if len(got) != len(want) {
+		t.Fatalf("Ranking() = %v, want %v", got, want)
+	}
+	for i, w := range want {
+		if got[i].Rank != w.rank || got[i].Name != w.name || got[i].IsMe != (w.name == "dan") {
+			t.Errorf("Ranking()[%d] = %d %s, want %d %s", i, got[i].Rank, got[i].Name, w.rank, w.name)
+		}
+	}
+
+	if len(lb.Ranking("team", date, LANG_DE, "dan")) != 0 || len(lb.Ranking("team", "2024-01-01", LANG_EN, "dan")) != 0 {
+		t.Errorf("Ranking() should only rank results of the given day and language")
+	}
+
+	lb.LeaveGroup("anna", "team")
+	if len(lb.Ranking("team", date, LANG_EN, "dan")) != 3 {
+		t.Errorf("Ranking() should only rank group members")
+	}
+}
+
+func Test_leaderboard_store(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	today := dailyDate(now)
+	old := dailyDate(now.AddDate(0, 0, -DAILY_RESULTS_RETENTION_DAYS-1))
+
+	lb, err := newLeaderboard(dir)
+	if err != nil {
+		t.Fatalf("newLeaderboard() failed: %s", err)
+	}
+	lb.SetName("anna", "Anna")
+	lb.JoinGroup("anna", "Team")
+	lb.Report(old, LANG_EN, dailyResult{PlayerID: "anna", Outcome: OUTCOME_SOLVED, FinishedAt: now.AddDate(0, 0, -DAILY_RESULTS_RETENTION_DAYS-1)})
+	lb.Report(today, LANG_EN, dailyResult{PlayerID: "anna", Outcome: OUTCOME_SOLVED, Attempts: 2, FinishedAt: now})
+
+	loaded, err := newLeaderboard(dir)
+	if err != nil {
+		t.Fatalf("newLeaderboard() reload failed: %s", err)
+	}
+
+	if p, ok := loaded.Player("anna"); !ok || p.Name != "Anna" || !p.InGroup("team") {
+		t.Errorf("Player() after reload = %v, %v", p, ok)
+	}
+	if r, ok := loaded.Result(today, LANG_EN, "anna"); !ok || r.Attempts != 2 {
+		t.Errorf("Result() after reload = %v, %v", r, ok)
+	}
+	if _, ok := loaded.Result(old, LANG_EN, "anna"); ok {
+		t.Errorf("Report() should drop results older than %d days", DAILY_RESULTS_RETENTION_DAYS)
+	}
+}
+
+func Test_leaderboard_Start(t *testing.T) {
+	lb, _ := newLeaderboard(t.TempDir())
+	now := time.Now()
+	today := dailyDate(now)
+
+	startedAt, isNew, err := lb.Start(today, LANG_EN, "anna", now)
+	if err != nil || !isNew || !startedAt.Equal(now) {
+		t.Fatalf("Start() = %v, %v, %v, want a new start", startedAt, isNew, err)
+	}
+
+	// e.g. restarted via '/new', the daily must not be played again
+	startedAt, isNew, _ = lb.Start(today, LANG_EN, "anna", now.Add(time.Minute))
+	if isNew || !startedAt.Equal(now) {
+		t.Errorf("Start() again = %v, %v, want the first start", startedAt, isNew)
+	}
+	if _, isNew, _ := lb.Start(today, LANG_DE, "anna", now); !isNew {
+		t.Errorf("Start() of another language should be new")
+	}
+
+	lb.Report(today, LANG_EN, abandonedDaily("anna", startedAt, now.Add(time.Minute)))
+	if r, ok := lb.Result(today, LANG_EN, "anna"); !ok || r.Outcome != OUTCOME_LOST || r.Attempts != 6 || r.Duration != time.Minute {
+		t.Errorf("Result() of abandoned daily = %v, %v, want it lost with every attempt", r, ok)
+	}
+}
+
+func Test_leaderboard_DailySolution(t *testing.T) {
+	wdb := wordDatabase{}
+	wdb.Init(nil, nil)
+	words := []word{{'m', 'a', 't', 'c', 'h'}, {'h', 'o', 'u', 's', 'e'}, {'g', 'a', 'm', 'e', 's'}}
+	wdb.Replace(LANG_EN, WC_ALL, words)
+
+	lb, _ := newLeaderboard("")
+	first, _ := lb.DailySolution("2024-01-01", LANG_EN, wdb)
+	second, _ := lb.DailySolution("2024-01-02", LANG_EN, wdb)
+	if first == second {
+		t.Errorf("DailySolution() should not repeat past daily solutions, got %v twice", first)
+	}
+
+	other, _ := newLeaderboard("")
+	if w, _ := other.DailySolution("2024-01-01", LANG_EN, wdb); w != first {
+		t.Errorf("DailySolution() = %v, want the same solution %v on every server", w, first)
+	}
+
+	wdb.Replace(LANG_EN, WC_BANNED, []word{first})
+	if w, _ := lb.DailySolution("2024-01-01", LANG_EN, wdb); w != first {
+		t.Errorf("DailySolution() = %v, must not change once picked, want %v", w, first)
+	}
+	if w, _ := other.DailySolution("2024-01-03", LANG_EN, wdb); w == first {
+		t.Errorf("DailySolution() must not pick banned words, got %v", w)
+	}
+}
+
+func Test_session_dailyPuzzleDate(t *testing.T) {
+	s := session{language: LANG_EN}
+	if _, ok := s.dailyPuzzleDate(); ok {
+		t.Errorf("dailyPuzzleDate() should be unset without a daily puzzle")
+	}
+
+	s.startCustomPuzzle(dailyPuzzle("2024-01-02", LANG_EN, word{'h', 'o', 'u', 's', 'e'}), wordDatabase{})
+	if date, ok := s.dailyPuzzleDate(); !ok || date != "2024-01-02" {
+		t.Errorf("dailyPuzzleDate() = %s, %v, want 2024-01-02", date, ok)
+	}
+
+	s.customPuzzleID = "custom-id"
+	if _, ok := s.dailyPuzzleDate(); ok {
+		t.Errorf("dailyPuzzleDate() should be unset for custom puzzles")
+	}
+}
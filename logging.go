@@ -0,0 +1,156 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pandorasNox/lettr/pkg/tracing"
+)
+
+const REQUEST_ID_HEADER = "X-Request-ID"
+
+// sensitiveLogKeys are only logged at debug level, above their values get
+// redacted, no matter where the log call comes from.
+var sensitiveLogKeys = []string{"solution", "guess", "email", "token"}
+
+type contextKey string
+
+const requestIDKey contextKey = "request_id"
+
+// newLogger builds the logger from LOG_LEVEL (debug, info, warn, error) and
+// LOG_FORMAT (text, json).
+func newLogger(w io.Writer, level string, format string) (*slog.Logger, error) {
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(cmp.Or(level, "info"))); err != nil {
+		return nil, fmt.Errorf("invalid log level '%s': %s", level, err)
+	}
+
+	opts := &slog.HandlerOptions{Level: l}
+
+	var h slog.Handler
+	switch cmp.Or(format, "text") {
+	case "text":
+		h = slog.NewTextHandler(w, opts)
+	case "json":
+		h = slog.NewJSONHandler(w, opts)
+	default:
+		return nil, fmt.Errorf("invalid log format '%s'", format)
+	}
+
+	return slog.New(contextHandler{h}), nil
+}
+
+// fatal logs an error and exits, the slog counterpart of log.Fatalf.
+func fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
+
+// contextHandler adds the request id and the active span of the context to
+// every record and redacts sensitiveLogKeys above debug level.
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if id, ok := ctx.Value(requestIDKey).(string); ok {
+		r.AddAttrs(slog.String(string(requestIDKey), id))
+	}
+	if sc := tracing.SpanFromContext(ctx).SpanContext(); sc.IsValid() {
+		r.AddAttrs(slog.String("trace_id", sc.TraceID.String()), slog.String("span_id", sc.SpanID.String()))
+	}
+
+	if r.Level > slog.LevelDebug {
+		redacted := slog.NewRecord(r.Time, r.Level, r.Message, r.PC)
+		r.Attrs(func(a slog.Attr) bool {
+			if slices.Contains(sensitiveLogKeys, a.Key) {
+				a = slog.String(a.Key, "[redacted]")
+			}
+			redacted.AddAttrs(a)
+			return true
+		})
+		r = redacted
+	}
+
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	for i, a := range attrs {
+		// loggers derived via With don't know the level of later records, redact right away
+		if slices.Contains(sensitiveLogKeys, a.Key) {
+			attrs[i] = slog.String(a.Key, "[redacted]")
+		}
+	}
+
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
+
+// validRequestID accepts incoming ids of proxies and load balancers, as long
+// as they are short and can't mess up the log lines.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+
+	return !strings.ContainsFunc(id, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("-_.:", r))
+	})
+}
+
+// sessionHash identifies a session in the logs without revealing its id,
+// which would be enough to take the session over.
+func sessionHash(r *http.Request) string {
+	c, err := r.Cookie(SESSION_COOKIE_NAME)
+	if err != nil {
+		return ""
+	}
+
+	sum := sha256.Sum256([]byte(c.Value))
+	return hex.EncodeToString(sum[:6])
+}
+
+// accessLog assigns every request an id, honouring REQUEST_ID_HEADER, and
+// logs it once it's done.
+func accessLog(h http.Handler, mux *http.ServeMux) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(REQUEST_ID_HEADER)
+		if !validRequestID(id) {
+			id = uuid.NewString()
+		}
+		w.Header().Set(REQUEST_ID_HEADER, id)
+		r = r.WithContext(context.WithValue(r.Context(), requestIDKey, id))
+		tracing.SpanFromContext(r.Context()).SetAttributes(tracing.String("http.request.id", id))
+
+		_, route := mux.Handler(r)
+
+		start := time.Now()
+		sr := &statusRecorder{ResponseWriter: w}
+		h.ServeHTTP(sr, r)
+
+		slog.InfoContext(r.Context(), "request",
+			"method", r.Method,
+			"path", r.URL.Path,
+			"route", route,
+			"status", sr.status(),
+			"bytes", sr.bytes,
+			"duration_ms", float64(time.Since(start).Microseconds())/1000,
+			"session", sessionHash(r),
+		)
+	})
+}
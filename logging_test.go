@@ -0,0 +1,187 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func Test_newLogger(t *testing.T) {
+	tests := []struct {
+		name    string
+		level   string
+		format  string
+		wantErr bool
+	}{
+		{"defaults", "", "", false},
+		{"debug json", "debug", "json", false},
+		{"upper case level", "WARN", "text", false},
+		{"invalid level", "verbose", "", true},
+		{"invalid format", "", "xml", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newLogger(&bytes.Buffer{}, tt.level, tt.format)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("newLogger() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func logLine(t *testing.T, buf *bytes.Buffer) map[string]any {
+	t.Helper()
+
+	m := map[string]any{}
+	if err := json.Unmarshal(buf.Bytes(), &m); err != nil {
+		t.Fatalf("log line '%s' isn't json: %s", buf, err)
+	}
+
+	return m
+}
+
+func Test_contextHandler_redaction(t *testing.T) {
+	tests := []struct {
+		name  string
+		level string
+		log   func(l *slog.Logger)
+		want  string
+	}{
+		{
+			name:  "info redacts",
+			level: "info",
+			log:   func(l *slog.Logger) { l.Info("x", "solution", "roate") },
+			want:  "[redacted]",
+		},
+		{
+			name:  "debug logs at debug",
+			level: "debug",
+			log:   func(l *slog.Logger) { l.Debug("x", "solution", "roate") },
+			want:  "roate",
+		},
+		{
+			name:  "debug level still redacts info records",
+			level: "debug",
+			log:   func(l *slog.Logger) { l.Info("x", "solution", "roate") },
+			want:  "[redacted]",
+		},
+		{
+			name:  "with attrs redacts",
+			level: "debug",
+			log:   func(l *slog.Logger) { l.With("solution", "roate").Debug("x") },
+			want:  "[redacted]",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			l, err := newLogger(buf, tt.level, "json")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			tt.log(l)
+
+			if got := logLine(t, buf)["solution"]; got != tt.want {
+				t.Errorf("solution = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_contextHandler_requestID(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l, err := newLogger(buf, "", "json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l.InfoContext(context.WithValue(context.Background(), requestIDKey, "abc-123"), "x")
+
+	if got := logLine(t, buf)["request_id"]; got != "abc-123" {
+		t.Errorf("request_id = %v, want %v", got, "abc-123")
+	}
+}
+
+func Test_validRequestID(t *testing.T) {
+	tests := []struct {
+		id   string
+		want bool
+	}{
+		{"", false},
+		{"0b9c8e1a-6f2e-4f43-9d6b-2f1f0c1e7a11", true},
+		{"fly:abc.123_x", true},
+		{"with space", false},
+		{"new\nline", false},
+		{strings.Repeat("a", 129), false},
+	}
+	for _, tt := range tests {
+		if got := validRequestID(tt.id); got != tt.want {
+			t.Errorf("validRequestID(%q) = %v, want %v", tt.id, got, tt.want)
+		}
+	}
+}
+
+func Test_accessLog(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l, err := newLogger(buf, "", "json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer slog.SetDefault(slog.Default())
+	slog.SetDefault(l)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /history/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+	h := accessLog(mux, mux)
+
+	tests := []struct {
+		name     string
+		incoming string
+		wantSame bool
+	}{
+		{"honours incoming id", "upstream-42", true},
+		{"replaces invalid id", "bad id\n", false},
+		{"generates missing id", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf.Reset()
+
+			r := httptest.NewRequest(http.MethodGet, "/history/abc", nil)
+			r.Header.Set(REQUEST_ID_HEADER, tt.incoming)
+			r.AddCookie(&http.Cookie{Name: SESSION_COOKIE_NAME, Value: "secret-session-id"})
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			id := w.Header().Get(REQUEST_ID_HEADER)
+			if !validRequestID(id) {
+				t.Errorf("response id '%s' isn't valid", id)
+			}
+			if (id == tt.incoming) != tt.wantSame {
+				t.Errorf("response id = '%s', incoming '%s', want same %v", id, tt.incoming, tt.wantSame)
+			}
+
+			line := logLine(t, buf)
+			if line["request_id"] != id {
+				t.Errorf("logged request_id = %v, want %v", line["request_id"], id)
+			}
+			if line["route"] != "GET /history/{id}" {
+				t.Errorf("logged route = %v, want %v", line["route"], "GET /history/{id}")
+			}
+			if line["status"] != float64(http.StatusTeapot) {
+				t.Errorf("logged status = %v, want %v", line["status"], http.StatusTeapot)
+			}
+			if s, _ := line["session"].(string); s == "" || strings.Contains(buf.String(), "secret-session-id") {
+				t.Errorf("logged session = %v, want a hash of the session id", line["session"])
+			}
+		})
+	}
+}
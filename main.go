@@ -2,6 +2,7 @@ package main
 
 import (
 	"bufio"
+	"context"
 	"embed"
 	"errors"
 	"fmt"
@@ -10,51 +11,142 @@ import (
 	"math/rand"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"unicode"
 
 	iofs "io/fs"
-	"log"
+	"log/slog"
+	"maps"
+	"net"
 	"net/http"
 	"net/url"
 	"os"
 	"slices"
+	"strconv"
 	"time"
 	"unicode/utf8"
 
 	"github.com/google/uuid"
 	"github.com/pandorasNox/lettr/pkg/middleware"
+	"github.com/pandorasNox/lettr/pkg/tracing"
+	"github.com/pandorasNox/lettr/pkg/wordlist"
 )
 
-var Revision = "0000000"
+var Revision = "0000000" // set at build time, see container-images/app/Dockerfile
 var FaviconPath = "/static/assets/favicon"
 
 const SESSION_COOKIE_NAME = "session"
 const SESSION_MAX_AGE_IN_SECONDS = 24 * 60 * 60
 
 //go:embed configs/*.txt
+//go:embed configs/*.json
+//go:embed configs/i18n/*.json
 //go:embed templates/*.html.tmpl
 //go:embed web/static/assets/*
-//go:embed web/static/generated/*.js
-//go:embed web/static/generated/*.css
+//go:embed web/static/npm
+//go:embed web/static/generated
 var fs embed.FS
 
 var ErrNotInWordList = errors.New("not in wordlist")
 
 type env struct {
-	port string
+	port             string
+	adminUser        string
+	adminPassword    string
+	wordListStoreDir string
+
+	definitionsDir       string
+	definitionsURLFormat string
+
+	puzzleSecret string
+
+	leaderboardStoreDir string
+
+	accountStoreDir string
+	mailer          string
+	mailDir         string
+	publicBaseURL   string
+
+	metricsToken string
+
+	logLevel  string
+	logFormat string
+
+	tracesExporter     string
+	otlpEndpoint       string
+	otlpTracesEndpoint string
+	otlpHeaders        string
+	otelServiceName    string
+
+	rateLimits     string
+	clientIPHeader string
+
+	cspReportOnly bool
 }
 
 func (e env) String() string {
 	s := fmt.Sprintf("port: %s\n", e.port)
+	s = s + fmt.Sprintf("admin enabled: %t\n", e.adminPassword != "")
+	s = s + fmt.Sprintf("word list store dir: %s\n", e.wordListStoreDir)
+	s = s + fmt.Sprintf("definitions dir: %s\n", e.definitionsDir)
+	s = s + fmt.Sprintf("definitions url format: %s\n", e.definitionsURLFormat)
+	s = s + fmt.Sprintf("custom puzzle secret set: %t\n", e.puzzleSecret != "")
+	s = s + fmt.Sprintf("leaderboard store dir: %s\n", e.leaderboardStoreDir)
+	s = s + fmt.Sprintf("account store dir: %s\n", e.accountStoreDir)
+	s = s + fmt.Sprintf("mailer: %s\n", e.mailer)
+	s = s + fmt.Sprintf("mail dir: %s\n", e.mailDir)
+	s = s + fmt.Sprintf("public base url: %s\n", e.publicBaseURL)
+	s = s + fmt.Sprintf("metrics enabled: %t\n", e.metricsToken != "")
+	s = s + fmt.Sprintf("log level: %s\n", e.logLevel)
+	s = s + fmt.Sprintf("log format: %s\n", e.logFormat)
+	s = s + fmt.Sprintf("traces exporter: %s\n", e.tracesExporter)
+	s = s + fmt.Sprintf("otlp endpoint: %s\n", e.otlpEndpoint)
+	s = s + fmt.Sprintf("otlp traces endpoint: %s\n", e.otlpTracesEndpoint)
+	s = s + fmt.Sprintf("otlp headers set: %t\n", e.otlpHeaders != "")
+	s = s + fmt.Sprintf("otel service name: %s\n", e.otelServiceName)
+	s = s + fmt.Sprintf("rate limits: %s\n", e.rateLimits)
+	s = s + fmt.Sprintf("client ip header: %s\n", e.clientIPHeader)
+	s = s + fmt.Sprintf("csp report only: %t\n", e.cspReportOnly)
 	// s = s + fmt.Sprintf("foo: %s\n", e.port)
 	return s
 }
 
+// LogValue logs the same as String, without secrets.
+func (e env) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("port", e.port),
+		slog.Bool("admin_enabled", e.adminPassword != ""),
+		slog.String("word_list_store_dir", e.wordListStoreDir),
+		slog.String("definitions_dir", e.definitionsDir),
+		slog.String("definitions_url_format", e.definitionsURLFormat),
+		slog.Bool("custom_puzzle_secret_set", e.puzzleSecret != ""),
+		slog.String("leaderboard_store_dir", e.leaderboardStoreDir),
+		slog.String("account_store_dir", e.accountStoreDir),
+		slog.String("mailer", e.mailer),
+		slog.String("mail_dir", e.mailDir),
+		slog.String("public_base_url", e.publicBaseURL),
+		slog.Bool("metrics_enabled", e.metricsToken != ""),
+		slog.String("log_level", e.logLevel),
+		slog.String("log_format", e.logFormat),
+		slog.String("traces_exporter", e.tracesExporter),
+		slog.String("otlp_endpoint", e.otlpEndpoint),
+		slog.String("otlp_traces_endpoint", e.otlpTracesEndpoint),
+		slog.Bool("otlp_headers_set", e.otlpHeaders != ""),
+		slog.String("otel_service_name", e.otelServiceName),
+		slog.String("rate_limits", e.rateLimits),
+		slog.String("client_ip_header", e.clientIPHeader),
+		slog.Bool("csp_report_only", e.cspReportOnly),
+	)
+}
+
 type counterState struct {
 	mu    sync.Mutex
 	count int
 }
 
+// session is passed around by value, copies share the maps and the backing
+// arrays of the slices. Never modify those in place, replace them with an
+// updated copy (slices.Clone, maps.Clone) instead.
 type session struct {
 	// todo: think about using mutex or channel for rw session
 	id                   string
@@ -64,6 +156,88 @@ type session struct {
 	activeSolutionWord   word
 	lastEvaluatedAttempt puzzle
 	pastWords            []word
+	gameStartedAt        time.Time
+	hintsUsed            []hint
+	keyboardLayout       string // chosen by the player, empty for the language default
+	uiLocale             string
+	parkedGames          map[language]game // games of the other languages, resumed when switching back
+	history              []gameRecord      // finished and abandoned games, oldest first, bounded by MAX_HISTORY
+	mode                 gameMode          // empty for MODE_CLASSIC
+	speedrun             speedrun
+	timedOut             bool // the active puzzle ran out of time
+	stats                statistics
+	boards               boards // MODE_MULTI only
+	multiBoards          int    // chosen number of boards, zero for the default
+	customPuzzleID       string // set while a custom puzzle is played
+	createdPuzzles       []createdPuzzle
+	accountID            string    // set once logged in, history and stats follow the account
+	accountSyncedAt      time.Time // last account change taken over
+}
+
+// game is the per language state of a session.
+type game struct {
+	activeSolutionWord   word
+	lastEvaluatedAttempt puzzle
+	pastWords            []word
+	startedAt            time.Time
+	hintsUsed            []hint
+	timedOut             bool
+	boards               boards
+	customPuzzleID       string
+}
+
+// switchLanguage parks the game of the current language and resumes the one
+// of l, a new game is started if l wasn't played before.
+func (s *session) switchLanguage(l language, wdb wordDatabase) {
+	if l == s.language {
+		return
+	}
+
+	parked := maps.Clone(s.parkedGames)
+	if parked == nil {
+		parked = make(map[language]game)
+	}
+
+	parked[s.language] = game{
+		activeSolutionWord:   s.activeSolutionWord,
+		lastEvaluatedAttempt: s.lastEvaluatedAttempt,
+		pastWords:            s.pastWords,
+		startedAt:            s.gameStartedAt,
+		hintsUsed:            s.hintsUsed,
+		timedOut:             s.timedOut,
+		boards:               s.boards,
+		customPuzzleID:       s.customPuzzleID,
+	}
+
+	g, ok := parked[l]
+	if !ok {
+		g = game{activeSolutionWord: wdb.RandomPickWithFallback(l, []word{}, 0), pastWords: []word{}, startedAt: time.Now()}
+	}
+	delete(parked, l)
+
+	s.language = l
+	s.activeSolutionWord = g.activeSolutionWord
+	s.lastEvaluatedAttempt = g.lastEvaluatedAttempt
+	s.pastWords = g.pastWords
+	s.gameStartedAt = g.startedAt
+	s.hintsUsed = g.hintsUsed
+	s.timedOut = g.timedOut
+	s.boards = g.boards
+	s.customPuzzleID = g.customPuzzleID
+	s.parkedGames = parked
+
+	if s.Mode() == MODE_MULTI && len(s.boards) == 0 {
+		s.startBoards(wdb)
+	}
+}
+
+// KeyboardLayout returns the layout chosen by the player or the default of the session language.
+func (s session) KeyboardLayout() string {
+	if s.keyboardLayout != "" {
+		return s.keyboardLayout
+	}
+
+	return languages.config(s.language).KeyboardLayout
 }
 
 func (s *session) AddPastWord(w word) {
@@ -85,6 +259,14 @@ func (ss sessions) String() string {
 	return out
 }
 
+// save is updateOrSet within a span of the request.
+func (ss *sessions) save(ctx context.Context, sess session) {
+	_, span := tracing.Start(ctx, "sessions.save")
+	defer span.End()
+
+	ss.updateOrSet(sess)
+}
+
 func (ss *sessions) updateOrSet(sess session) {
 	index := slices.IndexFunc((*ss), func(s session) bool {
 		return s.id == sess.id
@@ -100,12 +282,11 @@ func (ss *sessions) updateOrSet(sess session) {
 type language string
 
 func NewLang(maybeLang string) (language, error) {
-	switch language(maybeLang) {
-	case LANG_EN, LANG_DE:
-		return language(maybeLang), nil
-	default:
+	if _, ok := languages.get(language(maybeLang)); !ok {
 		return LANG_EN, fmt.Errorf("couldn't create new language from given value: '%s'", maybeLang)
 	}
+
+	return language(maybeLang), nil
 }
 
 const (
@@ -179,13 +360,13 @@ func toWord(wo string) (word, error) {
 	out := word{}
 
 	length := 0
-	for i, l := range wo {
+	for _, l := range wo {
 		length++
 		if length > len(out) {
 			return word{}, fmt.Errorf("string does not match allowed word length: length=%d, expectedLength=%d", length, len(out))
 		}
 
-		out[i] = l
+		out[length-1] = l // range index counts bytes, multi byte letters like 'ü' need the rune count
 	}
 
 	if length < len(out) {
@@ -262,6 +443,15 @@ func (wg wordGuess) isSolved() bool {
 	return true
 }
 
+func (wg wordGuess) word() word {
+	w := word{}
+	for i, lg := range wg {
+		w[i] = lg.Letter
+	}
+
+	return w
+}
+
 func (wg wordGuess) letterGuesses() []letterGuess {
 	s := []letterGuess{}
 
@@ -291,6 +481,44 @@ var funcMap = template.FuncMap{
 	"IsMatchVague": MatchVague.is,
 	"IsMatchNone":  MatchNone.is,
 	"IsMatchExact": MatchExact.is,
+	"dict":         dict,
+	"static":       staticFiles.Path,
+	"integrity":    staticFiles.Integrity,
+}
+
+// templateFiles are parsed into one template set, on start and in tests.
+var templateFiles = []string{
+	"templates/index.html.tmpl",
+	"templates/lettr-form.html.tmpl",
+	"templates/help.html.tmpl",
+	"templates/history.html.tmpl",
+	"templates/boards.html.tmpl",
+	"templates/rooms.html.tmpl",
+	"templates/puzzles.html.tmpl",
+	"templates/leaderboard.html.tmpl",
+	"templates/account.html.tmpl",
+	"templates/account-verify.html.tmpl",
+	"templates/admin.html.tmpl",
+}
+
+// dict allows passing multiple named values into a sub template,
+// e.g. {{ template "foo" (dict "Key" .Value "Other" $) }}
+func dict(keyValues ...any) (map[string]any, error) {
+	if len(keyValues)%2 != 0 {
+		return nil, fmt.Errorf("dict expects an even number of arguments, got: %d", len(keyValues))
+	}
+
+	m := make(map[string]any, len(keyValues)/2)
+	for i := 0; i < len(keyValues); i += 2 {
+		key, ok := keyValues[i].(string)
+		if !ok {
+			return nil, fmt.Errorf("dict keys must be strings, got: %T", keyValues[i])
+		}
+
+		m[key] = keyValues[i+1]
+	}
+
+	return m, nil
 }
 
 const (
@@ -299,102 +527,204 @@ const (
 	MatchExact
 )
 
+type notInWordListData struct {
+	Word   word
+	Locale string
+}
+
 type FormData struct {
-	Data                        puzzle
-	Errors                      map[string]string
-	IsSolved                    bool
-	IsLoose                     bool
-	JSCachePurgeTimestamp       int64
-	Language                    language
-	Revision                    string
-	FaviconPath                 string
-	Keyboard                    keyboard
-	PastWords                   []word
-	SolutionHasDublicateLetters bool
-}
-
-func (fd FormData) New(l language, p puzzle, pastWords []word, SolutionHasDublicateLetters bool) FormData {
+	Data            puzzle
+	Errors          map[string]string
+	IsSolved        bool
+	IsLoose         bool
+	IsTimedOut      bool
+	Mode            modeData
+	Boards          boards
+	IsCustomPuzzle  bool
+	IsDailyPuzzle   bool
+	IsLoggedIn      bool
+	Language        language
+	Locale          string
+	Locales         []string
+	LanguageConfig  languageConfig
+	Languages       []languageConfig
+	Revision        string
+	FaviconPath     string
+	CSPNonce        string // set by handlers rendering whole pages
+	CSRFToken       string // set by handlers rendering whole pages
+	Keyboard        keyboard
+	KeyboardLayouts []string
+}
+
+func (fd FormData) New(s session, p puzzle) FormData {
 	kb := keyboard{}
-	kb.Init(l, p.letterGuesses())
+	var bs boards
+	if s.Mode() == MODE_MULTI {
+		bs = s.boards
+		kb.InitBoards(s.KeyboardLayout(), languages.config(s.language).Umlauts, bs.letterGuesses())
+	} else {
+		kb.Init(s.KeyboardLayout(), languages.config(s.language).Umlauts, p.letterGuesses())
+	}
 
 	return FormData{
-		Data:                        p,
-		Errors:                      make(map[string]string),
-		JSCachePurgeTimestamp:       time.Now().Unix(),
-		Language:                    l,
-		Revision:                    Revision,
-		FaviconPath:                 FaviconPath,
-		Keyboard:                    kb,
-		PastWords:                   pastWords,
-		SolutionHasDublicateLetters: SolutionHasDublicateLetters,
+		Data:            p,
+		Errors:          make(map[string]string),
+		IsTimedOut:      s.timedOut,
+		Mode:            newModeData(s, time.Now()),
+		Boards:          bs,
+		IsCustomPuzzle:  s.customPuzzleID != "",
+		IsDailyPuzzle:   strings.HasPrefix(s.customPuzzleID, DAILY_PUZZLE_ID_PREFIX),
+		IsLoggedIn:      s.accountID != "",
+		Language:        s.language,
+		Locale:          s.uiLocale,
+		Locales:         translations.Locales(),
+		LanguageConfig:  languages.config(s.language),
+		Languages:       languages.Languages,
+		Revision:        Revision,
+		FaviconPath:     FaviconPath,
+		Keyboard:        kb,
+		KeyboardLayouts: keyboardLayoutNames(),
 	}
 }
 
 type wordCollection string
 
 const (
-	WC_ALL    wordCollection = "wc_all"
-	WC_COMMON wordCollection = "wc_common"
+	WC_ALL       wordCollection = "wc_all"
+	WC_COMMON    wordCollection = "wc_common"
+	WC_BANNED    wordCollection = "wc_banned"    // never picked as solution
+	WC_SUGGESTED wordCollection = "wc_suggested" // pending player suggestions
 )
 
+func NewWordCollection(maybeCollection string) (wordCollection, error) {
+	switch wordCollection(maybeCollection) {
+	case WC_ALL, WC_COMMON, WC_BANNED, WC_SUGGESTED:
+		return wordCollection(maybeCollection), nil
+	default:
+		return WC_ALL, fmt.Errorf("couldn't create new word collection from given value: '%s'", maybeCollection)
+	}
+}
+
 type wordDatabase struct {
+	mu *sync.RWMutex // guards db against live edits (e.g. admin curation), nil for static dbs
 	db map[language]map[wordCollection]map[word]bool
 }
 
+func (wdb wordDatabase) rlock() (unlock func()) {
+	if wdb.mu == nil {
+		return func() {}
+	}
+
+	wdb.mu.RLock()
+	return wdb.mu.RUnlock
+}
+
+func (wdb wordDatabase) lock() (unlock func()) {
+	if wdb.mu == nil {
+		return func() {}
+	}
+
+	wdb.mu.Lock()
+	return wdb.mu.Unlock
+}
+
+// newWordDatabase returns an empty database which can be handed out before
+// Init loaded it, copies share the words and the lock.
+func newWordDatabase() wordDatabase {
+	return wordDatabase{mu: &sync.RWMutex{}, db: make(map[language]map[wordCollection]map[word]bool)}
+}
+
 func (wdb *wordDatabase) Init(fs iofs.FS, filePathsByLanguage map[language]map[wordCollection][]string) error {
-	wdb.db = make(map[language]map[wordCollection]map[word]bool)
+	if wdb.db == nil {
+		*wdb = newWordDatabase()
+	}
 
+	loaded := make(map[language]map[wordCollection]map[word]bool)
 	for l, collection := range filePathsByLanguage {
-		wdb.db[l] = make(map[wordCollection]map[word]bool)
+		loaded[l] = make(map[wordCollection]map[word]bool)
 		for c, paths := range collection {
-			wdb.db[l][c] = make(map[word]bool)
+			loaded[l][c] = make(map[word]bool)
 
 			for _, path := range paths {
-				f, err := fs.Open(path)
+				words, err := readWordList(fs, path, languages.config(l).normaliser())
 				if err != nil {
-					return fmt.Errorf("wordDatabase init failed when opening file: %s", err)
+					return fmt.Errorf("wordDatabase init failed: %s", err)
 				}
-				defer f.Close()
 
-				fInfo, err := f.Stat()
-				if err != nil {
-					return fmt.Errorf("wordDatabase init failed when obtaining stat: %s", err)
+				for _, w := range words {
+					loaded[l][c][w] = true
 				}
+			}
+		}
+	}
 
-				var allowedSize int64 = 2 * 1024 * 1024 // 2 MB
-				if fInfo.Size() > allowedSize {
-					return fmt.Errorf("wordDatabase init failed with forbidden file size: path='%s', size='%d'", path, fInfo.Size())
-				}
+	defer wdb.lock()()
+	for l, collections := range loaded {
+		wdb.db[l] = collections
+	}
+
+	return nil
+}
+
+// readWordList reads a word list file in the format used in configs/*.txt,
+// a metadata line followed by one word per line. Further tab separated
+// columns (e.g. corpora frequencies) are ignored. Returned words are normalised
+// by n, words it rejects are skipped and logged.
+func readWordList(fs iofs.FS, path string, n normaliser) ([]word, error) {
+	f, err := fs.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed when opening file: %s", err)
+	}
+	defer f.Close()
 
-				scanner := bufio.NewScanner(f)
-				var line int = 0
-				for scanner.Scan() {
-					if line == 0 { // skip first metadata line
-						line++
-						continue
-					}
+	fInfo, err := f.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("failed when obtaining stat: %s", err)
+	}
 
-					candidate := scanner.Text()
-					word, err := toWord(candidate)
-					if err != nil {
-						return fmt.Errorf("wordDatabase init, couldn't parse line to word: line='%s', err=%s", candidate, err)
-					}
+	var allowedSize int64 = 2 * 1024 * 1024 // 2 MB
+	if fInfo.Size() > allowedSize {
+		return nil, fmt.Errorf("forbidden file size: path='%s', size='%d'", path, fInfo.Size())
+	}
 
-					wdb.db[l][c][word.ToLower()] = true
+	words := []word{}
 
-					line++
-				}
-				if err := scanner.Err(); err != nil {
-					return fmt.Errorf("wordDatabase init failed scanning file with: path='%s', err=%s", path, err)
-				}
-			}
+	scanner := bufio.NewScanner(f)
+	var line int = 0
+	for scanner.Scan() {
+		if line == 0 { // skip first metadata line
+			line++
+			continue
+		}
+
+		line++
+
+		// e.g. "größe" doesn't fit once transliterated, one word must not fail the whole list
+		candidate, _, _ := strings.Cut(scanner.Text(), "\t")
+		w, err := n.Word(candidate)
+		if err != nil {
+			slog.Warn("word list skipped word", "path", path, "line", line, "word", candidate, "err", err)
+			continue
 		}
+
+		words = append(words, w)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed scanning file with: path='%s', err=%s", path, err)
 	}
 
-	return nil
+	return words, nil
 }
 
+// Exists reports whether w is a valid guess, after normalising it the same way the lists were.
 func (wdb wordDatabase) Exists(l language, w word) bool {
+	nw, err := languages.config(l).normaliser().Word(w.String())
+	if err != nil {
+		return false
+	}
+
+	defer wdb.rlock()()
+
 	db, ok := wdb.db[l]
 	if !ok {
 		return false
@@ -405,10 +735,103 @@ func (wdb wordDatabase) Exists(l language, w word) bool {
 		return false
 	}
 
-	_, ok = db_c[w.ToLower()]
+	_, ok = db_c[nw]
+	return ok
+}
+
+func (wdb wordDatabase) Has(l language, c wordCollection, w word) bool {
+	defer wdb.rlock()()
+
+	_, ok := wdb.db[l][c][w.ToLower()]
 	return ok
 }
 
+func (wdb wordDatabase) Add(l language, c wordCollection, w word) {
+	defer wdb.lock()()
+
+	if _, ok := wdb.db[l]; !ok {
+		wdb.db[l] = make(map[wordCollection]map[word]bool)
+	}
+
+	if _, ok := wdb.db[l][c]; !ok {
+		wdb.db[l][c] = make(map[word]bool)
+	}
+
+	wdb.db[l][c][w.ToLower()] = true
+}
+
+func (wdb wordDatabase) Remove(l language, c wordCollection, w word) {
+	defer wdb.lock()()
+
+	delete(wdb.db[l][c], w.ToLower())
+}
+
+// Replace swaps the whole collection, e.g. when loading a curated list.
+func (wdb wordDatabase) Replace(l language, c wordCollection, words []word) {
+	defer wdb.lock()()
+
+	if _, ok := wdb.db[l]; !ok {
+		wdb.db[l] = make(map[wordCollection]map[word]bool)
+	}
+
+	wdb.db[l][c] = make(map[word]bool, len(words))
+	for _, w := range words {
+		wdb.db[l][c][w.ToLower()] = true
+	}
+}
+
+// Sizes counts the words per language and collection.
+func (wdb wordDatabase) Sizes() map[language]map[wordCollection]int {
+	defer wdb.rlock()()
+
+	sizes := make(map[language]map[wordCollection]int, len(wdb.db))
+	for l, collections := range wdb.db {
+		sizes[l] = make(map[wordCollection]int, len(collections))
+		for c, words := range collections {
+			sizes[l][c] = len(words)
+		}
+	}
+
+	return sizes
+}
+
+// Words returns all words of a collection sorted alphabetically.
+func (wdb wordDatabase) Words(l language, c wordCollection) []word {
+	defer wdb.rlock()()
+
+	out := make([]word, 0, len(wdb.db[l][c]))
+	for w := range wdb.db[l][c] {
+		out = append(out, w)
+	}
+
+	slices.SortFunc(out, func(a, b word) int {
+		return strings.Compare(a.String(), b.String())
+	})
+
+	return out
+}
+
+// Search returns up to limit words of WC_ALL containing query, sorted alphabetically.
+func (wdb wordDatabase) Search(l language, query string, limit int) []word {
+	query = strings.ToLower(strings.TrimSpace(query))
+	if query == "" {
+		return []word{}
+	}
+
+	out := []word{}
+	for _, w := range wdb.Words(l, WC_ALL) {
+		if len(out) >= limit {
+			break
+		}
+
+		if strings.Contains(w.String(), query) {
+			out = append(out, w)
+		}
+	}
+
+	return out
+}
+
 func (wdb wordDatabase) RandomPick(l language, avoidList []word, retryAkkumulator uint8) (word, error) {
 	const MAX_RETRY uint8 = 10
 
@@ -416,6 +839,24 @@ func (wdb wordDatabase) RandomPick(l language, avoidList []word, retryAkkumulato
 		return word{}, fmt.Errorf("RandomPick exceeded retries: retryAkkumulator='%d' | MAX_RETRY='%d'", retryAkkumulator, MAX_RETRY)
 	}
 
+	w, err := wdb.randomPick(l)
+	if err != nil {
+		return word{}, err
+	}
+
+	wordContained := slices.ContainsFunc(avoidList, func(wo word) bool {
+		return w.isEqual(wo)
+	})
+	if wordContained || wdb.Has(l, WC_BANNED, w) {
+		return wdb.RandomPick(l, avoidList, retryAkkumulator+1)
+	}
+
+	return w, nil
+}
+
+func (wdb wordDatabase) randomPick(l language) (word, error) {
+	defer wdb.rlock()()
+
 	db, ok := wdb.db[l]
 	if !ok {
 		return word{}, fmt.Errorf("RandomPick failed with unknown language: '%s'", l)
@@ -432,6 +873,10 @@ func (wdb wordDatabase) RandomPick(l language, avoidList []word, retryAkkumulato
 		}
 	}
 
+	if len(db_c) == 0 {
+		return word{}, fmt.Errorf("RandomPick with lang '%s' failed with empty collection: '%s'", l, collection)
+	}
+
 	randsource := rand.NewSource(time.Now().UnixNano())
 	randgenerator := rand.New(randsource)
 	rolledLine := randgenerator.Intn(len(db_c))
@@ -439,14 +884,6 @@ func (wdb wordDatabase) RandomPick(l language, avoidList []word, retryAkkumulato
 	currentLine := 0
 	for w := range db_c {
 		if currentLine == rolledLine {
-
-			wordContained := slices.ContainsFunc(avoidList, func(wo word) bool {
-				return w.isEqual(wo)
-			})
-			if wordContained {
-				return wdb.RandomPick(l, avoidList, retryAkkumulator+1)
-			}
-
 			return w, nil
 		}
 
@@ -466,35 +903,125 @@ func (wdb wordDatabase) RandomPickWithFallback(l language, avoidList []word, ret
 }
 
 type keyboard struct {
-	KeyGrid [][]keyboardKey
+	Layout     string
+	KeyGrid    [][]keyboardKey
+	BoardMatch [][][]match // MODE_MULTI only, per key of KeyGrid the match per board
+}
+
+// keyboardLayouts are referenced by name from the language registry as a
+// language default, players may pick any of them instead.
+var keyboardLayouts = map[string][][]string{
+	"qwerty": {
+		{"Q", "W", "E", "R", "T", "Y", "U", "I", "O", "P", "Delete"},
+		{"A", "S", "D", "F", "G", "H", "J", "K", "L", "Enter"},
+		{"Z", "X", "C", "V", "B", "N", "M"},
+	},
+	"qwertz": {
+		{"Q", "W", "E", "R", "T", "Z", "U", "I", "O", "P", "Ü"},
+		{"A", "S", "D", "F", "G", "H", "J", "K", "L", "Ö", "Ä"},
+		{"Enter", "Y", "X", "C", "V", "B", "N", "M", "Delete"},
+	},
+	"azerty": {
+		{"A", "Z", "E", "R", "T", "Y", "U", "I", "O", "P", "Delete"},
+		{"Q", "S", "D", "F", "G", "H", "J", "K", "L", "M", "Enter"},
+		{"W", "X", "C", "V", "B", "N"},
+	},
+	"dvorak": {
+		{"P", "Y", "F", "G", "C", "R", "L", "Delete"},
+		{"A", "O", "E", "U", "I", "D", "H", "T", "N", "S", "Enter"},
+		{"Q", "J", "K", "X", "B", "M", "W", "V", "Z"},
+	},
+	"colemak": {
+		{"Q", "W", "F", "P", "G", "J", "L", "U", "Y", "Delete"},
+		{"A", "R", "S", "T", "D", "H", "N", "E", "I", "O", "Enter"},
+		{"Z", "X", "C", "V", "B", "K", "M"},
+	},
+}
+
+func keyboardLayoutNames() []string {
+	names := make([]string, 0, len(keyboardLayouts))
+	for name := range keyboardLayouts {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	return names
 }
 
-func (k *keyboard) Init(l language, lgs []letterGuess) {
-	k.KeyGrid = [][]keyboardKey{
-		{{"Q", false, MatchNone}, {"W", false, MatchNone}, {"E", false, MatchNone}, {"R", false, MatchNone}, {"T", false, MatchNone}, {"Y", false, MatchNone}, {"U", false, MatchNone}, {"I", false, MatchNone}, {"O", false, MatchNone}, {"P", false, MatchNone}, {"Delete", false, MatchNone}},
-		{{"A", false, MatchNone}, {"S", false, MatchNone}, {"D", false, MatchNone}, {"F", false, MatchNone}, {"G", false, MatchNone}, {"H", false, MatchNone}, {"J", false, MatchNone}, {"K", false, MatchNone}, {"L", false, MatchNone}, {"Enter", false, MatchNone}},
-		{{"Z", false, MatchNone}, {"X", false, MatchNone}, {"C", false, MatchNone}, {"V", false, MatchNone}, {"B", false, MatchNone}, {"N", false, MatchNone}, {"M", false, MatchNone}},
+// Init builds the key grid of the named layout (qwerty if unknown) and colours
+// every key by the best match of its letter among the guesses, e.g. 'Ä' once
+// 'ä' was guessed in a language keeping umlauts. Umlaut keys are left out for
+// any other policy, no guess typed with them could be in the word list.
+func (k *keyboard) Init(layoutName string, umlauts wordlist.UmlautPolicy, lgs []letterGuess) {
+	layout, ok := keyboardLayouts[layoutName]
+	if !ok {
+		layoutName = "qwerty"
+		layout = keyboardLayouts[layoutName]
+	}
+
+	k.Layout = layoutName
+
+	k.KeyGrid = make([][]keyboardKey, len(layout))
+	for ri, row := range layout {
+		k.KeyGrid[ri] = make([]keyboardKey, 0, len(row))
+		for _, key := range row {
+			if _, isUmlaut := wordlist.Transliterations[unicode.ToLower(firstRune(key))]; isUmlaut && umlauts != wordlist.UMLAUTS_KEEP {
+				continue
+			}
+
+			k.KeyGrid[ri] = append(k.KeyGrid[ri], keyboardKey{key, false, MatchNone})
+		}
 	}
 
 	for ri, r := range k.KeyGrid {
-	KeyLoop:
 		for ki, kk := range r {
-			for _, lg := range lgs {
-				if kk.Key == "Enter" || kk.Key == "Delete" {
-					continue KeyLoop
-				}
+			if m := bestMatch(kk.Key, lgs); m != 0 {
+				k.KeyGrid[ri][ki].IsUsed = true
+				k.KeyGrid[ri][ki].Match = m
+			}
+		}
+	}
+}
 
-				KeyR := firstRune(kk.Key)
-				betterMatch := (k.KeyGrid[ri][ki].Match == MatchNone) ||
-					(k.KeyGrid[ri][ki].Match == MatchVague && lg.Match == MatchExact)
+// InitBoards builds the key grid for MODE_MULTI, BoardMatch additionally
+// holds the match of every key per board (zero if not guessed on that board).
+func (k *keyboard) InitBoards(layoutName string, umlauts wordlist.UmlautPolicy, lgsPerBoard [][]letterGuess) {
+	k.Init(layoutName, umlauts, slices.Concat(lgsPerBoard...))
 
-				if lg.Letter == unicode.ToLower(KeyR) && betterMatch {
-					k.KeyGrid[ri][ki].IsUsed = true
-					k.KeyGrid[ri][ki].Match = lg.Match
-				}
+	k.BoardMatch = make([][][]match, len(k.KeyGrid))
+	for ri, r := range k.KeyGrid {
+		k.BoardMatch[ri] = make([][]match, len(r))
+		for ki, kk := range r {
+			if kk.Key == "Enter" || kk.Key == "Delete" {
+				continue
 			}
+
+			ms := make([]match, len(lgsPerBoard))
+			for bi, lgs := range lgsPerBoard {
+				ms[bi] = bestMatch(kk.Key, lgs)
+			}
+			k.BoardMatch[ri][ki] = ms
+		}
+	}
+}
+
+// bestMatch returns the best match of the letter of key among the guesses,
+// zero if it wasn't guessed at all.
+func bestMatch(key string, lgs []letterGuess) match {
+	if key == "Enter" || key == "Delete" {
+		return 0
+	}
+
+	letter := unicode.ToLower(firstRune(key))
+
+	best := match(0)
+	for _, lg := range lgs {
+		if lg.Letter == letter && lg.Match > best {
+			best = lg.Match
 		}
 	}
+
+	return best
 }
 
 func firstRune(s string) rune {
@@ -535,62 +1062,93 @@ func getAllFilenames(efs iofs.FS) (files []string, err error) {
 	return files, nil
 }
 
-func filePathsByLang() map[language]map[wordCollection][]string {
-	return map[language]map[wordCollection][]string{
-		LANG_EN: {
-			WC_ALL: {
-				"configs/corpora-eng_news_2023_10K-export.txt",
-				"configs/en-en.words.v2.txt",
-			},
-			WC_COMMON: {
-				"configs/corpora-eng_news_2023_10K-export.txt",
-			},
-		},
-		LANG_DE: {
-			WC_ALL: {
-				"configs/corpora-deu_news_2023_10K-export.txt",
-				"configs/de-de.words.v2.txt",
-			},
-			WC_COMMON: {
-				"configs/corpora-deu_news_2023_10K-export.txt",
-			},
-		},
-	}
-}
-
 func main() {
-	log.Println("staring server...")
+	if len(os.Args) > 1 {
+		os.Exit(runCommand(os.Args[1:], os.Stdout, os.Stderr))
+	}
 
 	envCfg := envConfig()
+
+	logger, err := newLogger(os.Stderr, envCfg.logLevel, envCfg.logFormat)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	slog.SetDefault(logger)
+
+	tracer, err := newTracer(envCfg)
+	if err != nil {
+		fatal("init tracing failed", "err", err)
+	}
+	tracing.SetDefault(tracer)
+
+	slog.Info("starting server...")
 	sessions := sessions{}
 
-	wordDb := wordDatabase{}
-	err := wordDb.Init(fs, filePathsByLang())
+	// loaded in the background once the server listens, see awaitLoaded
+	wordDb := newWordDatabase()
+	wordsLoaded := &atomic.Bool{}
+
+	// sessions live in memory of the instance, there's no store to check
+	ready := &readiness{}
+	ready.Add("word_database", loadedCheck(wordsLoaded))
+
+	var wlStore *wordListStore
+	if envCfg.wordListStoreDir != "" {
+		wlStore = &wordListStore{dir: envCfg.wordListStoreDir}
+	}
+	curator := wordCurator{wdb: wordDb, store: wlStore}
+
+	definitions := newDefinitionProvider(envCfg)
+
+	sealer, err := newPuzzleSealer(envCfg.puzzleSecret)
+	if err != nil {
+		fatal("init custom puzzles failed", "err", err)
+	}
+	customResults := newCustomPuzzleResults()
+
+	csrfTokens, err := middleware.NewCSRFTokens(SESSION_MAX_AGE_IN_SECONDS * time.Second)
+	if err != nil {
+		fatal("init csrf tokens failed", "err", err)
+	}
+
+	leaderboards, err := newLeaderboard(envCfg.leaderboardStoreDir)
+	if err != nil {
+		fatal("loading leaderboard failed", "err", err)
+	}
+
+	accs, err := newAccounts(envCfg.accountStoreDir)
+	if err != nil {
+		fatal("loading accounts failed", "err", err)
+	}
+
+	magicLinkMailer, err := newMailer(envCfg.mailer, envCfg.mailDir)
 	if err != nil {
-		log.Fatalf("init wordDatabase failed: %s", err)
+		fatal("init mailer failed", "err", err)
 	}
 
-	log.Printf("env conf:\n%s", envCfg)
+	baseURL, err := publicBaseURL(envCfg)
+	if err != nil {
+		fatal("invalid public base url", "err", err)
+	}
+
+	slog.Info("env config", "env", envCfg)
 
 	// t := template.Must(template.ParseFS(fs, "templates/index.html.tmpl", "templates/lettr-form.html.tmpl"))
 	// log.Printf("template name: %s", t.Name())
-	t := template.Must(template.New("index.html.tmpl").Funcs(funcMap).ParseFS(
+	t := template.Must(template.New("index.html.tmpl").Funcs(funcMap).Funcs(translateFuncs(translations, false)).ParseFS(
 		fs,
-		"templates/index.html.tmpl",
-		"templates/lettr-form.html.tmpl",
-		"templates/help.html.tmpl",
+		templateFiles...,
 	))
 
 	mux := http.NewServeMux()
 
-	staticFS, err := iofs.Sub(fs, "web/static")
-	if err != nil {
-		log.Fatalf("subtree for 'static' dir of embed fs failed: %s", err)
+	if err := staticFiles.checkNPMAssets(); err != nil {
+		fatal("loading static assets failed", "err", err)
 	}
-
 	mux.Handle(
 		"GET /static/",
-		http.StripPrefix("/static", http.FileServer(http.FS(staticFS))),
+		http.StripPrefix("/static", staticFiles),
 	)
 
 	mux.HandleFunc("GET /", func(w http.ResponseWriter, req *http.Request) {
@@ -599,15 +1157,17 @@ func main() {
 		p := sess.lastEvaluatedAttempt
 		// log.Printf("debug '/' route - sess.lastEvaluatedAttempt:\n %v\n", wo)
 		p.Debug = sess.activeSolutionWord.String()
-		sessions.updateOrSet(sess)
+		sessions.save(req.Context(), sess)
 
-		fData := FormData{}.New(sess.language, p, sess.PastWords(), sess.activeSolutionWord.hasDublicateLetters())
-		fData.IsSolved = p.isSolved()
-		fData.IsLoose = p.isLoose()
+		fData := FormData{}.New(sess, p)
+		fData.IsSolved = sess.isSolved()
+		fData.IsLoose = sess.isLoose()
+		fData.CSPNonce = middleware.CSPNonce(req.Context())
+		fData.CSRFToken = csrfTokens.Token(sess.id)
 
 		err := t.ExecuteTemplate(w, "index.html.tmpl", fData)
 		if err != nil {
-			log.Printf("error t.Execute '/' route: %s", err)
+			slog.ErrorContext(req.Context(), "executing template failed", "route", "/", "err", err)
 		}
 	})
 
@@ -616,17 +1176,17 @@ func main() {
 
 		p := s.lastEvaluatedAttempt
 
-		sessions.updateOrSet(s)
+		sessions.save(r.Context(), s)
 
 		p.Debug = s.activeSolutionWord.String()
 
-		fData := FormData{}.New(s.language, p, s.PastWords(), s.activeSolutionWord.hasDublicateLetters())
-		fData.IsSolved = p.isSolved()
-		fData.IsLoose = p.isLoose()
+		fData := FormData{}.New(s, p)
+		fData.IsSolved = s.isSolved()
+		fData.IsLoose = s.isLoose()
 
 		err = t.ExecuteTemplate(w, "lettr-form", fData)
 		if err != nil {
-			log.Printf("error t.ExecuteTemplate '/lettr' route: %s", err)
+			slog.ErrorContext(r.Context(), "executing template failed", "route", "/lettr", "err", err)
 		}
 	})
 
@@ -636,109 +1196,565 @@ func main() {
 		// b, err := io.ReadAll(r.Body)
 		// if err != nil {
 		// 	// log.Fatalln(err)
-		// 	log.Printf("error: %s", err)
+		// 	slog.WarnContext(r.Context(), "parsing form failed", "err", err)
 		// }
 		// log.Printf("word: %s\nbody:\n%s", s.activeWord, b)
 
 		err := r.ParseForm()
 		if err != nil {
 			// log.Fatalln(err)
-			log.Printf("error: %s", err)
+			slog.WarnContext(r.Context(), "parsing form failed", "err", err)
 		}
 
 		p := s.lastEvaluatedAttempt
 		p.Debug = s.activeSolutionWord.String()
 
-		if p.isSolved() || p.isLoose() {
+		if s.gameOver() {
 			w.WriteHeader(204)
 			return
 		}
 
 		// log.Printf("debug '/lettr' route - row compare: activeRow='%d' / formRowCount='%d' \n", s.lastEvaluatedAttempt.activeRow(), countFilledFormRows(r.PostForm))
 		if s.lastEvaluatedAttempt.activeRow() != countFilledFormRows(r.PostForm)-1 {
+			appMetrics.guessRejected(REJECT_FAKED_ROWS)
 			w.WriteHeader(422)
 			w.Write([]byte("faked rows"))
 			return
 		}
 
-		p, err = parseForm(p, r.PostForm, s.activeSolutionWord, s.language, wordDb)
+		p, err = parseForm(r.Context(), p, r.PostForm, s.activeSolutionWord, s.language, wordDb)
 		if err == ErrNotInWordList {
+			appMetrics.guessRejected(REJECT_NOT_IN_WORD_LIST)
 			w.WriteHeader(422)
-			w.Write([]byte("word not in word list"))
+
+			guessedWord, _ := sliceToWord(r.PostForm[fmt.Sprintf("r%d", s.lastEvaluatedAttempt.activeRow())], languages.config(s.language).normaliser())
+			err = t.ExecuteTemplate(w, "not-in-word-list", notInWordListData{guessedWord, s.uiLocale})
+			if err != nil {
+				slog.ErrorContext(r.Context(), "executing template failed", "route", "/lettr", "err", err)
+			}
 			return
 		}
 
 		s.lastEvaluatedAttempt = p
-		sessions.updateOrSet(s)
+		if p.activeRow() == 1 {
+			// puzzles are handed out to every visitor, only a first guess starts a game
+			appMetrics.gameStarted(s.language)
+		}
+		if row := p.activeRow(); row > 0 {
+			// redacted unless LOG_LEVEL=debug, see sensitiveLogKeys
+			slog.DebugContext(r.Context(), "guess evaluated", "solution", s.activeSolutionWord.String(), "guess", p.Guesses[row-1].word().String(), "row", row)
+		}
+		if p.isSolved() || p.isLoose() {
+			o := OUTCOME_LOST
+			if p.isSolved() {
+				o = OUTCOME_SOLVED
+			}
+
+			if date, ok := s.dailyPuzzleDate(); ok {
+				err := leaderboards.Report(date, s.language, dailyResult{
+					PlayerID:   playerID(w, r, true),
+					Outcome:    o,
+					Attempts:   int(p.activeRow()),
+					Hints:      len(s.hintsUsed),
+					Duration:   time.Since(s.gameStartedAt),
+					FinishedAt: time.Now(),
+				})
+				if err != nil && !errors.Is(err, ErrDailyAlreadyDone) {
+					slog.ErrorContext(r.Context(), "reporting daily result failed", "err", err)
+				}
+			} else if s.customPuzzleID != "" {
+				customResults.Report(s.customPuzzleID, s.id, customPuzzleResult{Outcome: o, Guesses: p.Guesses, FinishedAt: time.Now()})
+			}
 
-		fData := FormData{}.New(s.language, p, s.PastWords(), s.activeSolutionWord.hasDublicateLetters())
-		fData.IsSolved = p.isSolved()
-		fData.IsLoose = p.isLoose()
+			s.finishPuzzle(o, time.Now(), wordDb)
+		}
+		sessions.save(r.Context(), s)
+
+		// a speedrun continues with the next puzzle
+		p = s.lastEvaluatedAttempt
+		p.Debug = s.activeSolutionWord.String()
+
+		fData := FormData{}.New(s, p)
+		fData.IsSolved = s.isSolved()
+		fData.IsLoose = s.isLoose()
 
 		err = t.ExecuteTemplate(w, "lettr-form", fData)
 		if err != nil {
-			log.Printf("error t.ExecuteTemplate '/lettr' route: %s", err)
+			slog.ErrorContext(r.Context(), "executing template failed", "route", "/lettr", "err", err)
 		}
 	})
 
 	mux.HandleFunc("POST /new", func(w http.ResponseWriter, r *http.Request) {
 		s := handleSession(w, r, &sessions, wordDb)
 
-		// handle lang switch
-		l := s.language
-		maybeLang := r.FormValue("lang")
-		if maybeLang != "" {
-			l, _ = NewLang(maybeLang)
-			s.language = l
-
-			data := struct {
-				Language language
-			}{
-				Language: l,
-			}
+		p := puzzle{}
 
-			err := t.ExecuteTemplate(w, "oob-lang-switch", data)
-			if err != nil {
-				log.Printf("error t.ExecuteTemplate '/new' route: %s", err)
-			}
+		s.newGame(wordDb)
+		sessions.save(r.Context(), s)
+
+		p.Debug = s.activeSolutionWord.String()
+
+		fData := FormData{}.New(s, p)
+		fData.IsSolved = s.isSolved()
+		fData.IsLoose = s.isLoose()
+
+		// w.Header().Add("HX-Refresh", "true")
+		err := t.ExecuteTemplate(w, "lettr-form", fData)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "executing template failed", "route", "/new", "err", err)
 		}
+	})
 
-		p := puzzle{}
+	mux.HandleFunc("POST /boards", func(w http.ResponseWriter, r *http.Request) {
+		s := handleSession(w, r, &sessions, wordDb)
 
-		s.lastEvaluatedAttempt = p
-		s.AddPastWord(s.activeSolutionWord)
-		s.activeSolutionWord = wordDb.RandomPickWithFallback(l, s.pastWords, 0)
-		sessions.updateOrSet(s)
+		err := r.ParseForm()
+		if err != nil {
+			slog.WarnContext(r.Context(), "parsing form failed", "err", err)
+		}
+
+		if s.Mode() != MODE_MULTI {
+			w.WriteHeader(422)
+			w.Write([]byte("no multi board game running"))
+			return
+		}
+
+		if s.gameOver() {
+			w.WriteHeader(204)
+			return
+		}
+
+		// guards against replayed or doubled submits, like the row check of '/lettr'
+		if r.PostForm.Get("round") != strconv.Itoa(s.boards.Rounds()) {
+			appMetrics.guessRejected(REJECT_FAKED_ROWS)
+			w.WriteHeader(422)
+			w.Write([]byte("faked rows"))
+			return
+		}
+
+		n := languages.config(s.language).normaliser()
+		guessedWord, err := sliceToWord(r.PostForm["g"], n)
+		if err != nil {
+			appMetrics.guessRejected(REJECT_INVALID_GUESS)
+			w.WriteHeader(422)
+			w.Write([]byte("invalid guess"))
+			return
+		}
+
+		if !wordDb.Exists(s.language, guessedWord) {
+			appMetrics.guessRejected(REJECT_NOT_IN_WORD_LIST)
+			w.WriteHeader(422)
+
+			err = t.ExecuteTemplate(w, "not-in-word-list", notInWordListData{guessedWord, s.uiLocale})
+			if err != nil {
+				slog.ErrorContext(r.Context(), "executing template failed", "route", "/boards", "err", err)
+			}
+			return
+		}
+
+		s.boards = s.boards.guess(n, guessedWord)
+		if s.boards.Rounds() == 1 {
+			appMetrics.gameStarted(s.language)
+		}
+		if s.boards.isSolved() {
+			s.finishPuzzle(OUTCOME_SOLVED, time.Now(), wordDb)
+		} else if s.boards.isLoose() {
+			s.finishPuzzle(OUTCOME_LOST, time.Now(), wordDb)
+		}
+		sessions.save(r.Context(), s)
 
+		fData := FormData{}.New(s, s.lastEvaluatedAttempt)
+		fData.IsSolved = s.isSolved()
+		fData.IsLoose = s.isLoose()
+
+		err = t.ExecuteTemplate(w, "lettr-form", fData)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "executing template failed", "route", "/boards", "err", err)
+		}
+	})
+
+	mux.HandleFunc("POST /mode", func(w http.ResponseWriter, r *http.Request) {
+		s := handleSession(w, r, &sessions, wordDb)
+
+		m, err := NewGameMode(r.FormValue("mode"))
+		if err != nil {
+			w.WriteHeader(422)
+			w.Write([]byte("unknown game mode"))
+			return
+		}
+
+		minutes := speedrunMinutes[0]
+		if r.FormValue("minutes") != "" {
+			minutes, err = strconv.Atoi(r.FormValue("minutes"))
+			if err != nil || !slices.Contains(speedrunMinutes, minutes) {
+				w.WriteHeader(422)
+				w.Write([]byte("unsupported speedrun length"))
+				return
+			}
+		}
+
+		boardCount := boardCounts[0]
+		if r.FormValue("boards") != "" {
+			boardCount, err = strconv.Atoi(r.FormValue("boards"))
+			if err != nil || !slices.Contains(boardCounts, boardCount) {
+				w.WriteHeader(422)
+				w.Write([]byte("unsupported number of boards"))
+				return
+			}
+		}
+
+		s.setMode(m, minutes, boardCount, time.Now(), wordDb)
+		sessions.save(r.Context(), s)
+
+		p := s.lastEvaluatedAttempt
 		p.Debug = s.activeSolutionWord.String()
 
-		fData := FormData{}.New(s.language, p, s.PastWords(), s.activeSolutionWord.hasDublicateLetters())
-		fData.IsSolved = p.isSolved()
-		fData.IsLoose = p.isLoose()
+		fData := FormData{}.New(s, p)
 
-		// w.Header().Add("HX-Refresh", "true")
-		err := t.ExecuteTemplate(w, "lettr-form", fData)
+		err = t.ExecuteTemplate(w, "lettr-form", fData)
 		if err != nil {
-			log.Printf("error t.ExecuteTemplate '/new' route: %s", err)
+			slog.ErrorContext(r.Context(), "executing template failed", "route", "/mode", "err", err)
 		}
 	})
 
-	mux.HandleFunc("POST /help", func(w http.ResponseWriter, r *http.Request) {
+	mux.HandleFunc("POST /hint", func(w http.ResponseWriter, r *http.Request) {
+		s := handleSession(w, r, &sessions, wordDb)
+
+		h, ok := NewHint(r.FormValue("hint"))
+		if !ok {
+			w.WriteHeader(422)
+			w.Write([]byte("unknown hint"))
+			return
+		}
+
+		hd := s.revealHint(h)
+		sessions.save(r.Context(), s)
+
+		err := t.ExecuteTemplate(w, "hint-"+string(h), hd)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "executing template failed", "route", "/hint", "err", err)
+		}
+	})
+
+	mux.HandleFunc("GET /history", func(w http.ResponseWriter, r *http.Request) {
+		s := handleSession(w, r, &sessions, wordDb)
+
+		err := t.ExecuteTemplate(w, "history", historyData{Locale: s.uiLocale, Records: s.History()})
+		if err != nil {
+			slog.ErrorContext(r.Context(), "executing template failed", "route", "/history", "err", err)
+		}
+	})
+
+	mux.HandleFunc("GET /history/{id}", func(w http.ResponseWriter, r *http.Request) {
+		s := handleSession(w, r, &sessions, wordDb)
+
+		gr, ok := s.historyRecord(r.PathValue("id"))
+		if !ok || !gr.IsReplayable() {
+			w.WriteHeader(404)
+			w.Write([]byte("game not found"))
+			return
+		}
+
+		step, err := strconv.Atoi(r.FormValue("step"))
+		if err != nil {
+			step = 0
+		}
+
+		err = t.ExecuteTemplate(w, "replay", newReplayData(s.uiLocale, gr, step))
+		if err != nil {
+			slog.ErrorContext(r.Context(), "executing template failed", "route", "/history/{id}", "err", err)
+		}
+	})
+
+	mux.HandleFunc("GET /puzzles", func(w http.ResponseWriter, r *http.Request) {
+		s := handleSession(w, r, &sessions, wordDb)
+
+		err := t.ExecuteTemplate(w, "puzzles", newPuzzlesData(baseURL, s, customResults))
+		if err != nil {
+			slog.ErrorContext(r.Context(), "executing template failed", "route", "/puzzles", "err", err)
+		}
+	})
+
+	mux.HandleFunc("POST /puzzles", func(w http.ResponseWriter, r *http.Request) {
+		s := handleSession(w, r, &sessions, wordDb)
+
+		data := newPuzzlesData(baseURL, s, customResults)
+		data.WordInput = r.FormValue("word")
+
+		wo, err := languages.config(s.language).normaliser().Word(r.FormValue("word"))
+		if err != nil {
+			data.Error = tr(s.uiLocale, "suggest.invalid")
+		} else if cp, err := s.createPuzzle(s.language, wo, wordDb, sealer); errors.Is(err, ErrNotInWordList) {
+			data.Error = tr(s.uiLocale, "error.not_in_word_list")
+		} else if err != nil {
+			slog.ErrorContext(r.Context(), "creating custom puzzle failed", "err", err)
+			w.WriteHeader(500)
+			return
+		} else {
+			sessions.save(r.Context(), s)
+
+			data = newPuzzlesData(baseURL, s, customResults)
+			data.Created = puzzleURL(baseURL, cp.Token)
+		}
+
+		err = t.ExecuteTemplate(w, "puzzles", data)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "executing template failed", "route", "/puzzles", "err", err)
+		}
+	})
+
+	mux.HandleFunc("GET /p/{token}", func(w http.ResponseWriter, r *http.Request) {
 		s := handleSession(w, r, &sessions, wordDb)
 
+		cp, err := sealer.Open(r.PathValue("token"))
+		if err != nil {
+			w.WriteHeader(404)
+			w.Write([]byte(tr(s.uiLocale, "puzzle.invalid")))
+			return
+		}
+
+		if s.customPuzzleID != cp.ID {
+			s.startCustomPuzzle(cp, wordDb)
+		}
+		sessions.save(r.Context(), s)
+
 		p := s.lastEvaluatedAttempt
+		p.Debug = s.activeSolutionWord.String()
+
+		fData := FormData{}.New(s, p)
+		fData.IsSolved = s.isSolved()
+		fData.IsLoose = s.isLoose()
+		fData.CSPNonce = middleware.CSPNonce(r.Context())
+		fData.CSRFToken = csrfTokens.Token(s.id)
 
-		sessions.updateOrSet(s)
+		err = t.ExecuteTemplate(w, "index.html.tmpl", fData)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "executing template failed", "route", "/p/{token}", "err", err)
+		}
+	})
 
+	mux.HandleFunc("POST /daily", func(w http.ResponseWriter, r *http.Request) {
+		s := handleSession(w, r, &sessions, wordDb)
+		pid := playerID(w, r, true)
+
+		date := dailyDate(time.Now())
+		if _, played := leaderboards.Result(date, s.language, pid); played {
+			w.WriteHeader(422)
+			w.Write([]byte(tr(s.uiLocale, "leaderboard.daily_played")))
+			return
+		}
+
+		solution, err := leaderboards.DailySolution(date, s.language, wordDb)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "picking daily solution failed", "err", err)
+			w.WriteHeader(500)
+			return
+		}
+
+		if cp := dailyPuzzle(date, s.language, solution); s.customPuzzleID != cp.ID {
+			startedAt, isNew, err := leaderboards.Start(date, s.language, pid, time.Now())
+			if err != nil {
+				slog.ErrorContext(r.Context(), "starting daily failed", "err", err)
+				w.WriteHeader(500)
+				return
+			}
+
+			if !isNew {
+				err := leaderboards.Report(date, s.language, abandonedDaily(pid, startedAt, time.Now()))
+				if err != nil && !errors.Is(err, ErrDailyAlreadyDone) {
+					slog.ErrorContext(r.Context(), "reporting abandoned daily failed", "err", err)
+				}
+
+				w.WriteHeader(422)
+				w.Write([]byte(tr(s.uiLocale, "leaderboard.daily_played")))
+				return
+			}
+
+			s.startCustomPuzzle(cp, wordDb)
+		}
+		sessions.save(r.Context(), s)
+
+		p := s.lastEvaluatedAttempt
 		p.Debug = s.activeSolutionWord.String()
 
-		fData := FormData{}.New(s.language, p, s.PastWords(), s.activeSolutionWord.hasDublicateLetters())
-		fData.IsSolved = p.isSolved()
-		fData.IsLoose = p.isLoose()
+		fData := FormData{}.New(s, p)
+		fData.IsSolved = s.isSolved()
+		fData.IsLoose = s.isLoose()
+
+		err = t.ExecuteTemplate(w, "lettr-form", fData)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "executing template failed", "route", "/daily", "err", err)
+		}
+	})
+
+	mux.HandleFunc("POST /language", func(w http.ResponseWriter, r *http.Request) {
+		s := handleSession(w, r, &sessions, wordDb)
+
+		l, err := NewLang(r.FormValue("lang"))
+		if err != nil {
+			w.WriteHeader(422)
+			w.Write([]byte("unknown language"))
+			return
+		}
+
+		s.switchLanguage(l, wordDb)
+		sessions.save(r.Context(), s)
+
+		err = t.ExecuteTemplate(w, "oob-lang-switch", languages.config(l))
+		if err != nil {
+			slog.ErrorContext(r.Context(), "executing template failed", "route", "/language", "err", err)
+		}
+
+		p := s.lastEvaluatedAttempt
+		p.Debug = s.activeSolutionWord.String()
+
+		fData := FormData{}.New(s, p)
+		fData.IsSolved = s.isSolved()
+		fData.IsLoose = s.isLoose()
+
+		err = t.ExecuteTemplate(w, "lettr-form", fData)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "executing template failed", "route", "/language", "err", err)
+		}
+	})
+
+	mux.HandleFunc("POST /locale", func(w http.ResponseWriter, r *http.Request) {
+		s := handleSession(w, r, &sessions, wordDb)
+
+		locale := r.FormValue("locale")
+		if !translations.Has(locale) {
+			w.WriteHeader(422)
+			w.Write([]byte("unknown locale"))
+			return
+		}
+
+		s.uiLocale = locale
+		sessions.save(r.Context(), s)
+
+		// every rendered text changes, a full reload is simpler than oob swapping all of them
+		w.Header().Set("HX-Refresh", "true")
+		w.WriteHeader(204)
+	})
+
+	mux.HandleFunc("POST /keyboard", func(w http.ResponseWriter, r *http.Request) {
+		s := handleSession(w, r, &sessions, wordDb)
+
+		layout := r.FormValue("layout")
+		if _, ok := keyboardLayouts[layout]; !ok && layout != "" {
+			w.WriteHeader(422)
+			w.Write([]byte("unknown keyboard layout"))
+			return
+		}
+
+		s.keyboardLayout = layout
+		sessions.save(r.Context(), s)
+
+		p := s.lastEvaluatedAttempt
+		fData := FormData{}.New(s, p)
+
+		err := t.ExecuteTemplate(w, "keyboard", fData)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "executing template failed", "route", "/keyboard", "err", err)
+		}
+	})
+
+	mux.HandleFunc("GET /definition", func(w http.ResponseWriter, r *http.Request) {
+		s := handleSession(w, r, &sessions, wordDb)
+
+		if !s.gameOver() {
+			// don't leak the solution of a running game
+			w.WriteHeader(204)
+			return
+		}
+
+		data := definitionData{Word: s.activeSolutionWord, Locale: s.uiLocale}
+		ms, err := definitions.Define(r.Context(), s.language, s.activeSolutionWord)
+		if err != nil && !errors.Is(err, ErrNoDefinition) {
+			slog.ErrorContext(r.Context(), "looking up definition failed", "err", err)
+		}
+		data.Meanings = ms
+
+		err = t.ExecuteTemplate(w, "definition-panel", data)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "executing template failed", "route", "/definition", "err", err)
+		}
+	})
+
+	mux.HandleFunc("POST /suggest", func(w http.ResponseWriter, r *http.Request) {
+		s := handleSession(w, r, &sessions, wordDb)
+
+		lc := languages.config(s.language)
+		wo, err := lc.normaliser().Word(r.FormValue("word"))
+		if err != nil || !lc.inAlphabet(wo) {
+			w.WriteHeader(422)
+			w.Write([]byte(tr(s.uiLocale, "suggest.invalid")))
+			return
+		}
+
+		err = curator.Suggest(s.language, wo)
+		if err == ErrAlreadyInWordList {
+			w.Write([]byte(tr(s.uiLocale, "suggest.known")))
+			return
+		}
+		if err == ErrAlreadySuggested {
+			w.Write([]byte(tr(s.uiLocale, "suggest.pending")))
+			return
+		}
+		if err == ErrTooManySuggestions {
+			w.WriteHeader(429)
+			w.Write([]byte(tr(s.uiLocale, "suggest.full")))
+			return
+		}
+		if err != nil {
+			slog.ErrorContext(r.Context(), "saving word suggestion failed", "err", err)
+			w.WriteHeader(500)
+			w.Write([]byte(tr(s.uiLocale, "suggest.failed")))
+			return
+		}
+
+		w.Write([]byte(tr(s.uiLocale, "suggest.saved")))
+	})
+
+	registerAdminRoutes(mux, t, envCfg, curator, func(w http.ResponseWriter, r *http.Request) string {
+		return csrfTokens.Token(handleSession(w, r, &sessions, wordDb).id)
+	})
+	registerHealthRoutes(mux, ready, wordDb)
+	registerSecurityRoutes(mux)
+
+	appMetrics.observeSessions()
+	appMetrics.observeWordDatabase(wordDb)
+	if envCfg.metricsToken != "" {
+		mux.Handle("GET /metrics", metricsAuth(envCfg.metricsToken, appMetrics.registry.Handler()))
+	}
+
+	registerLeaderboardRoutes(mux, t, leaderboards, func(w http.ResponseWriter, r *http.Request) session {
+		return handleSession(w, r, &sessions, wordDb)
+	})
+
+	registerAccountRoutes(mux, t, accs, magicLinkMailer, baseURL, func(w http.ResponseWriter, r *http.Request) session {
+		return handleSession(w, r, &sessions, wordDb)
+	}, sessions.updateOrSet, csrfTokens.Token)
+
+	rooms := newRoomHub(wordDb)
+	registerRoomRoutes(mux, t, rooms, func(w http.ResponseWriter, r *http.Request) session {
+		return handleSession(w, r, &sessions, wordDb)
+	})
+
+	mux.HandleFunc("POST /help", func(w http.ResponseWriter, r *http.Request) {
+		s := handleSession(w, r, &sessions, wordDb)
+
+		p := s.lastEvaluatedAttempt
+
+		sessions.save(r.Context(), s)
+
+		// hints are only revealed by POST /hint, which records them
+		fData := FormData{}.New(s, p)
+		fData.IsSolved = s.isSolved()
+		fData.IsLoose = s.isLoose()
 
 		err := t.ExecuteTemplate(w, "help", fData)
 		if err != nil {
-			log.Printf("error t.ExecuteTemplate '/help' route: %s", err)
+			slog.ErrorContext(r.Context(), "executing template failed", "route", "/help", "err", err)
 		}
 	})
 
@@ -749,23 +1765,67 @@ func main() {
 		counter.count++
 		defer counter.mu.Unlock()
 
-		b, err := io.ReadAll(req.Body)
+		n, err := io.Copy(io.Discard, req.Body)
 		if err != nil {
-			log.Fatalln(err)
+			slog.WarnContext(req.Context(), "reading counter body failed", "err", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
 		}
 
-		log.Printf("Method: %s\nbody:\n%s", req.Method, b)
+		slog.DebugContext(req.Context(), "counter", "method", req.Method, "body_bytes", n)
 
 		io.WriteString(w, fmt.Sprintf("<span>%d</span>", counter.count))
 
 	})
 
+	limits, err := rateLimits(envCfg.rateLimits)
+	if err != nil {
+		fatal("invalid RATE_LIMITS", "err", err)
+	}
+
 	middlewares := []func(h http.Handler) http.Handler{
+		func(h http.Handler) http.Handler {
+			return accountSync(h, &sessions, accs)
+		},
+		func(h http.Handler) http.Handler {
+			return awaitLoaded(h, wordsLoaded)
+		},
 		func(h http.Handler) http.Handler {
 			return middleware.NewRequestSize(h, 32*1024 /* 32kiB */)
 		},
 		func(h http.Handler) http.Handler {
-			return middleware.NewBodySize(h, 32*1024 /* 32kiB */)
+			return middleware.NewBodySizeNotify(h, 32*1024 /* 32kiB */, func(r *http.Request) {
+				appMetrics.guessRejected(REJECT_BODY_TOO_LARGE)
+			})
+		},
+		func(h http.Handler) http.Handler {
+			return middleware.NewCSRF(h, csrfTokens, csrfSessionID, func(r *http.Request) bool {
+				return csrfExempt(r, mux)
+			}, func(r *http.Request, err error) string {
+				return tr(translations.Match(r.Header.Get("Accept-Language")), "error.csrf")
+			})
+		},
+		func(h http.Handler) http.Handler {
+			return middleware.NewRateLimit(h, limits, func(r *http.Request) string {
+				_, route := mux.Handler(r)
+				return route
+			}, func(r *http.Request) []string {
+				return rateLimitKeys(r, envCfg.clientIPHeader)
+			}, func(r *http.Request) string {
+				return tr(translations.Match(r.Header.Get("Accept-Language")), "error.rate_limited")
+			})
+		},
+		func(h http.Handler) http.Handler {
+			return middleware.NewSecurityHeaders(h, securityHeadersConfig(envCfg.cspReportOnly, mux))
+		},
+		func(h http.Handler) http.Handler {
+			return appMetrics.instrument(h, mux)
+		},
+		func(h http.Handler) http.Handler {
+			return accessLog(h, mux)
+		},
+		func(h http.Handler) http.Handler {
+			return traceRequests(h, mux)
 		},
 	}
 
@@ -774,7 +1834,31 @@ func main() {
 		muxWithMiddlewares = fm(muxWithMiddlewares)
 	}
 
-	log.Fatal(http.ListenAndServe(fmt.Sprintf(":%s", envCfg.port), muxWithMiddlewares))
+	ln, err := net.Listen("tcp", fmt.Sprintf(":%s", envCfg.port))
+	if err != nil {
+		fatal("listen failed", "err", err)
+	}
+
+	go func() {
+		start := time.Now()
+		err := wordDb.Init(fs, languages.filePaths())
+		if err != nil {
+			fatal("init wordDatabase failed", "err", err)
+		}
+
+		if wlStore != nil {
+			err = wlStore.Load(wordDb)
+			if err != nil {
+				fatal("loading word list store failed", "err", err)
+			}
+		}
+
+		wordsLoaded.Store(true)
+		slog.Info("word database loaded", "duration_ms", float64(time.Since(start).Microseconds())/1000)
+	}()
+
+	err = http.Serve(ln, muxWithMiddlewares)
+	fatal("server stopped", "err", err)
 }
 
 func envConfig() env {
@@ -783,28 +1867,94 @@ func envConfig() env {
 		panic("PORT not provided")
 	}
 
-	return env{port}
+	adminUser, ok := os.LookupEnv("ADMIN_USER")
+	if !ok {
+		adminUser = "admin"
+	}
+
+	// admin area stays disabled as long as no password is provided
+	adminPassword := os.Getenv("ADMIN_PASSWORD")
+
+	// curated word lists are only kept in memory if no store dir is provided
+	wordListStoreDir := os.Getenv("WORDLIST_STORE_DIR")
+
+	// dir with pre-fetched definition datasets named "<lang>.json", see bin/wordset
+	definitionsDir := os.Getenv("DEFINITIONS_DIR")
+
+	// optional remote lookup, e.g. "https://raw.githubusercontent.com/wordset/wordset-dictionary/master/data/%s.json"
+	definitionsURLFormat := os.Getenv("DEFINITIONS_URL")
+
+	// key for custom puzzle links, without one links only work until the next restart
+	puzzleSecret := os.Getenv("PUZZLE_SECRET")
+
+	// players, groups and daily results are only kept in memory if no store dir is provided
+	leaderboardStoreDir := os.Getenv("LEADERBOARD_STORE_DIR")
+
+	// accounts are only kept in memory if no store dir is provided
+	accountStoreDir := os.Getenv("ACCOUNT_STORE_DIR")
+
+	// sends the magic links for logins, "console" (default) or "file" (writes to MAIL_DIR)
+	mailer := os.Getenv("MAILER")
+	mailDir := os.Getenv("MAIL_DIR")
+	// scheme and host of magic and puzzle links, e.g. "https://lettr.fly.dev", required unless MAILER is "console"
+	publicBaseURL := os.Getenv("PUBLIC_BASE_URL")
+
+	// '/metrics' stays disabled as long as no token is provided, scrapers send it as bearer token
+	metricsToken := os.Getenv("METRICS_TOKEN")
+
+	// "debug", "info" (default), "warn" or "error", solutions and guesses are only logged with "debug"
+	logLevel := os.Getenv("LOG_LEVEL")
+	// "text" (default) or "json"
+	logFormat := os.Getenv("LOG_FORMAT")
+
+	// tracing stays disabled unless set to "otlp" (OTLP/HTTP with json) or "console" (stdout)
+	tracesExporter := os.Getenv("OTEL_TRACES_EXPORTER")
+	// base url of the collector, defaults to "http://localhost:4318", "/v1/traces" gets appended
+	otlpEndpoint := os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")
+	// full url for traces, takes precedence over OTEL_EXPORTER_OTLP_ENDPOINT
+	otlpTracesEndpoint := os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT")
+	// e.g. "Authorization=Bearer xyz,X-Tenant=lettr"
+	otlpHeaders := os.Getenv("OTEL_EXPORTER_OTLP_HEADERS")
+	// defaults to "lettr"
+	otelServiceName := os.Getenv("OTEL_SERVICE_NAME")
+
+	// overrides defaultRateLimits, e.g. "POST /lettr=2:20;POST /new=0.5:5" (tokens per second:burst), "off" disables them
+	rateLimits := os.Getenv("RATE_LIMITS")
+	// header of the proxy with the client ip, e.g. "Fly-Client-IP", only set it if the proxy always overwrites it
+	clientIPHeader := os.Getenv("CLIENT_IP_HEADER")
+
+	// "true" only reports content security policy violations (to '/csp-report') instead of blocking, e.g. to try out policy changes
+	cspReportOnly := os.Getenv("CSP_REPORT_ONLY") == "true"
+
+	return env{port, adminUser, adminPassword, wordListStoreDir, definitionsDir, definitionsURLFormat, puzzleSecret, leaderboardStoreDir, accountStoreDir, mailer, mailDir, publicBaseURL, metricsToken, logLevel, logFormat, tracesExporter, otlpEndpoint, otlpTracesEndpoint, otlpHeaders, otelServiceName, rateLimits, clientIPHeader, cspReportOnly}
 }
 
 func handleSession(w http.ResponseWriter, req *http.Request, sessions *sessions, wdb wordDatabase) session {
+	ctx, span := tracing.Start(req.Context(), "handleSession")
+	defer span.End()
+	req = req.WithContext(ctx)
+
 	var err error
 	var sess session
 
 	cookie, err := req.Cookie(SESSION_COOKIE_NAME)
 	if err != nil {
-		return newSession(w, sessions, wdb)
+		return newSession(w, req, sessions, wdb)
 	}
 
 	if cookie == nil {
-		return newSession(w, sessions, wdb)
+		return newSession(w, req, sessions, wdb)
 	}
 
 	sid := cookie.Value
+	_, lookup := tracing.Start(ctx, "sessions.lookup")
 	i := slices.IndexFunc(*sessions, func(s session) bool {
 		return s.id == sid
 	})
+	lookup.SetAttributes(tracing.Bool("found", i != -1))
+	lookup.End()
 	if i == -1 {
-		return newSession(w, sessions, wdb)
+		return newSession(w, req, sessions, wdb)
 	}
 
 	sess = (*sessions)[i]
@@ -813,14 +1963,21 @@ func handleSession(w http.ResponseWriter, req *http.Request, sessions *sessions,
 	http.SetCookie(w, &c)
 
 	sess.expiresAt = generateSessionLifetime()
+	sess.checkClock(time.Now())
 	(*sessions)[i] = sess
+	appMetrics.sessionSeen(sess.id, sess.expiresAt)
 
 	return sess
 }
 
-func newSession(w http.ResponseWriter, sessions *sessions, wdb wordDatabase) session {
+func newSession(w http.ResponseWriter, req *http.Request, sessions *sessions, wdb wordDatabase) session {
+	_, span := tracing.Start(req.Context(), "sessions.create")
+	defer span.End()
+
 	sess := generateSession(LANG_EN, wdb)
+	sess.uiLocale = translations.Match(req.Header.Get("Accept-Language"))
 	*sessions = append(*sessions, sess)
+	appMetrics.sessionSeen(sess.id, sess.expiresAt)
 	c := constructCookie(sess)
 	http.SetCookie(w, &c)
 
@@ -844,12 +2001,21 @@ func generateSession(lang language, wdb wordDatabase) session { //todo: pass it
 	expiresAt := generateSessionLifetime()
 	activeWord, err := wdb.RandomPick(lang, []word{}, 0)
 	if err != nil {
-		log.Printf("pick random word failed: %s", err)
+		slog.Error("pick random word failed", "lang", lang, "err", err)
 
 		activeWord = word{'R', 'O', 'A', 'T', 'E'}.ToLower()
 	}
 
-	return session{id, expiresAt, SESSION_MAX_AGE_IN_SECONDS, lang, activeWord, puzzle{}, []word{}}
+	return session{
+		id:                 id,
+		expiresAt:          expiresAt,
+		maxAgeSeconds:      SESSION_MAX_AGE_IN_SECONDS,
+		language:           lang,
+		activeSolutionWord: activeWord,
+		pastWords:          []word{},
+		gameStartedAt:      time.Now(),
+		uiLocale:           translations.DefaultLocale(),
+	}
 }
 
 func generateSessionLifetime() time.Time {
@@ -874,23 +2040,34 @@ func countFilledFormRows(postPuzzleForm url.Values) uint8 {
 	return count
 }
 
-func parseForm(p puzzle, form url.Values, solutionWord word, l language, wdb wordDatabase) (puzzle, error) {
+func parseForm(ctx context.Context, p puzzle, form url.Values, solutionWord word, l language, wdb wordDatabase) (puzzle, error) {
+	ctx, span := tracing.Start(ctx, "parseForm", tracing.String("language", string(l)))
+	defer span.End()
+
+	n := languages.config(l).normaliser()
+
 	for ri := range p.Guesses {
 		maybeGuessedWord, ok := form[fmt.Sprintf("r%d", ri)]
 		if !ok {
 			continue
 		}
 
-		guessedWord, err := sliceToWord(maybeGuessedWord)
+		guessedWord, err := sliceToWord(maybeGuessedWord, n)
 		if err != nil {
 			return p, fmt.Errorf("parseForm could not create guessedWord from form input: %s", err.Error())
 		}
 
-		if !wdb.Exists(l, guessedWord) {
+		_, lookup := tracing.Start(ctx, "wordDatabase.Exists", tracing.String("language", string(l)))
+		exists := wdb.Exists(l, guessedWord)
+		lookup.SetAttributes(tracing.Bool("exists", exists))
+		lookup.End()
+		if !exists {
 			return p, ErrNotInWordList
 		}
 
-		wg := evaluateGuessedWord(guessedWord, solutionWord)
+		_, eval := tracing.Start(ctx, "evaluateGuessedWord", tracing.Int("row", ri))
+		wg := evaluateGuessedWord(n, guessedWord, solutionWord)
+		eval.End()
 
 		p.Guesses[ri] = wg
 	}
@@ -898,7 +2075,9 @@ func parseForm(p puzzle, form url.Values, solutionWord word, l language, wdb wor
 	return p, nil
 }
 
-func sliceToWord(maybeGuessedWord []string) (word, error) {
+// sliceToWord takes the first letter of every input cell and normalises the
+// result with n, like wordDatabase.Exists does.
+func sliceToWord(maybeGuessedWord []string, n normaliser) (word, error) {
 	w := word{}
 
 	if len(maybeGuessedWord) != len(w) {
@@ -906,17 +2085,18 @@ func sliceToWord(maybeGuessedWord []string) (word, error) {
 	}
 
 	for i, l := range maybeGuessedWord {
-		w[i], _ = utf8.DecodeRuneInString(strings.ToLower(l))
+		w[i], _ = utf8.DecodeRuneInString(l)
 		if w[i] == 65533 {
 			w[i] = 0
 		}
 	}
 
-	return w, nil
+	return n.Guess(w), nil
 }
 
-func evaluateGuessedWord(guessedWord word, solutionWord word) wordGuess {
-	solutionWord = solutionWord.ToLower()
+func evaluateGuessedWord(n normaliser, guessedWord word, solutionWord word) wordGuess {
+	guessedWord = n.Guess(guessedWord)
+	solutionWord = n.Guess(solutionWord)
 	guessedLetterCountMap := make(map[rune]int)
 
 	resultWordGuess := wordGuess{}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
@@ -11,6 +12,8 @@ import (
 
 	"github.com/agiledragon/gomonkey/v2"
 	"github.com/google/uuid"
+
+	"github.com/pandorasNox/lettr/pkg/wordlist"
 )
 
 func Test_constructCookie(t *testing.T) {
@@ -28,7 +31,7 @@ func Test_constructCookie(t *testing.T) {
 		// add test cases here
 		{
 			"test_name",
-			args{session{fixedUuid, expireDate, SESSION_MAX_AGE_IN_SECONDS, LANG_EN, word{}, puzzle{}, []word{}}},
+			args{session{id: fixedUuid, expiresAt: expireDate, maxAgeSeconds: SESSION_MAX_AGE_IN_SECONDS, language: LANG_EN, pastWords: []word{}}},
 			http.Cookie{
 				Name:     SESSION_COOKIE_NAME,
 				Value:    fixedUuid,
@@ -94,6 +97,37 @@ func Test_handleSession(t *testing.T) {
 				language:           LANG_EN,
 				activeSolutionWord: word{'R', 'O', 'A', 'T', 'E'},
 				pastWords:          []word{},
+				gameStartedAt:      time.Unix(1615256178, 0),
+				uiLocale:           "en",
+			},
+		},
+		{
+			"test handleSession picks the ui locale of a new session from Accept-Language",
+			args{
+				httptest.NewRecorder(),
+				func() *http.Request {
+					req := httptest.NewRequest("get", "/", nil)
+					req.Header.Set("Accept-Language", "de-DE,de;q=0.9,en;q=0.8")
+					return req
+				}(),
+				&sessions{},
+				wordDatabase{db: map[language]map[wordCollection]map[word]bool{
+					LANG_EN: {
+						WC_COMMON: {
+							word{'R', 'O', 'A', 'T', 'E'}: true,
+						},
+					},
+				}},
+			},
+			session{
+				id:                 "12345678-abcd-1234-abcd-ab1234567890",
+				expiresAt:          time.Unix(1615256178, 0).Add(SESSION_MAX_AGE_IN_SECONDS * time.Second),
+				maxAgeSeconds:      86400,
+				language:           LANG_EN,
+				activeSolutionWord: word{'R', 'O', 'A', 'T', 'E'},
+				pastWords:          []word{},
+				gameStartedAt:      time.Unix(1615256178, 0),
+				uiLocale:           "de",
 			},
 		},
 		// {
@@ -207,7 +241,7 @@ func Test_parseForm(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got, err := parseForm(tt.args.p, tt.args.form, tt.args.solutionWord, tt.args.language, tt.args.wdb); !reflect.DeepEqual(got, tt.want) || (err != nil) != tt.wantErr {
+			if got, err := parseForm(context.Background(), tt.args.p, tt.args.form, tt.args.solutionWord, tt.args.language, tt.args.wdb); !reflect.DeepEqual(got, tt.want) || (err != nil) != tt.wantErr {
 				t.Errorf("parseForm() = %v, %v; want %v, %v", got, err != nil, tt.want, tt.wantErr)
 			}
 		})
@@ -329,7 +363,7 @@ func Test_evaluateGuessedWord(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := evaluateGuessedWord(tt.args.guessedWord, tt.args.solutionWord); !reflect.DeepEqual(got, tt.want) {
+			if got := evaluateGuessedWord(normaliser{}, tt.args.guessedWord, tt.args.solutionWord); !reflect.DeepEqual(got, tt.want) {
 				t.Errorf("evaluateGuessedWord() = %v, want %v", got, tt.want)
 			}
 		})
@@ -378,3 +412,124 @@ func Test_sessions_updateOrSet(t *testing.T) {
 // todo: test for ???:
 //   files, err := getAllFilenames(staticFS)
 //   log.Printf("  debug fsys:\n    %v\n    %s\n", files, err)
+
+func Test_keyboardLayouts_complete(t *testing.T) {
+	for name, layout := range keyboardLayouts {
+		keys := map[string]bool{}
+		for _, row := range layout {
+			for _, k := range row {
+				if keys[k] {
+					t.Errorf("layout '%s' has duplicate key '%s'", name, k)
+				}
+				keys[k] = true
+			}
+		}
+
+		for _, k := range append(strings.Split("ABCDEFGHIJKLMNOPQRSTUVWXYZ", ""), "Enter", "Delete") {
+			if !keys[k] {
+				t.Errorf("layout '%s' misses key '%s'", name, k)
+			}
+		}
+	}
+}
+
+func Test_keyboard_Init(t *testing.T) {
+	lgs := []letterGuess{{'z', MatchExact}, {'y', MatchVague}, {'ä', MatchNone}, {'a', MatchVague}, {'a', MatchExact}}
+
+	keyState := func(kb keyboard, key string) keyboardKey {
+		for _, row := range kb.KeyGrid {
+			for _, k := range row {
+				if k.Key == key {
+					return k
+				}
+			}
+		}
+		t.Fatalf("key '%s' not found in layout '%s'", key, kb.Layout)
+		return keyboardKey{}
+	}
+
+	kb := keyboard{}
+	kb.Init("qwertz", wordlist.UMLAUTS_KEEP, lgs)
+
+	if kb.KeyGrid[0][5].Key != "Z" {
+		t.Errorf("qwertz expects 'Z' as sixth key, got '%s'", kb.KeyGrid[0][5].Key)
+	}
+	if got := keyState(kb, "Z"); got != (keyboardKey{"Z", true, MatchExact}) {
+		t.Errorf("key Z = %v", got)
+	}
+	if got := keyState(kb, "Y"); got != (keyboardKey{"Y", true, MatchVague}) {
+		t.Errorf("key Y = %v", got)
+	}
+	if got := keyState(kb, "Ä"); got != (keyboardKey{"Ä", true, MatchNone}) {
+		t.Errorf("key Ä = %v", got)
+	}
+	if got := keyState(kb, "A"); got != (keyboardKey{"A", true, MatchExact}) {
+		t.Errorf("key A should keep the better match, got %v", got)
+	}
+	if got := keyState(kb, "Enter"); got.IsUsed {
+		t.Errorf("key Enter should never be coloured, got %v", got)
+	}
+
+	kb = keyboard{}
+	kb.Init("qwertz", wordlist.UMLAUTS_TRANSLITERATE, lgs)
+	for _, row := range kb.KeyGrid {
+		for _, k := range row {
+			if k.Key == "Ä" || k.Key == "Ö" || k.Key == "Ü" {
+				t.Errorf("umlaut key '%s' shown although umlauts are transliterated", k.Key)
+			}
+		}
+	}
+
+	kb = keyboard{}
+	kb.Init("unknown", wordlist.UMLAUTS_KEEP, lgs)
+	if kb.Layout != "qwerty" {
+		t.Errorf("unknown layout should fall back to qwerty, got '%s'", kb.Layout)
+	}
+}
+
+func Test_session_switchLanguage(t *testing.T) {
+	wdb := wordDatabase{}
+	wdb.Init(nil, nil)
+	wdb.Replace(LANG_DE, WC_COMMON, []word{{'t', 'i', 's', 'c', 'h'}})
+
+	enWord := word{'m', 'a', 't', 'c', 'h'}
+	enAttempt := puzzle{Guesses: [6]wordGuess{evaluateGuessedWord(normaliser{}, word{'r', 'o', 'a', 't', 'e'}, enWord)}}
+
+	s := session{
+		language:             LANG_EN,
+		activeSolutionWord:   enWord,
+		lastEvaluatedAttempt: enAttempt,
+		pastWords:            []word{{'r', 'o', 'a', 't', 'e'}},
+	}
+	before := s
+
+	s.switchLanguage(LANG_DE, wdb)
+	if s.language != LANG_DE || s.activeSolutionWord != (word{'t', 'i', 's', 'c', 'h'}) {
+		t.Fatalf("switchLanguage() should start a new german game, got lang=%s, word=%s", s.language, s.activeSolutionWord)
+	}
+	if s.lastEvaluatedAttempt.activeRow() != 0 || len(s.pastWords) != 0 {
+		t.Errorf("switchLanguage() new game should be fresh, got %v, %v", s.lastEvaluatedAttempt, s.pastWords)
+	}
+	if before.parkedGames != nil {
+		t.Errorf("switchLanguage() must not modify copies of the session")
+	}
+
+	deAttempt := puzzle{Guesses: [6]wordGuess{evaluateGuessedWord(normaliser{}, word{'t', 'a', 's', 'c', 'h'}, s.activeSolutionWord)}}
+	s.lastEvaluatedAttempt = deAttempt
+
+	s.switchLanguage(LANG_EN, wdb)
+	if s.language != LANG_EN || s.activeSolutionWord != enWord || s.lastEvaluatedAttempt != enAttempt {
+		t.Errorf("switchLanguage() should resume the english game, got lang=%s, word=%s, attempt=%v", s.language, s.activeSolutionWord, s.lastEvaluatedAttempt)
+	}
+	if !reflect.DeepEqual(s.pastWords, before.pastWords) {
+		t.Errorf("switchLanguage() should restore the english past words, got %v", s.pastWords)
+	}
+
+	s.switchLanguage(LANG_DE, wdb)
+	if s.lastEvaluatedAttempt != deAttempt {
+		t.Errorf("switchLanguage() should resume the german game, got %v", s.lastEvaluatedAttempt)
+	}
+	if _, ok := s.parkedGames[LANG_DE]; ok || len(s.parkedGames) != 1 {
+		t.Errorf("switchLanguage() should only park the inactive language, got %v", s.parkedGames)
+	}
+}
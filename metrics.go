@@ -0,0 +1,181 @@
+package main
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pandorasNox/lettr/pkg/metrics"
+)
+
+type rejectReason string
+
+const (
+	REJECT_NOT_IN_WORD_LIST rejectReason = "not_in_word_list"
+	REJECT_FAKED_ROWS       rejectReason = "faked_rows"
+	REJECT_INVALID_GUESS    rejectReason = "invalid_guess"
+	REJECT_BODY_TOO_LARGE   rejectReason = "body_too_large" // see middleware.BodySize
+)
+
+// appMetrics are collected all the time, they are only exposed via /metrics
+// if METRICS_TOKEN is set.
+var appMetrics = newLettrMetrics()
+
+type lettrMetrics struct {
+	registry        *metrics.Registry
+	requests        *metrics.CounterVec
+	requestDuration *metrics.HistogramVec
+	gamesStarted    *metrics.CounterVec
+	gamesFinished   *metrics.CounterVec
+	guessesRejected *metrics.CounterVec
+
+	// sessions aren't safe to read while scraping, handlers report their expiry instead
+	sessionsMu      sync.Mutex
+	sessionExpiries map[string]time.Time // session id -> expiry
+}
+
+func newLettrMetrics() *lettrMetrics {
+	r := metrics.NewRegistry()
+
+	return &lettrMetrics{
+		registry:        r,
+		requests:        r.NewCounterVec("lettr_http_requests_total", "HTTP requests by route pattern, method and status code.", "route", "method", "code"),
+		requestDuration: r.NewHistogramVec("lettr_http_request_duration_seconds", "HTTP request latency by route pattern and method.", metrics.DefBuckets, "route", "method"),
+		gamesStarted:    r.NewCounterVec("lettr_games_started_total", "Games started with a first guess by puzzle language.", "language"),
+		gamesFinished:   r.NewCounterVec("lettr_games_finished_total", "Games finished by puzzle language and outcome.", "language", "outcome"),
+		guessesRejected: r.NewCounterVec("lettr_guesses_rejected_total", "Guesses rejected by reason.", "reason"),
+		sessionExpiries: make(map[string]time.Time),
+	}
+}
+
+func (m *lettrMetrics) gameStarted(l language) {
+	m.gamesStarted.Inc(string(l))
+}
+
+func (m *lettrMetrics) gameFinished(l language, o gameOutcome) {
+	m.gamesFinished.Inc(string(l), string(o))
+}
+
+func (m *lettrMetrics) guessRejected(reason rejectReason) {
+	m.guessesRejected.Inc(string(reason))
+}
+
+// sessionSeen records the current expiry of a session.
+func (m *lettrMetrics) sessionSeen(id string, expiresAt time.Time) {
+	m.sessionsMu.Lock()
+	defer m.sessionsMu.Unlock()
+
+	m.sessionExpiries[id] = expiresAt
+}
+
+// observeSessions reports the sessions which didn't expire yet, see sessionSeen.
+func (m *lettrMetrics) observeSessions() {
+	m.registry.NewGaugeFunc("lettr_sessions_active", "Sessions which didn't expire yet.", nil, func() []metrics.Sample {
+		m.sessionsMu.Lock()
+		defer m.sessionsMu.Unlock()
+
+		now := time.Now()
+		for id, expiresAt := range m.sessionExpiries {
+			if !expiresAt.After(now) {
+				delete(m.sessionExpiries, id)
+			}
+		}
+
+		return []metrics.Sample{{Value: float64(len(m.sessionExpiries))}}
+	})
+}
+
+func (m *lettrMetrics) observeWordDatabase(wdb wordDatabase) {
+	m.registry.NewGaugeFunc("lettr_words", "Words in the word database by language and collection.", []string{"language", "collection"}, func() []metrics.Sample {
+		samples := []metrics.Sample{}
+		for l, collections := range wdb.Sizes() {
+			for c, size := range collections {
+				samples = append(samples, metrics.Sample{LabelValues: []string{string(l), string(c)}, Value: float64(size)})
+			}
+		}
+
+		return samples
+	})
+}
+
+// statusRecorder remembers the status code and counts the written bytes. It
+// passes Flush through, server sent events of the rooms rely on it.
+type statusRecorder struct {
+	http.ResponseWriter
+	code  int
+	bytes int
+}
+
+func (sr *statusRecorder) status() int {
+	if sr.code == 0 {
+		return http.StatusOK
+	}
+
+	return sr.code
+}
+
+func (sr *statusRecorder) WriteHeader(code int) {
+	if sr.code == 0 {
+		sr.code = code
+	}
+	sr.ResponseWriter.WriteHeader(code)
+}
+
+func (sr *statusRecorder) Write(b []byte) (int, error) {
+	if sr.code == 0 {
+		sr.code = http.StatusOK
+	}
+
+	n, err := sr.ResponseWriter.Write(b)
+	sr.bytes += n
+	return n, err
+}
+
+func (sr *statusRecorder) Flush() {
+	if f, ok := sr.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (sr *statusRecorder) Unwrap() http.ResponseWriter {
+	return sr.ResponseWriter
+}
+
+// instrument counts requests and their latency by the pattern of the mux
+// route, so path values like room codes don't blow up the label values.
+func (m *lettrMetrics) instrument(h http.Handler, mux *http.ServeMux) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := "unmatched"
+		if _, pattern := mux.Handler(r); pattern != "" {
+			// patterns carry the method already, e.g. "POST /lettr"
+			_, route, _ = strings.Cut(pattern, " ")
+			if route == "" {
+				route = pattern
+			}
+		}
+
+		start := time.Now()
+		sr := &statusRecorder{ResponseWriter: w}
+		h.ServeHTTP(sr, r)
+
+		m.requests.Inc(route, r.Method, strconv.Itoa(sr.status()))
+		m.requestDuration.Observe(time.Since(start).Seconds(), route, r.Method)
+	})
+}
+
+// metricsAuth only lets scrapers with the bearer token pass.
+func metricsAuth(token string, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		given, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="lettr metrics"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		h.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_lettrMetrics_instrument(t *testing.T) {
+	m := newLettrMetrics()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /rooms/{code}", func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := w.(http.Flusher); !ok {
+			t.Errorf("instrument() must keep the response writer flushable")
+		}
+	})
+	mux.HandleFunc("POST /lettr", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(422)
+	})
+	h := m.instrument(mux, mux)
+
+	for _, target := range []string{"/rooms/ABCDE", "/rooms/FGHIJ"} {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, target, nil))
+	}
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/lettr", nil))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/nope", nil))
+
+	tests := []struct {
+		route  string
+		method string
+		code   string
+		want   float64
+	}{
+		{"/rooms/{code}", http.MethodGet, "200", 2},
+		{"/lettr", http.MethodPost, "422", 1},
+		{"unmatched", http.MethodGet, "404", 1},
+	}
+	for _, tt := range tests {
+		if got := m.requests.Value(tt.route, tt.method, tt.code); got != tt.want {
+			t.Errorf("requests(%s, %s, %s) = %v, want %v", tt.route, tt.method, tt.code, got, tt.want)
+		}
+	}
+
+	if got := m.requestDuration.Count("/rooms/{code}", http.MethodGet); got != 2 {
+		t.Errorf("requestDuration count = %d, want 2", got)
+	}
+}
+
+func Test_metricsAuth(t *testing.T) {
+	h := metricsAuth("secret", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	tests := []struct {
+		name          string
+		authorization string
+		want          int
+	}{
+		{"no token", "", http.StatusUnauthorized},
+		{"wrong token", "Bearer nope", http.StatusUnauthorized},
+		{"basic auth", "Basic c2VjcmV0", http.StatusUnauthorized},
+		{"token", "Bearer secret", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+			if tt.authorization != "" {
+				r.Header.Set("Authorization", tt.authorization)
+			}
+
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, r)
+			if rec.Code != tt.want {
+				t.Errorf("metricsAuth() = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
+
+func Test_lettrMetrics_observeSessions(t *testing.T) {
+	m := newLettrMetrics()
+	m.observeSessions()
+
+	m.sessionSeen("expired", time.Now().Add(-time.Second))
+	m.sessionSeen("active", time.Now().Add(time.Hour))
+	m.sessionSeen("renewed", time.Now().Add(-time.Second))
+	m.sessionSeen("renewed", time.Now().Add(time.Hour))
+
+	w := httptest.NewRecorder()
+	m.registry.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	if !strings.Contains(w.Body.String(), "lettr_sessions_active 2\n") {
+		t.Errorf("observeSessions() = %q, want 2 active sessions", w.Body.String())
+	}
+}
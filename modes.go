@@ -0,0 +1,320 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"maps"
+	"time"
+)
+
+type gameMode string
+
+const (
+	MODE_CLASSIC  gameMode = "classic"
+	MODE_TIMED    gameMode = "timed"    // countdown per puzzle
+	MODE_SPEEDRUN gameMode = "speedrun" // as many puzzles as possible until the run ends
+	MODE_MULTI    gameMode = "multi"    // one guess is played on several boards at once
+)
+
+var gameModes = []gameMode{MODE_CLASSIC, MODE_TIMED, MODE_SPEEDRUN, MODE_MULTI}
+
+func NewGameMode(maybeMode string) (gameMode, error) {
+	switch gameMode(maybeMode) {
+	case MODE_CLASSIC, MODE_TIMED, MODE_SPEEDRUN, MODE_MULTI:
+		return gameMode(maybeMode), nil
+	default:
+		return MODE_CLASSIC, fmt.Errorf("couldn't create new game mode from given value: '%s'", maybeMode)
+	}
+}
+
+// TIMED_PUZZLE_DURATION is the countdown of a single puzzle in MODE_TIMED.
+const TIMED_PUZZLE_DURATION = 3 * time.Minute
+
+// speedrunMinutes are the run lengths players can pick from.
+var speedrunMinutes = []int{3, 5, 10}
+
+// speedrun is the state of the current (or last) run of MODE_SPEEDRUN.
+// All timing is taken from the server clock, the client only displays it.
+type speedrun struct {
+	Minutes   int
+	StartedAt time.Time
+	EndsAt    time.Time
+	Solved    int
+	Played    int // finished puzzles, solved or lost
+	IsOver    bool
+}
+
+func newSpeedrun(minutes int, now time.Time) speedrun {
+	return speedrun{
+		Minutes:   minutes,
+		StartedAt: now,
+		EndsAt:    now.Add(time.Duration(minutes) * time.Minute),
+	}
+}
+
+// statistics keeps the results of the timed modes per session.
+type statistics struct {
+	TimedPlayed  int
+	TimedSolved  int
+	TimedFastest time.Duration // zero until the first timed puzzle is solved
+	SpeedrunRuns int
+	SpeedrunBest map[int]int // most solved puzzles by run length in minutes
+}
+
+// BestSpeedrun returns the most puzzles solved within a run of the given length.
+func (st statistics) BestSpeedrun(minutes int) int {
+	return st.SpeedrunBest[minutes]
+}
+
+// countdown is what the client displays, it is rendered from the server clock
+// on every response.
+type countdown struct {
+	Deadline  time.Time
+	Remaining time.Duration
+}
+
+func newCountdown(deadline time.Time, now time.Time) countdown {
+	if deadline.IsZero() {
+		return countdown{}
+	}
+
+	return countdown{Deadline: deadline, Remaining: max(0, deadline.Sub(now)).Round(time.Second)}
+}
+
+func (c countdown) IsSet() bool {
+	return !c.Deadline.IsZero()
+}
+
+func (c countdown) String() string {
+	secs := int(c.Remaining.Seconds())
+	return fmt.Sprintf("%d:%02d", secs/60, secs%60)
+}
+
+// Mode returns the game mode of the session, classic if none was chosen.
+func (s session) Mode() gameMode {
+	if s.mode == "" {
+		return MODE_CLASSIC
+	}
+
+	return s.mode
+}
+
+// deadline returns when the active puzzle runs out of time, zero for untimed games.
+func (s session) deadline() time.Time {
+	switch s.Mode() {
+	case MODE_TIMED:
+		return s.gameStartedAt.Add(TIMED_PUZZLE_DURATION)
+	case MODE_SPEEDRUN:
+		return s.speedrun.EndsAt
+	default:
+		return time.Time{}
+	}
+}
+
+func (s session) isSolved() bool {
+	if s.Mode() == MODE_MULTI {
+		return s.boards.isSolved()
+	}
+
+	return s.lastEvaluatedAttempt.isSolved()
+}
+
+func (s session) isLoose() bool {
+	if s.Mode() == MODE_MULTI {
+		return s.boards.isLoose()
+	}
+
+	return s.lastEvaluatedAttempt.isLoose()
+}
+
+func (s session) gameOver() bool {
+	return s.isSolved() || s.isLoose() || s.timedOut
+}
+
+// rounds counts the guesses made in the active game.
+func (s session) rounds() int {
+	if s.Mode() == MODE_MULTI {
+		return s.boards.Rounds()
+	}
+
+	return int(s.lastEvaluatedAttempt.activeRow())
+}
+
+// boardCount returns the number of boards played in MODE_MULTI.
+func (s session) boardCount() int {
+	if s.multiBoards == 0 {
+		return boardCounts[0]
+	}
+
+	return s.multiBoards
+}
+
+// checkClock ends the active puzzle (and a speedrun) once its deadline passed.
+func (s *session) checkClock(now time.Time) {
+	d := s.deadline()
+	if d.IsZero() || now.Before(d) || s.gameOver() {
+		return
+	}
+
+	s.timedOut = true
+	s.recordGame(OUTCOME_TIMEOUT)
+
+	switch s.Mode() {
+	case MODE_TIMED:
+		s.stats.TimedPlayed++
+	case MODE_SPEEDRUN:
+		s.finishSpeedrun()
+	}
+}
+
+func (s *session) finishSpeedrun() {
+	s.speedrun.IsOver = true
+	s.stats.SpeedrunRuns++
+
+	if s.speedrun.Solved > s.stats.BestSpeedrun(s.speedrun.Minutes) {
+		best := maps.Clone(s.stats.SpeedrunBest)
+		if best == nil {
+			best = make(map[int]int)
+		}
+		best[s.speedrun.Minutes] = s.speedrun.Solved
+		s.stats.SpeedrunBest = best
+	}
+}
+
+// finishPuzzle records a solved or lost puzzle. A speedrun continues with the
+// next puzzle right away.
+func (s *session) finishPuzzle(o gameOutcome, now time.Time, wdb wordDatabase) {
+	s.recordGame(o)
+
+	switch s.Mode() {
+	case MODE_TIMED:
+		s.stats.TimedPlayed++
+		if o == OUTCOME_SOLVED {
+			s.stats.TimedSolved++
+
+			took := now.Sub(s.gameStartedAt).Round(time.Second)
+			if s.stats.TimedFastest == 0 || took < s.stats.TimedFastest {
+				s.stats.TimedFastest = took
+			}
+		}
+	case MODE_SPEEDRUN:
+		s.speedrun.Played++
+		if o == OUTCOME_SOLVED {
+			s.speedrun.Solved++
+		}
+		s.newGame(wdb)
+	}
+}
+
+// retireGame records a running game as abandoned and moves its solutions
+// to the past words, custom puzzles are kept out of them. An abandoned timed
+// or speedrun puzzle counts as played and not solved, its clock was running.
+func (s *session) retireGame() {
+	if !s.gameOver() && !s.gameStartedAt.IsZero() {
+		switch s.Mode() {
+		case MODE_TIMED:
+			s.stats.TimedPlayed++
+		case MODE_SPEEDRUN:
+			if !s.speedrun.IsOver {
+				s.speedrun.Played++
+			}
+		}
+	}
+
+	if !s.gameOver() && s.rounds() > 0 {
+		s.recordGame(OUTCOME_ABANDONED)
+	}
+
+	switch {
+	case s.customPuzzleID != "":
+	case len(s.boards) > 0:
+		for _, w := range s.boards.solutions() {
+			s.AddPastWord(w)
+		}
+	default:
+		s.AddPastWord(s.activeSolutionWord)
+	}
+}
+
+// newGame starts the next puzzle, a running one is recorded as abandoned.
+// A finished speedrun gets restarted with the same length.
+func (s *session) newGame(wdb wordDatabase) {
+	s.retireGame()
+	s.nextGame(wdb)
+}
+
+// nextGame starts the next puzzle without retiring the running one.
+func (s *session) nextGame(wdb wordDatabase) {
+	if s.Mode() == MODE_SPEEDRUN && s.speedrun.IsOver {
+		s.speedrun = newSpeedrun(s.speedrun.Minutes, time.Now())
+	}
+
+	s.boards = nil
+	if s.Mode() == MODE_MULTI {
+		s.startBoards(wdb)
+		return
+	}
+
+	s.startGame(wdb.RandomPickWithFallback(s.language, s.pastWords, 0))
+}
+
+// startBoards starts a new MODE_MULTI game, the first board doubles as the
+// active solution word. Once the played words leave too few, they may repeat,
+// without enough words at all it's a MODE_CLASSIC game instead.
+func (s *session) startBoards(wdb wordDatabase) {
+	bs, err := newBoards(s.boardCount(), s.language, s.pastWords, wdb)
+	if err != nil {
+		bs, err = newBoards(s.boardCount(), s.language, []word{}, wdb)
+	}
+	if err != nil {
+		slog.Error("starting boards failed, falling back to classic mode", "err", err)
+		s.mode = MODE_CLASSIC
+		s.startGame(wdb.RandomPickWithFallback(s.language, s.pastWords, 0))
+		return
+	}
+
+	s.startGame(bs[0].Solution)
+	s.boards = bs
+}
+
+// setMode switches the game mode and starts a new puzzle, for MODE_SPEEDRUN
+// a new run of the given length, for MODE_MULTI with the given number of boards.
+func (s *session) setMode(m gameMode, minutes int, boardCount int, now time.Time, wdb wordDatabase) {
+	// in the mode it was played in
+	s.retireGame()
+
+	s.mode = m
+	s.speedrun = speedrun{}
+	if m == MODE_SPEEDRUN {
+		s.speedrun = newSpeedrun(minutes, now)
+	}
+	if m == MODE_MULTI {
+		s.multiBoards = boardCount
+	}
+
+	s.nextGame(wdb)
+}
+
+type modeData struct {
+	Mode            gameMode
+	Modes           []gameMode
+	SpeedrunMinutes []int
+	Speedrun        speedrun
+	BoardCounts     []int
+	BoardCount      int
+	Countdown       countdown
+	Stats           statistics
+}
+
+func newModeData(s session, now time.Time) modeData {
+	return modeData{
+		Mode:            s.Mode(),
+		Modes:           gameModes,
+		SpeedrunMinutes: speedrunMinutes,
+		Speedrun:        s.speedrun,
+		BoardCounts:     boardCounts,
+		BoardCount:      s.boardCount(),
+		Countdown:       newCountdown(s.deadline(), now),
+		Stats:           s.stats,
+	}
+}
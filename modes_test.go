@@ -0,0 +1,146 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func Test_session_checkClock(t *testing.T) {
+	start := time.Unix(1615256178, 0)
+
+	tests := []struct {
+		name         string
+		s            session
+		now          time.Time
+		wantTimedOut bool
+		wantPlayed   int
+	}{
+		{"classic never times out", session{gameStartedAt: start}, start.Add(24 * time.Hour), false, 0},
+		{"timed within the countdown", session{mode: MODE_TIMED, gameStartedAt: start}, start.Add(TIMED_PUZZLE_DURATION - time.Second), false, 0},
+		{"timed after the countdown", session{mode: MODE_TIMED, gameStartedAt: start}, start.Add(TIMED_PUZZLE_DURATION), true, 1},
+		{
+			"timed but already solved",
+			session{mode: MODE_TIMED, gameStartedAt: start, lastEvaluatedAttempt: puzzle{Guesses: [6]wordGuess{evaluateGuessedWord(normaliser{}, word{'m', 'a', 't', 'c', 'h'}, word{'m', 'a', 't', 'c', 'h'})}}},
+			start.Add(TIMED_PUZZLE_DURATION),
+			false,
+			0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.s.checkClock(tt.now)
+			if tt.s.timedOut != tt.wantTimedOut {
+				t.Errorf("checkClock() timedOut = %v, want %v", tt.s.timedOut, tt.wantTimedOut)
+			}
+			if tt.s.stats.TimedPlayed != tt.wantPlayed {
+				t.Errorf("checkClock() TimedPlayed = %d, want %d", tt.s.stats.TimedPlayed, tt.wantPlayed)
+			}
+			if tt.wantTimedOut && (len(tt.s.history) != 1 || tt.s.history[0].Outcome != OUTCOME_TIMEOUT) {
+				t.Errorf("checkClock() should record the puzzle as timed out, got %v", tt.s.history)
+			}
+		})
+	}
+}
+
+func Test_session_speedrun(t *testing.T) {
+	wdb := wordDatabase{}
+	wdb.Init(nil, nil)
+	wdb.Replace(LANG_EN, WC_ALL, []word{{'m', 'a', 't', 'c', 'h'}, {'h', 'o', 'u', 's', 'e'}, {'g', 'a', 'm', 'e', 's'}})
+
+	start := time.Now()
+	s := session{language: LANG_EN}
+	s.setMode(MODE_SPEEDRUN, 3, 0, start, wdb)
+
+	solve := func() {
+		s.lastEvaluatedAttempt.Guesses[0] = evaluateGuessedWord(normaliser{}, s.activeSolutionWord, s.activeSolutionWord)
+		s.finishPuzzle(OUTCOME_SOLVED, start.Add(time.Minute), wdb)
+	}
+	solve()
+	solve()
+
+	if s.speedrun.Solved != 2 || s.gameOver() {
+		t.Fatalf("finishPuzzle() should continue the run with a new puzzle, got %+v, gameOver=%v", s.speedrun, s.gameOver())
+	}
+
+	copied := s
+	s.checkClock(start.Add(3 * time.Minute))
+
+	if !s.speedrun.IsOver || !s.timedOut {
+		t.Errorf("checkClock() should end the run, got %+v", s.speedrun)
+	}
+	if s.stats.SpeedrunRuns != 1 || s.stats.BestSpeedrun(3) != 2 || s.stats.BestSpeedrun(5) != 0 {
+		t.Errorf("checkClock() statistics = %+v", s.stats)
+	}
+	if copied.stats.BestSpeedrun(3) != 0 {
+		t.Errorf("finishSpeedrun() must not modify copies of the session")
+	}
+
+	s.newGame(wdb)
+	if s.speedrun.IsOver || s.speedrun.Solved != 0 || s.speedrun.Minutes != 3 || s.gameOver() {
+		t.Errorf("newGame() should restart a finished run, got %+v", s.speedrun)
+	}
+}
+
+func Test_session_retireGame_countsAbandonedClocks(t *testing.T) {
+	wdb := wordDatabase{}
+	wdb.Init(nil, nil)
+	wdb.Replace(LANG_EN, WC_ALL, []word{{'m', 'a', 't', 'c', 'h'}, {'h', 'o', 'u', 's', 'e'}, {'g', 'a', 'm', 'e', 's'}})
+
+	s := session{language: LANG_EN}
+	s.setMode(MODE_TIMED, 0, 0, time.Now(), wdb)
+	s.newGame(wdb)
+	if s.stats.TimedPlayed != 1 || s.stats.TimedSolved != 0 {
+		t.Errorf("newGame() should count the abandoned timed puzzle as played, got %+v", s.stats)
+	}
+
+	s.setMode(MODE_CLASSIC, 0, 0, time.Now(), wdb)
+	if s.stats.TimedPlayed != 2 {
+		t.Errorf("setMode() should count the abandoned timed puzzle as played, got %+v", s.stats)
+	}
+
+	s.setMode(MODE_SPEEDRUN, 3, 0, time.Now(), wdb)
+	s.newGame(wdb)
+	if s.speedrun.Played != 1 || s.speedrun.Solved != 0 {
+		t.Errorf("newGame() should count the skipped speedrun puzzle as played, got %+v", s.speedrun)
+	}
+}
+
+func Test_session_startBoards(t *testing.T) {
+	wdb := wordDatabase{}
+	wdb.Init(nil, nil)
+	wdb.Replace(LANG_EN, WC_ALL, []word{{'m', 'a', 't', 'c', 'h'}, {'h', 'o', 'u', 's', 'e'}, {'g', 'a', 'm', 'e', 's'}, {'r', 'o', 'a', 't', 'e'}})
+
+	s := session{language: LANG_EN, pastWords: []word{{'m', 'a', 't', 'c', 'h'}, {'h', 'o', 'u', 's', 'e'}}}
+	s.setMode(MODE_MULTI, 0, 4, time.Now(), wdb)
+	if s.Mode() != MODE_MULTI || len(s.boards) != 4 {
+		t.Errorf("setMode() with played words should repeat them, got mode %s with %d boards", s.Mode(), len(s.boards))
+	}
+
+	s = session{language: LANG_EN}
+	s.setMode(MODE_MULTI, 0, 8, time.Now(), wdb)
+	if s.Mode() != MODE_CLASSIC || len(s.boards) != 0 || s.activeSolutionWord == (word{}) {
+		t.Errorf("setMode() without enough words should fall back to classic, got mode %s with %d boards", s.Mode(), len(s.boards))
+	}
+}
+
+func Test_countdown_String(t *testing.T) {
+	start := time.Unix(1615256178, 0)
+
+	tests := []struct {
+		name     string
+		deadline time.Time
+		now      time.Time
+		want     string
+	}{
+		{"minutes", start.Add(3 * time.Minute), start, "3:00"},
+		{"seconds", start.Add(65 * time.Second), start.Add(500 * time.Millisecond), "1:05"},
+		{"passed", start, start.Add(time.Minute), "0:00"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := newCountdown(tt.deadline, tt.now).String(); got != tt.want {
+				t.Errorf("countdown.String() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,166 @@
+// Package corpora exports word lists from the Leipzig Corpora Collection
+// word dumps (<corpus>/<corpus>-words.txt), see https://downloads.wortschatz-leipzig.de/corpora/
+package corpora
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+const SourceURL = "https://downloads.wortschatz-leipzig.de/corpora/"
+
+type Entry struct {
+	Word string
+	Freq int
+}
+
+// ReadWords parses a "*-words.txt" dump, tab separated lines of "w_id, word, freq".
+func ReadWords(r io.Reader) ([]Entry, error) {
+	es := []Entry{}
+
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+
+		cols := strings.Split(scanner.Text(), "\t")
+		if len(cols) < 3 {
+			return nil, fmt.Errorf("expected at least 3 tab separated columns: line=%d", line)
+		}
+
+		freq, err := strconv.Atoi(strings.TrimSpace(cols[2]))
+		if err != nil {
+			return nil, fmt.Errorf("couldn't parse freq: line=%d, err=%s", line, err)
+		}
+
+		es = append(es, Entry{Word: cols[1], Freq: freq})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed scanning words: %s", err)
+	}
+
+	return es, nil
+}
+
+// Filter mirrors the former MariaDB export query:
+//
+//	SELECT LOWER(word) FROM words
+//	WHERE CHAR_LENGTH(word) = 5
+//	  AND word RLIKE "^[A-Z]?[a-z]+$"
+//	  AND freq > 1
+//	ORDER BY freq DESC, word
+//
+// RLIKE ignored case under the _ci collations, so DefaultFilter's Pattern is
+// case insensitive as well. Unlike the query's output, Write adds the
+// frequency as second column.
+type Filter struct {
+	Length  int
+	MinFreq int // inclusive, so `freq > 1` equals a MinFreq of 2
+	Pattern *regexp.Regexp
+}
+
+var DefaultFilter = Filter{
+	Length:  5,
+	MinFreq: 2,
+	Pattern: regexp.MustCompile(`(?i)^[A-Z]?[a-z]+$`),
+}
+
+func (f Filter) String() string {
+	return fmt.Sprintf("length=%d, min freq=%d, pattern=%s", f.Length, f.MinFreq, f.Pattern)
+}
+
+func (f Filter) Apply(es []Entry) []Entry {
+	out := []Entry{}
+	for _, e := range es {
+		if utf8.RuneCountInString(e.Word) != f.Length || e.Freq < f.MinFreq || !f.Pattern.MatchString(e.Word) {
+			continue
+		}
+
+		out = append(out, Entry{Word: strings.ToLower(e.Word), Freq: e.Freq})
+	}
+
+	slices.SortStableFunc(out, func(a, b Entry) int {
+		if a.Freq != b.Freq {
+			return b.Freq - a.Freq
+		}
+
+		return strings.Compare(a.Word, b.Word)
+	})
+
+	return out
+}
+
+// Write emits the entries in the configs/*.txt format, with the frequency as
+// second tab separated column.
+func Write(w io.Writer, header string, es []Entry) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "// %s\n", header)
+	for _, e := range es {
+		fmt.Fprintf(bw, "%s\t%d\n", e.Word, e.Freq)
+	}
+
+	return bw.Flush()
+}
+
+// ExportDir exports every corpus dir found in datasetsDir (as extracted from
+// the downloaded tar.gz) to "<exportDir>/corpora-<corpus>-export.txt" and
+// returns the written paths.
+func ExportDir(datasetsDir string, exportDir string, f Filter) ([]string, error) {
+	entries, err := os.ReadDir(datasetsDir)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't read datasets dir: %s", err)
+	}
+
+	written := []string{}
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+
+		corpus := e.Name()
+		path, err := exportCorpus(filepath.Join(datasetsDir, corpus, corpus+"-words.txt"), corpus, exportDir, f)
+		if err != nil {
+			return written, err
+		}
+
+		written = append(written, path)
+	}
+
+	return written, nil
+}
+
+func exportCorpus(wordsPath string, corpus string, exportDir string, f Filter) (string, error) {
+	in, err := os.Open(wordsPath)
+	if err != nil {
+		return "", fmt.Errorf("couldn't open words dump: %s", err)
+	}
+	defer in.Close()
+
+	es, err := ReadWords(in)
+	if err != nil {
+		return "", fmt.Errorf("couldn't read '%s': %s", wordsPath, err)
+	}
+
+	exportPath := filepath.Join(exportDir, fmt.Sprintf("corpora-%s-export.txt", corpus))
+	out, err := os.Create(exportPath)
+	if err != nil {
+		return "", fmt.Errorf("couldn't create export file: %s", err)
+	}
+	defer out.Close()
+
+	header := fmt.Sprintf("sourced from %s (%s, %s)", SourceURL, corpus, f)
+	err = Write(out, header, f.Apply(es))
+	if err != nil {
+		return "", fmt.Errorf("couldn't write '%s': %s", exportPath, err)
+	}
+
+	return exportPath, nil
+}
@@ -0,0 +1,64 @@
+package corpora
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestFilter_Apply(t *testing.T) {
+	f, err := os.Open("testdata/eng_news_2023_10K/eng_news_2023_10K-words.txt")
+	if err != nil {
+		t.Fatalf("opening fixture failed: %s", err)
+	}
+	defer f.Close()
+
+	es, err := ReadWords(f)
+	if err != nil {
+		t.Fatalf("ReadWords() failed: %s", err)
+	}
+
+	want := []Entry{{"would", 300}, {"about", 120}, {"there", 120}, {"nasdq", 90}, {"march", 60}, {"about", 3}}
+	if got := DefaultFilter.Apply(es); !reflect.DeepEqual(got, want) {
+		t.Errorf("Filter.Apply() = %v, want %v", got, want)
+	}
+}
+
+func TestExportDir(t *testing.T) {
+	exportDir := t.TempDir()
+
+	written, err := ExportDir("testdata", exportDir, DefaultFilter)
+	if err != nil {
+		t.Fatalf("ExportDir() failed: %s", err)
+	}
+
+	wantPath := filepath.Join(exportDir, "corpora-eng_news_2023_10K-export.txt")
+	if !reflect.DeepEqual(written, []string{wantPath}) {
+		t.Fatalf("ExportDir() = %v, want %v", written, []string{wantPath})
+	}
+
+	b, err := os.ReadFile(wantPath)
+	if err != nil {
+		t.Fatalf("reading export failed: %s", err)
+	}
+
+	want := "// sourced from https://downloads.wortschatz-leipzig.de/corpora/ (eng_news_2023_10K, length=5, min freq=2, pattern=(?i)^[A-Z]?[a-z]+$)\n" +
+		"would\t300\nabout\t120\nthere\t120\nnasdq\t90\nmarch\t60\nabout\t3\n"
+	if string(b) != want {
+		t.Errorf("ExportDir() wrote %q, want %q", string(b), want)
+	}
+}
+
+func TestReadWords_invalid(t *testing.T) {
+	f, err := os.CreateTemp(t.TempDir(), "words")
+	if err != nil {
+		t.Fatalf("creating fixture failed: %s", err)
+	}
+	f.WriteString("1\tonly-two-columns\n")
+	f.Seek(0, 0)
+
+	if _, err := ReadWords(f); err == nil {
+		t.Errorf("ReadWords() expected error for malformed line")
+	}
+}
@@ -0,0 +1,219 @@
+// Package i18n holds the ui message catalogues, one json file per locale
+// (e.g. en.json) mapping keys either to a text or to plural forms:
+//
+//	{"game.new": "New Game", "past_words": {"one": "%d past word", "other": "%d past words"}}
+//
+// Texts are fmt format strings, arguments are passed on translation.
+package i18n
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	iofs "io/fs"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+var ErrMissingKey = errors.New("missing translation key")
+
+type message struct {
+	text   string
+	plural map[string]string
+}
+
+func (m *message) UnmarshalJSON(b []byte) error {
+	if err := json.Unmarshal(b, &m.text); err == nil {
+		return nil
+	}
+
+	return json.Unmarshal(b, &m.plural)
+}
+
+type Catalogue struct {
+	defaultLocale string
+	messages      map[string]map[string]message
+}
+
+// Load reads every "<locale>.json" file in dir. The default locale is used
+// as fallback for unknown locales and missing keys.
+func Load(fsys iofs.FS, dir string, defaultLocale string) (*Catalogue, error) {
+	files, err := iofs.Glob(fsys, path.Join(dir, "*.json"))
+	if err != nil {
+		return nil, fmt.Errorf("i18n failed listing catalogues: %s", err)
+	}
+
+	c := &Catalogue{defaultLocale: defaultLocale, messages: make(map[string]map[string]message)}
+	for _, f := range files {
+		b, err := iofs.ReadFile(fsys, f)
+		if err != nil {
+			return nil, fmt.Errorf("i18n failed reading '%s': %s", f, err)
+		}
+
+		ms := map[string]message{}
+		if err := json.Unmarshal(b, &ms); err != nil {
+			return nil, fmt.Errorf("i18n failed parsing '%s': %s", f, err)
+		}
+
+		c.messages[strings.TrimSuffix(path.Base(f), ".json")] = ms
+	}
+
+	if _, ok := c.messages[defaultLocale]; !ok {
+		return nil, fmt.Errorf("i18n misses catalogue of default locale: '%s'", defaultLocale)
+	}
+
+	return c, nil
+}
+
+func (c *Catalogue) DefaultLocale() string {
+	return c.defaultLocale
+}
+
+func (c *Catalogue) Locales() []string {
+	ls := make([]string, 0, len(c.messages))
+	for l := range c.messages {
+		ls = append(ls, l)
+	}
+	slices.Sort(ls)
+
+	return ls
+}
+
+func (c *Catalogue) Has(locale string) bool {
+	_, ok := c.messages[locale]
+	return ok
+}
+
+// Keys returns the sorted keys of a locale.
+func (c *Catalogue) Keys(locale string) []string {
+	keys := make([]string, 0, len(c.messages[locale]))
+	for k := range c.messages[locale] {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+
+	return keys
+}
+
+// Translate returns the text of key. A key missing in the locale falls back
+// to the default locale (or the key itself) and reports ErrMissingKey.
+func (c *Catalogue) Translate(locale string, key string, args ...any) (string, error) {
+	m, err := c.lookup(locale, key)
+	if m.plural != nil {
+		return key, fmt.Errorf("i18n key '%s' has plural forms, translate it with a count", key)
+	}
+
+	return format(m.text, args), err
+}
+
+// TranslatePlural picks the plural form of key for n. n is passed as first
+// format argument, followed by args.
+func (c *Catalogue) TranslatePlural(locale string, key string, n int, args ...any) (string, error) {
+	m, err := c.lookup(locale, key)
+	if m.plural == nil {
+		return format(m.text, append([]any{n}, args...)), err
+	}
+
+	text, ok := m.plural[pluralCategory(n)]
+	if !ok {
+		text, ok = m.plural["other"]
+	}
+	if !ok {
+		return key, fmt.Errorf("i18n key '%s' misses plural form 'other': %w", key, ErrMissingKey)
+	}
+
+	return format(text, append([]any{n}, args...)), err
+}
+
+func (c *Catalogue) lookup(locale string, key string) (message, error) {
+	if m, ok := c.messages[locale][key]; ok {
+		return m, nil
+	}
+
+	err := fmt.Errorf("locale='%s', key='%s': %w", locale, key, ErrMissingKey)
+	if m, ok := c.messages[c.defaultLocale][key]; ok {
+		return m, err
+	}
+
+	return message{text: key}, err
+}
+
+// pluralCategory implements the cardinal rule shared by english and german
+// (CLDR "one" for exactly one, "other" otherwise), plus an optional "zero".
+func pluralCategory(n int) string {
+	switch n {
+	case 0:
+		return "zero"
+	case 1:
+		return "one"
+	default:
+		return "other"
+	}
+}
+
+func format(text string, args []any) string {
+	if len(args) == 0 || !strings.Contains(text, "%") {
+		return text
+	}
+
+	return fmt.Sprintf(text, args...)
+}
+
+// Match picks the best available locale for an Accept-Language header value,
+// e.g. "de-CH,de;q=0.9,en;q=0.8" gives "de". Regional variants fall back to
+// their base language.
+func (c *Catalogue) Match(acceptLanguage string) string {
+	type candidate struct {
+		tag string
+		q   float64
+	}
+
+	cs := []candidate{}
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if tag == "" {
+			continue
+		}
+
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+
+		cs = append(cs, candidate{strings.ToLower(tag), q})
+	}
+
+	slices.SortStableFunc(cs, func(a, b candidate) int {
+		switch {
+		case a.q > b.q:
+			return -1
+		case a.q < b.q:
+			return 1
+		default:
+			return 0
+		}
+	})
+
+	for _, cand := range cs {
+		if cand.q <= 0 {
+			continue
+		}
+
+		if c.Has(cand.tag) {
+			return cand.tag
+		}
+
+		base, _, _ := strings.Cut(cand.tag, "-")
+		if c.Has(base) {
+			return base
+		}
+	}
+
+	return c.defaultLocale
+}
@@ -0,0 +1,103 @@
+package i18n
+
+import (
+	"errors"
+	"os"
+	"testing"
+)
+
+func loadTestdata(t *testing.T) *Catalogue {
+	c, err := Load(os.DirFS("testdata"), ".", "en")
+	if err != nil {
+		t.Fatalf("Load() failed: %s", err)
+	}
+
+	return c
+}
+
+func TestLoad_missingDefault(t *testing.T) {
+	_, err := Load(os.DirFS("testdata"), ".", "fr")
+	if err == nil {
+		t.Errorf("Load() expected error for missing default locale")
+	}
+}
+
+func TestCatalogue_Translate(t *testing.T) {
+	c := loadTestdata(t)
+
+	tests := []struct {
+		name    string
+		locale  string
+		key     string
+		args    []any
+		want    string
+		wantErr bool
+	}{
+		{"with args", "de", "greeting", []any{"Welt"}, "Hallo Welt", false},
+		{"fallback to default locale", "de", "only_en", nil, "english only", true},
+		{"unknown locale", "fr", "greeting", []any{"monde"}, "Hello monde", true},
+		{"missing everywhere", "en", "nope", nil, "nope", true},
+		{"plural key without count", "en", "words", nil, "words", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := c.Translate(tt.locale, tt.key, tt.args...)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Translate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Translate() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := c.Translate("en", "nope"); !errors.Is(err, ErrMissingKey) {
+		t.Errorf("Translate() error = %v, want ErrMissingKey", err)
+	}
+}
+
+func TestCatalogue_TranslatePlural(t *testing.T) {
+	c := loadTestdata(t)
+
+	tests := []struct {
+		locale string
+		n      int
+		want   string
+	}{
+		{"en", 0, "no words"},
+		{"en", 1, "1 word"},
+		{"en", 2, "2 words"},
+		{"de", 0, "0 Wörter"}, // no "zero" form, falls back to "other"
+		{"de", 1, "1 Wort"},
+		{"de", 5, "5 Wörter"},
+	}
+	for _, tt := range tests {
+		got, err := c.TranslatePlural(tt.locale, "words", tt.n)
+		if err != nil || got != tt.want {
+			t.Errorf("TranslatePlural(%s, %d) = %q, %v; want %q", tt.locale, tt.n, got, err, tt.want)
+		}
+	}
+}
+
+func TestCatalogue_Match(t *testing.T) {
+	c := loadTestdata(t)
+
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{"", "en"},
+		{"de", "de"},
+		{"de-CH,de;q=0.9,en;q=0.8", "de"},
+		{"fr-FR,fr;q=0.9,de;q=0.5,en;q=0.7", "en"},
+		{"en;q=0.1, DE-at", "de"},
+		{"de;q=0, fr", "en"},
+		{"*", "en"},
+		{"de;q=nope,en", "en"},
+	}
+	for _, tt := range tests {
+		if got := c.Match(tt.header); got != tt.want {
+			t.Errorf("Match(%q) = %q, want %q", tt.header, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,290 @@
+// Package metrics is a small registry of counters, histograms and gauges,
+// exposed in the Prometheus text format (version 0.0.4).
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefBuckets are latency buckets in seconds, the same as the ones of the
+// official Prometheus client.
+var DefBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// Sample is a single value of a gauge, reported by a GaugeFunc.
+type Sample struct {
+	LabelValues []string
+	Value       float64
+}
+
+type collector interface {
+	write(w *bufio.Writer)
+}
+
+type Registry struct {
+	mu         sync.Mutex
+	collectors []collector
+}
+
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+func (r *Registry) register(c collector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.collectors = append(r.collectors, c)
+}
+
+// Write writes all metrics in the order they were registered.
+func (r *Registry) Write(w io.Writer) error {
+	r.mu.Lock()
+	collectors := slices.Clone(r.collectors)
+	r.mu.Unlock()
+
+	bw := bufio.NewWriter(w)
+	for _, c := range collectors {
+		c.write(bw)
+	}
+
+	return bw.Flush()
+}
+
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		if err := r.Write(w); err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	})
+}
+
+type desc struct {
+	name   string
+	help   string
+	kind   string
+	labels []string
+}
+
+func (d desc) writeHeader(w *bufio.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n", d.name, strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(d.help))
+	fmt.Fprintf(w, "# TYPE %s %s\n", d.name, d.kind)
+}
+
+// labelString renders {a="x",b="y"}, extra is appended as is (e.g. le="1").
+func (d desc) labelString(values []string, extra string) string {
+	pairs := make([]string, 0, len(values)+1)
+	for i, v := range values {
+		pairs = append(pairs, fmt.Sprintf(`%s="%s"`, d.labels[i], escapeLabelValue(v)))
+	}
+	if extra != "" {
+		pairs = append(pairs, extra)
+	}
+
+	if len(pairs) == 0 {
+		return ""
+	}
+
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func (d desc) key(values []string) string {
+	if len(values) != len(d.labels) {
+		panic(fmt.Sprintf("metric '%s' expects %d label values, got %d", d.name, len(d.labels), len(values)))
+	}
+
+	return strings.Join(values, "\xff")
+}
+
+func escapeLabelValue(v string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(v)
+}
+
+func formatFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "+Inf"
+	case math.IsInf(f, -1):
+		return "-Inf"
+	default:
+		return strconv.FormatFloat(f, 'g', -1, 64)
+	}
+}
+
+// sortedKeys keeps the output stable between scrapes.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+
+	return keys
+}
+
+type CounterVec struct {
+	desc
+	mu     sync.Mutex
+	values map[string]float64
+	labels map[string][]string
+}
+
+func (r *Registry) NewCounterVec(name string, help string, labels ...string) *CounterVec {
+	cv := &CounterVec{
+		desc:   desc{name: name, help: help, kind: "counter", labels: labels},
+		values: make(map[string]float64),
+		labels: make(map[string][]string),
+	}
+	r.register(cv)
+
+	return cv
+}
+
+func (cv *CounterVec) Inc(labelValues ...string) {
+	cv.Add(1, labelValues...)
+}
+
+func (cv *CounterVec) Add(v float64, labelValues ...string) {
+	if v < 0 {
+		panic(fmt.Sprintf("counter '%s' can't decrease", cv.name))
+	}
+
+	k := cv.key(labelValues)
+
+	cv.mu.Lock()
+	defer cv.mu.Unlock()
+
+	cv.values[k] += v
+	cv.labels[k] = labelValues
+}
+
+// Value returns the current value of a series, zero if it wasn't touched yet.
+func (cv *CounterVec) Value(labelValues ...string) float64 {
+	cv.mu.Lock()
+	defer cv.mu.Unlock()
+
+	return cv.values[cv.key(labelValues)]
+}
+
+func (cv *CounterVec) write(w *bufio.Writer) {
+	cv.mu.Lock()
+	defer cv.mu.Unlock()
+
+	cv.writeHeader(w)
+	for _, k := range sortedKeys(cv.values) {
+		fmt.Fprintf(w, "%s%s %s\n", cv.name, cv.labelString(cv.labels[k], ""), formatFloat(cv.values[k]))
+	}
+}
+
+type histogram struct {
+	labels  []string
+	buckets []uint64 // cumulative counts are computed on write
+	count   uint64
+	sum     float64
+}
+
+type HistogramVec struct {
+	desc
+	mu         sync.Mutex
+	bounds     []float64
+	histograms map[string]*histogram
+}
+
+func (r *Registry) NewHistogramVec(name string, help string, buckets []float64, labels ...string) *HistogramVec {
+	bounds := slices.Clone(buckets)
+	slices.Sort(bounds)
+
+	hv := &HistogramVec{
+		desc:       desc{name: name, help: help, kind: "histogram", labels: labels},
+		bounds:     bounds,
+		histograms: make(map[string]*histogram),
+	}
+	r.register(hv)
+
+	return hv
+}
+
+func (hv *HistogramVec) Observe(v float64, labelValues ...string) {
+	k := hv.key(labelValues)
+
+	hv.mu.Lock()
+	defer hv.mu.Unlock()
+
+	h, ok := hv.histograms[k]
+	if !ok {
+		h = &histogram{labels: labelValues, buckets: make([]uint64, len(hv.bounds))}
+		hv.histograms[k] = h
+	}
+
+	if i, _ := slices.BinarySearch(hv.bounds, v); i < len(hv.bounds) {
+		h.buckets[i]++
+	}
+	h.count++
+	h.sum += v
+}
+
+// Count returns the number of observations of a series.
+func (hv *HistogramVec) Count(labelValues ...string) uint64 {
+	hv.mu.Lock()
+	defer hv.mu.Unlock()
+
+	if h, ok := hv.histograms[hv.key(labelValues)]; ok {
+		return h.count
+	}
+
+	return 0
+}
+
+func (hv *HistogramVec) write(w *bufio.Writer) {
+	hv.mu.Lock()
+	defer hv.mu.Unlock()
+
+	hv.writeHeader(w)
+	for _, k := range sortedKeys(hv.histograms) {
+		h := hv.histograms[k]
+
+		cumulative := uint64(0)
+		for i, bound := range hv.bounds {
+			cumulative += h.buckets[i]
+			fmt.Fprintf(w, "%s_bucket%s %d\n", hv.name, hv.labelString(h.labels, fmt.Sprintf(`le="%s"`, formatFloat(bound))), cumulative)
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", hv.name, hv.labelString(h.labels, `le="+Inf"`), h.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", hv.name, hv.labelString(h.labels, ""), formatFloat(h.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", hv.name, hv.labelString(h.labels, ""), h.count)
+	}
+}
+
+// GaugeFunc reports values computed on every scrape, e.g. sizes of things
+// which are cheaper to count than to track.
+type GaugeFunc struct {
+	desc
+	f func() []Sample
+}
+
+func (r *Registry) NewGaugeFunc(name string, help string, labels []string, f func() []Sample) *GaugeFunc {
+	gf := &GaugeFunc{desc: desc{name: name, help: help, kind: "gauge", labels: labels}, f: f}
+	r.register(gf)
+
+	return gf
+}
+
+func (gf *GaugeFunc) write(w *bufio.Writer) {
+	samples := gf.f()
+	slices.SortFunc(samples, func(a, b Sample) int {
+		return slices.Compare(a.LabelValues, b.LabelValues)
+	})
+
+	gf.writeHeader(w)
+	for _, s := range samples {
+		gf.key(s.LabelValues) // validates the number of label values
+		fmt.Fprintf(w, "%s%s %s\n", gf.name, gf.labelString(s.LabelValues, ""), formatFloat(s.Value))
+	}
+}
@@ -0,0 +1,81 @@
+package metrics
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRegistry_Write(t *testing.T) {
+	r := NewRegistry()
+
+	c := r.NewCounterVec("test_requests_total", "Requests by route.", "route", "code")
+	c.Inc("/b", "200")
+	c.Inc("/a", "200")
+	c.Add(2, "/a", "200")
+	c.Inc(`/"quoted"`, "500")
+
+	h := r.NewHistogramVec("test_duration_seconds", "Durations.", []float64{1, 0.1}, "route")
+	h.Observe(0.05, "/a")
+	h.Observe(0.1, "/a")
+	h.Observe(3, "/a")
+
+	r.NewGaugeFunc("test_size", "Sizes.", []string{"lang"}, func() []Sample {
+		return []Sample{{[]string{"en"}, 2}, {[]string{"de"}, 1.5}}
+	})
+
+	out := &strings.Builder{}
+	if err := r.Write(out); err != nil {
+		t.Fatalf("Write() failed: %s", err)
+	}
+
+	want := `# HELP test_requests_total Requests by route.
+# TYPE test_requests_total counter
+test_requests_total{route="/\"quoted\"",code="500"} 1
+test_requests_total{route="/a",code="200"} 3
+test_requests_total{route="/b",code="200"} 1
+# HELP test_duration_seconds Durations.
+# TYPE test_duration_seconds histogram
+test_duration_seconds_bucket{route="/a",le="0.1"} 2
+test_duration_seconds_bucket{route="/a",le="1"} 2
+test_duration_seconds_bucket{route="/a",le="+Inf"} 3
+test_duration_seconds_sum{route="/a"} 3.15
+test_duration_seconds_count{route="/a"} 3
+# HELP test_size Sizes.
+# TYPE test_size gauge
+test_size{lang="de"} 1.5
+test_size{lang="en"} 2
+`
+	if out.String() != want {
+		t.Errorf("Write() =\n%s\nwant\n%s", out, want)
+	}
+
+	if c.Value("/a", "200") != 3 || h.Count("/a") != 3 || h.Count("/b") != 0 {
+		t.Errorf("Value() = %v, Count() = %v", c.Value("/a", "200"), h.Count("/a"))
+	}
+}
+
+func TestCounterVec_labelMismatch(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Inc() with the wrong number of label values should panic")
+		}
+	}()
+
+	NewRegistry().NewCounterVec("test_total", "Test.", "a", "b").Inc("only one")
+}
+
+func TestRegistry_Handler(t *testing.T) {
+	r := NewRegistry()
+	r.NewCounterVec("test_total", "Test.").Inc()
+
+	rec := httptest.NewRecorder()
+	r.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("Handler() content type = %s", ct)
+	}
+	if !strings.Contains(rec.Body.String(), "test_total 1\n") {
+		t.Errorf("Handler() body = %s", rec.Body.String())
+	}
+}
@@ -3,7 +3,7 @@ package middleware
 import (
 	"bytes"
 	"io"
-	"log"
+	"log/slog"
 	"net/http"
 )
 
@@ -12,26 +12,34 @@ import (
 type BodySize struct {
 	handler    http.Handler
 	limitBytes int64
+	onReject   func(r *http.Request) // optional, e.g. for metrics
+}
+
+func (bs *BodySize) reject(w http.ResponseWriter, r *http.Request, msg string) {
+	if bs.onReject != nil {
+		bs.onReject(r)
+	}
+
+	w.WriteHeader(http.StatusRequestEntityTooLarge)
+	w.Write([]byte(msg))
 }
 
 func (bs *BodySize) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	if r.ContentLength > bs.limitBytes {
-		w.WriteHeader(http.StatusRequestEntityTooLarge)
-		w.Write([]byte(http.ErrContentLength.Error() + " (ContentLength header)"))
+		bs.reject(w, r, http.ErrContentLength.Error()+" (ContentLength header)")
 		return
 	}
 
 	limitedReader := &io.LimitedReader{R: r.Body, N: bs.limitBytes + 1}
 	readBytes, err := io.ReadAll(limitedReader)
 	if len(readBytes) == int(bs.limitBytes+1) {
-		w.WriteHeader(http.StatusRequestEntityTooLarge)
-		w.Write([]byte(http.ErrContentLength.Error() + " (actual body)"))
+		bs.reject(w, r, http.ErrContentLength.Error()+" (actual body)")
 		return
 	}
 	if err != nil && err != io.EOF {
 		w.WriteHeader(http.StatusBadRequest)
 		w.Write([]byte("Bad request"))
-		log.Printf("body_size middleware error - bad request: '%s' \n", err)
+		slog.WarnContext(r.Context(), "body_size middleware error - bad request", "err", err)
 		return
 	}
 
@@ -44,5 +52,11 @@ func (bs *BodySize) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 // NewBodySize retuns a BodySize middleware, that will limit content lenght to a specified
 // number of bytes.
 func NewBodySize(handlerToWrap http.Handler, bytes int64) http.Handler {
-	return &BodySize{handlerToWrap, bytes}
+	return &BodySize{handlerToWrap, bytes, nil}
+}
+
+// NewBodySizeNotify works like NewBodySize, onReject is called for every
+// request which gets rejected.
+func NewBodySizeNotify(handlerToWrap http.Handler, bytes int64, onReject func(r *http.Request)) http.Handler {
+	return &BodySize{handlerToWrap, bytes, onReject}
 }
@@ -0,0 +1,131 @@
+package middleware
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// CSRF_HEADER carries the token, htmx sends it via hx-headers.
+const CSRF_HEADER = "X-CSRF-Token"
+
+var (
+	ErrCSRFMissing  = errors.New("csrf token missing")
+	ErrCSRFMismatch = errors.New("csrf token doesn't match the session")
+	ErrCSRFExpired  = errors.New("csrf token expired")
+)
+
+// CSRFTokens issues and verifies tokens tied to a session id, each one is
+// the expiry and an hmac of the expiry and the session id.
+type CSRFTokens struct {
+	key      []byte
+	lifetime time.Duration
+	now      func() time.Time
+}
+
+// NewCSRFTokens returns CSRFTokens with a random key, tokens don't survive
+// restarts.
+func NewCSRFTokens(lifetime time.Duration) (*CSRFTokens, error) {
+	key := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return nil, fmt.Errorf("csrf tokens failed generating key: %s", err)
+	}
+
+	return &CSRFTokens{key: key, lifetime: lifetime, now: time.Now}, nil
+}
+
+func (ct *CSRFTokens) mac(sessionID string, expiry []byte) []byte {
+	m := hmac.New(sha256.New, ct.key)
+	m.Write(expiry)
+	m.Write([]byte(sessionID))
+
+	return m.Sum(nil)
+}
+
+// Token returns a token for the session, valid for the lifetime.
+func (ct *CSRFTokens) Token(sessionID string) string {
+	expiry := binary.BigEndian.AppendUint64(nil, uint64(ct.now().Add(ct.lifetime).Unix()))
+
+	return base64.RawURLEncoding.EncodeToString(append(expiry, ct.mac(sessionID, expiry)...))
+}
+
+// Verify returns ErrCSRFMissing, ErrCSRFMismatch or ErrCSRFExpired if the
+// token isn't valid for the session.
+func (ct *CSRFTokens) Verify(sessionID string, token string) error {
+	if token == "" {
+		return ErrCSRFMissing
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil || len(raw) != 8+sha256.Size || sessionID == "" {
+		return ErrCSRFMismatch
+	}
+
+	expiry, mac := raw[:8], raw[8:]
+	if !hmac.Equal(mac, ct.mac(sessionID, expiry)) {
+		return ErrCSRFMismatch
+	}
+
+	if ct.now().Unix() > int64(binary.BigEndian.Uint64(expiry)) {
+		return ErrCSRFExpired
+	}
+
+	return nil
+}
+
+// CSRF is a middleware requiring a valid CSRF_HEADER for all requests but
+// GET, HEAD, OPTIONS and TRACE, unless they are exempt.
+type CSRF struct {
+	handler http.Handler
+	tokens  *CSRFTokens
+	session func(r *http.Request) string            // e.g. the session cookie
+	exempt  func(r *http.Request) bool              // e.g. token authenticated apis, optional
+	message func(r *http.Request, err error) string // body of 403 responses, optional
+}
+
+func (c *CSRF) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		c.handler.ServeHTTP(w, r)
+		return
+	}
+
+	if c.exempt != nil && c.exempt(r) {
+		c.handler.ServeHTTP(w, r)
+		return
+	}
+
+	if err := c.tokens.Verify(c.session(r), r.Header.Get(CSRF_HEADER)); err != nil {
+		slog.WarnContext(r.Context(), "csrf check failed", "path", r.URL.Path, "err", err)
+
+		w.WriteHeader(http.StatusForbidden)
+		if c.message != nil {
+			w.Write([]byte(c.message(r, err)))
+		} else {
+			w.Write([]byte(http.StatusText(http.StatusForbidden)))
+		}
+		return
+	}
+
+	c.handler.ServeHTTP(w, r)
+}
+
+// NewCSRF returns a CSRF middleware, answering 403 to requests without a
+// valid token. exempt and message may be nil.
+func NewCSRF(handlerToWrap http.Handler, tokens *CSRFTokens, session func(r *http.Request) string, exempt func(r *http.Request) bool, message func(r *http.Request, err error) string) http.Handler {
+	return &CSRF{
+		handler: handlerToWrap,
+		tokens:  tokens,
+		session: session,
+		exempt:  exempt,
+		message: message,
+	}
+}
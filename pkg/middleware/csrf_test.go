@@ -0,0 +1,73 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCSRF(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tokens, err := NewCSRFTokens(time.Hour)
+	if err != nil {
+		t.Fatalf("NewCSRFTokens() error = %v", err)
+	}
+	tokens.now = func() time.Time { return now }
+
+	other, _ := NewCSRFTokens(time.Hour)
+
+	expired := tokens.Token("session-a")
+	now = now.Add(61 * time.Minute)
+	valid := tokens.Token("session-a")
+	tampered := []byte(valid)
+	tampered[0] ^= 1
+
+	var gotErr error
+	h := NewCSRF(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}),
+		tokens,
+		func(r *http.Request) string { return r.Header.Get("Session") },
+		func(r *http.Request) bool { return r.URL.Path == "/exempt" },
+		func(r *http.Request, err error) string { gotErr = err; return err.Error() },
+	)
+
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		session  string
+		token    string
+		wantCode int
+		wantErr  error
+	}{
+		{"valid", http.MethodPost, "/", "session-a", valid, http.StatusOK, nil},
+		{"missing", http.MethodPost, "/", "session-a", "", http.StatusForbidden, ErrCSRFMissing},
+		{"other session", http.MethodPost, "/", "session-b", valid, http.StatusForbidden, ErrCSRFMismatch},
+		{"no session", http.MethodPost, "/", "", valid, http.StatusForbidden, ErrCSRFMismatch},
+		{"other key", http.MethodPost, "/", "session-a", other.Token("session-a"), http.StatusForbidden, ErrCSRFMismatch},
+		{"tampered", http.MethodPost, "/", "session-a", string(tampered), http.StatusForbidden, ErrCSRFMismatch},
+		{"garbage", http.MethodPost, "/", "session-a", "not base64!", http.StatusForbidden, ErrCSRFMismatch},
+		{"expired", http.MethodPost, "/", "session-a", expired, http.StatusForbidden, ErrCSRFExpired},
+		{"delete", http.MethodDelete, "/", "session-a", "", http.StatusForbidden, ErrCSRFMissing},
+		{"get", http.MethodGet, "/", "session-a", "", http.StatusOK, nil},
+		{"exempt", http.MethodPost, "/exempt", "session-a", "", http.StatusOK, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotErr = nil
+			r := httptest.NewRequest(tt.method, tt.path, nil)
+			r.Header.Set("Session", tt.session)
+			if tt.token != "" {
+				r.Header.Set(CSRF_HEADER, tt.token)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if w.Code != tt.wantCode || gotErr != tt.wantErr {
+				t.Errorf("got %d %v, want %d %v", w.Code, gotErr, tt.wantCode, tt.wantErr)
+			}
+		})
+	}
+}
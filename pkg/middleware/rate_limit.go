@@ -0,0 +1,186 @@
+package middleware
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Limit is a token bucket, refilled with Rate tokens per second up to Burst.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// ParseLimits parses limits by route, "POST /lettr=1:10;POST /new=0.2:5",
+// each being "<route>=<tokens per second>:<burst>".
+func ParseLimits(s string) (map[string]Limit, error) {
+	limits := make(map[string]Limit)
+	for _, entry := range strings.Split(s, ";") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+
+		route, spec, ok := strings.Cut(entry, "=")
+		rate, burst, ok2 := strings.Cut(spec, ":")
+		if !ok || !ok2 {
+			return nil, fmt.Errorf("invalid limit '%s', expected <route>=<rate>:<burst>", entry)
+		}
+
+		r, err := strconv.ParseFloat(strings.TrimSpace(rate), 64)
+		if err != nil || r <= 0 || math.IsInf(r, 0) {
+			return nil, fmt.Errorf("invalid rate of limit '%s'", entry)
+		}
+		b, err := strconv.Atoi(strings.TrimSpace(burst))
+		if err != nil || b < 1 {
+			return nil, fmt.Errorf("invalid burst of limit '%s'", entry)
+		}
+
+		limits[strings.TrimSpace(route)] = Limit{Rate: r, Burst: b}
+	}
+
+	return limits, nil
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// take refills the bucket and takes a token, if there is none it returns how
+// long it takes until there is one.
+func (b *bucket) take(l Limit, now time.Time) (bool, time.Duration) {
+	b.tokens = math.Min(float64(l.Burst), b.tokens+now.Sub(b.last).Seconds()*l.Rate)
+	b.last = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+
+	return false, time.Duration((1 - b.tokens) / l.Rate * float64(time.Second))
+}
+
+const RATE_LIMIT_PRUNE_INTERVAL = time.Minute
+
+// RateLimit is a middleware limiting requests with token buckets. Every
+// route with a limit gets a bucket per key, e.g. one per client ip and one
+// per session, a request needs a token of all of its buckets.
+type RateLimit struct {
+	handler http.Handler
+	route   func(r *http.Request) string   // picks the limit, e.g. the mux pattern
+	keys    func(r *http.Request) []string // e.g. "ip:1.2.3.4" and "session:x"
+	limits  map[string]Limit               // by route, other routes aren't limited
+	message func(r *http.Request) string   // body of 429 responses, optional
+	now     func() time.Time
+
+	mu         sync.Mutex
+	buckets    map[string]*bucket
+	lastPruned time.Time
+}
+
+func (rl *RateLimit) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	route := rl.route(r)
+	l, ok := rl.limits[route]
+	if !ok {
+		rl.handler.ServeHTTP(w, r)
+		return
+	}
+
+	if ok, retryAfter := rl.allow(route, l, rl.keys(r)); !ok {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+		w.WriteHeader(http.StatusTooManyRequests)
+		if rl.message != nil {
+			w.Write([]byte(rl.message(r)))
+		} else {
+			w.Write([]byte(http.StatusText(http.StatusTooManyRequests)))
+		}
+		return
+	}
+
+	rl.handler.ServeHTTP(w, r)
+}
+
+func (rl *RateLimit) allow(route string, l Limit, keys []string) (bool, time.Duration) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	now := rl.now()
+	rl.prune(now)
+
+	allowed := true
+	var retryAfter time.Duration
+	for _, k := range keys {
+		if k == "" {
+			continue
+		}
+
+		id := route + "\xff" + k
+		b, ok := rl.buckets[id]
+		if !ok {
+			b = &bucket{tokens: float64(l.Burst), last: now}
+			rl.buckets[id] = b
+		}
+
+		// every bucket is charged, hopping between sessions doesn't save ip tokens
+		if ok, wait := b.take(l, now); !ok {
+			allowed = false
+			retryAfter = max(retryAfter, wait)
+		}
+	}
+
+	return allowed, retryAfter
+}
+
+// prune drops buckets which refilled completely, they are the same as new ones.
+func (rl *RateLimit) prune(now time.Time) {
+	if now.Sub(rl.lastPruned) < RATE_LIMIT_PRUNE_INTERVAL {
+		return
+	}
+	rl.lastPruned = now
+
+	for id, b := range rl.buckets {
+		route, _, _ := strings.Cut(id, "\xff")
+		l := rl.limits[route]
+		if b.tokens+now.Sub(b.last).Seconds()*l.Rate >= float64(l.Burst) {
+			delete(rl.buckets, id)
+		}
+	}
+}
+
+// NewRateLimit returns a RateLimit middleware, answering 429 with a
+// Retry-After header once a bucket of a request is empty. message may be nil.
+func NewRateLimit(handlerToWrap http.Handler, limits map[string]Limit, route func(r *http.Request) string, keys func(r *http.Request) []string, message func(r *http.Request) string) http.Handler {
+	return &RateLimit{
+		handler: handlerToWrap,
+		route:   route,
+		keys:    keys,
+		limits:  limits,
+		message: message,
+		now:     time.Now,
+		buckets: make(map[string]*bucket),
+	}
+}
+
+// ClientIP is the ip of the client, taken from trustedHeader if set (e.g.
+// "Fly-Client-IP"). Only use a header the proxy in front always overwrites,
+// clients can send any value.
+func ClientIP(r *http.Request, trustedHeader string) string {
+	if trustedHeader != "" {
+		if ip := net.ParseIP(strings.TrimSpace(r.Header.Get(trustedHeader))); ip != nil {
+			return ip.String()
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
@@ -0,0 +1,116 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRateLimit(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	h := NewRateLimit(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}),
+		map[string]Limit{"/limited": {Rate: 0.5, Burst: 2}},
+		func(r *http.Request) string { return r.URL.Path },
+		func(r *http.Request) []string {
+			return []string{"ip:" + ClientIP(r, ""), "session:" + r.Header.Get("Session")}
+		},
+		nil,
+	).(*RateLimit)
+	h.now = func() time.Time { return now }
+
+	do := func(path string, remoteAddr string, session string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, path, nil)
+		r.RemoteAddr = remoteAddr
+		r.Header.Set("Session", session)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+
+		return w
+	}
+
+	steps := []struct {
+		name           string
+		advance        time.Duration
+		path           string
+		remoteAddr     string
+		session        string
+		wantCode       int
+		wantRetryAfter string
+	}{
+		{"burst 1", 0, "/limited", "1.1.1.1:1", "a", http.StatusOK, ""},
+		{"burst 2", 0, "/limited", "1.1.1.1:1", "a", http.StatusOK, ""},
+		{"empty", 0, "/limited", "1.1.1.1:1", "a", http.StatusTooManyRequests, "2"},
+		{"new session same ip", 0, "/limited", "1.1.1.1:2", "b", http.StatusTooManyRequests, "2"},
+		{"other route", 0, "/free", "1.1.1.1:1", "a", http.StatusOK, ""},
+		{"other ip other session", 0, "/limited", "2.2.2.2:1", "c", http.StatusOK, ""},
+		{"half refilled", time.Second, "/limited", "1.1.1.1:1", "a", http.StatusTooManyRequests, "1"},
+		{"refilled", 2 * time.Second, "/limited", "1.1.1.1:1", "a", http.StatusOK, ""},
+	}
+	for _, s := range steps {
+		now = now.Add(s.advance)
+
+		w := do(s.path, s.remoteAddr, s.session)
+		if w.Code != s.wantCode || w.Header().Get("Retry-After") != s.wantRetryAfter {
+			t.Errorf("%s: got %d Retry-After %q, want %d %q", s.name, w.Code, w.Header().Get("Retry-After"), s.wantCode, s.wantRetryAfter)
+		}
+	}
+
+	now = now.Add(RATE_LIMIT_PRUNE_INTERVAL)
+	do("/free", "1.1.1.1:1", "a")
+	h.allow("/limited", h.limits["/limited"], nil) // triggers the pruning
+	if len(h.buckets) != 0 {
+		t.Errorf("buckets after pruning = %d, want 0", len(h.buckets))
+	}
+}
+
+func TestParseLimits(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    map[string]Limit
+		wantErr bool
+	}{
+		{"", map[string]Limit{}, false},
+		{"POST /lettr=1:10; POST /new=0.2:5", map[string]Limit{"POST /lettr": {1, 10}, "POST /new": {0.2, 5}}, false},
+		{"POST /lettr", nil, true},
+		{"POST /lettr=1", nil, true},
+		{"POST /lettr=0:1", nil, true},
+		{"POST /lettr=1:0", nil, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseLimits(tt.in)
+		if (err != nil) != tt.wantErr || (!tt.wantErr && !reflect.DeepEqual(got, tt.want)) {
+			t.Errorf("ParseLimits(%q) = %v, %v; want %v, %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	tests := []struct {
+		name          string
+		remoteAddr    string
+		header        string
+		trustedHeader string
+		want          string
+	}{
+		{"remote addr", "1.1.1.1:1234", "", "", "1.1.1.1"},
+		{"untrusted header ignored", "1.1.1.1:1234", "9.9.9.9", "", "1.1.1.1"},
+		{"trusted header", "10.0.0.1:1234", "9.9.9.9", "Fly-Client-IP", "9.9.9.9"},
+		{"invalid trusted header", "10.0.0.1:1234", "nope", "Fly-Client-IP", "10.0.0.1"},
+		{"ipv6", "[::1]:1234", "", "", "::1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.RemoteAddr = tt.remoteAddr
+			r.Header.Set("Fly-Client-IP", tt.header)
+
+			if got := ClientIP(r, tt.trustedHeader); got != tt.want {
+				t.Errorf("ClientIP() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
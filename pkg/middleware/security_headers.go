@@ -0,0 +1,199 @@
+package middleware
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// CSP_NONCE_PLACEHOLDER in a policy gets replaced by the nonce of the request.
+const CSP_NONCE_PLACEHOLDER = "{nonce}"
+
+const (
+	CSP_HEADER             = "Content-Security-Policy"
+	CSP_REPORT_ONLY_HEADER = "Content-Security-Policy-Report-Only"
+)
+
+// SecurityHeadersConfig configures the SecurityHeaders middleware.
+type SecurityHeadersConfig struct {
+	CSP        string // CSP_NONCE_PLACEHOLDER gets replaced by a fresh nonce per request
+	ReportOnly bool   // only report violations instead of blocking
+	ReportURI  string // receives violation reports, see NewCSPReportHandler, optional
+
+	Headers map[string]string // further headers, e.g. Strict-Transport-Security
+
+	// Overrides replace headers by route (picked by Route), the CSP as well
+	// as further headers. An empty value removes a header.
+	Overrides map[string]map[string]string
+	Route     func(r *http.Request) string
+}
+
+type cspNonceKey struct{}
+
+// CSPNonce returns the nonce of the request for inline scripts and styles,
+// "nonce" attributes of templates use it.
+func CSPNonce(ctx context.Context) string {
+	n, _ := ctx.Value(cspNonceKey{}).(string)
+	return n
+}
+
+// SecurityHeaders is a middleware setting the Content-Security-Policy (or
+// its report-only variant) and further security related headers.
+type SecurityHeaders struct {
+	handler http.Handler
+	cfg     SecurityHeadersConfig
+}
+
+func (sh *SecurityHeaders) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	nonceBytes := make([]byte, 16)
+	rand.Read(nonceBytes)
+	nonce := base64.RawURLEncoding.EncodeToString(nonceBytes) // nothing html/template escapes in attributes
+
+	headers := make(map[string]string, len(sh.cfg.Headers)+1)
+	for k, v := range sh.cfg.Headers {
+		headers[http.CanonicalHeaderKey(k)] = v
+	}
+	headers[CSP_HEADER] = sh.cfg.CSP
+
+	if sh.cfg.Route != nil {
+		for k, v := range sh.cfg.Overrides[sh.cfg.Route(r)] {
+			headers[http.CanonicalHeaderKey(k)] = v
+		}
+	}
+
+	csp := headers[CSP_HEADER]
+	delete(headers, CSP_HEADER)
+	if csp != "" {
+		csp = strings.ReplaceAll(csp, CSP_NONCE_PLACEHOLDER, nonce)
+		if sh.cfg.ReportURI != "" {
+			csp += "; report-uri " + sh.cfg.ReportURI + "; report-to csp"
+			headers["Reporting-Endpoints"] = `csp="` + sh.cfg.ReportURI + `"`
+		}
+
+		if sh.cfg.ReportOnly {
+			headers[CSP_REPORT_ONLY_HEADER] = csp
+		} else {
+			headers[CSP_HEADER] = csp
+		}
+	}
+
+	for k, v := range headers {
+		if v != "" {
+			w.Header().Set(k, v)
+		}
+	}
+
+	sh.handler.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), cspNonceKey{}, nonce)))
+}
+
+// NewSecurityHeaders returns a SecurityHeaders middleware.
+func NewSecurityHeaders(handlerToWrap http.Handler, cfg SecurityHeadersConfig) http.Handler {
+	return &SecurityHeaders{handlerToWrap, cfg}
+}
+
+// CSPReport is a violation as reported by browsers, either via report-uri or
+// the Reporting API (report-to), which use different field names.
+type CSPReport struct {
+	DocumentURI        string
+	BlockedURI         string
+	EffectiveDirective string
+	Disposition        string // "enforce" or "report"
+	SourceFile         string
+	LineNumber         int
+}
+
+type cspReportURIBody struct {
+	Report struct {
+		DocumentURI        string `json:"document-uri"`
+		BlockedURI         string `json:"blocked-uri"`
+		EffectiveDirective string `json:"effective-directive"`
+		ViolatedDirective  string `json:"violated-directive"`
+		Disposition        string `json:"disposition"`
+		SourceFile         string `json:"source-file"`
+		LineNumber         int    `json:"line-number"`
+	} `json:"csp-report"`
+}
+
+type reportingAPIBody []struct {
+	Type string `json:"type"`
+	Body struct {
+		DocumentURL        string `json:"documentURL"`
+		BlockedURL         string `json:"blockedURL"`
+		EffectiveDirective string `json:"effectiveDirective"`
+		Disposition        string `json:"disposition"`
+		SourceFile         string `json:"sourceFile"`
+		LineNumber         int    `json:"lineNumber"`
+	} `json:"body"`
+}
+
+// ParseCSPReports reads the reports of a request body of either format.
+func ParseCSPReports(contentType string, body io.Reader) ([]CSPReport, error) {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+
+	if mediaType == "application/reports+json" {
+		b := reportingAPIBody{}
+		if err := json.NewDecoder(body).Decode(&b); err != nil {
+			return nil, err
+		}
+
+		reports := []CSPReport{}
+		for _, r := range b {
+			if r.Type != "csp-violation" {
+				continue
+			}
+			reports = append(reports, CSPReport{
+				DocumentURI:        r.Body.DocumentURL,
+				BlockedURI:         r.Body.BlockedURL,
+				EffectiveDirective: r.Body.EffectiveDirective,
+				Disposition:        r.Body.Disposition,
+				SourceFile:         r.Body.SourceFile,
+				LineNumber:         r.Body.LineNumber,
+			})
+		}
+
+		return reports, nil
+	}
+
+	// application/csp-report, some browsers send application/json
+	b := cspReportURIBody{}
+	if err := json.NewDecoder(body).Decode(&b); err != nil {
+		return nil, err
+	}
+
+	directive := b.Report.EffectiveDirective
+	if directive == "" {
+		directive = b.Report.ViolatedDirective
+	}
+
+	return []CSPReport{{
+		DocumentURI:        b.Report.DocumentURI,
+		BlockedURI:         b.Report.BlockedURI,
+		EffectiveDirective: directive,
+		Disposition:        b.Report.Disposition,
+		SourceFile:         b.Report.SourceFile,
+		LineNumber:         b.Report.LineNumber,
+	}}, nil
+}
+
+// NewCSPReportHandler returns the handler of the report endpoint, onReport
+// is called for every violation, e.g. to log it.
+func NewCSPReportHandler(onReport func(r *http.Request, report CSPReport)) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reports, err := ParseCSPReports(r.Header.Get("Content-Type"), r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		for _, report := range reports {
+			onReport(r, report)
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
@@ -0,0 +1,127 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSecurityHeaders(t *testing.T) {
+	var gotNonce string
+	cfg := SecurityHeadersConfig{
+		CSP:     "script-src 'nonce-" + CSP_NONCE_PLACEHOLDER + "'",
+		Headers: map[string]string{"x-content-type-options": "nosniff", "X-Frame-Options": "DENY"},
+		Overrides: map[string]map[string]string{
+			"/json":  {CSP_HEADER: "default-src 'none'", "X-Frame-Options": ""},
+			"/embed": {CSP_HEADER: ""},
+		},
+		Route: func(r *http.Request) string { return r.URL.Path },
+	}
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotNonce = CSPNonce(r.Context())
+	})
+
+	do := func(cfg SecurityHeadersConfig, path string) http.Header {
+		w := httptest.NewRecorder()
+		NewSecurityHeaders(handler, cfg).ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w.Header()
+	}
+
+	h := do(cfg, "/")
+	if gotNonce == "" || h.Get(CSP_HEADER) != "script-src 'nonce-"+gotNonce+"'" {
+		t.Errorf("CSP = %q, want nonce %q", h.Get(CSP_HEADER), gotNonce)
+	}
+	if h.Get("X-Content-Type-Options") != "nosniff" || h.Get("X-Frame-Options") != "DENY" {
+		t.Errorf("headers = %v, want X-Content-Type-Options and X-Frame-Options", h)
+	}
+
+	firstNonce := gotNonce
+	do(cfg, "/")
+	if gotNonce == firstNonce {
+		t.Errorf("nonce %q reused", gotNonce)
+	}
+
+	h = do(cfg, "/json")
+	if h.Get(CSP_HEADER) != "default-src 'none'" || h.Get("X-Content-Type-Options") != "nosniff" {
+		t.Errorf("override: headers = %v", h)
+	}
+	if _, ok := h["X-Frame-Options"]; ok {
+		t.Errorf("override: X-Frame-Options not removed")
+	}
+
+	h = do(cfg, "/embed")
+	if _, ok := h[CSP_HEADER]; ok {
+		t.Errorf("override: CSP not removed")
+	}
+
+	cfg.ReportOnly = true
+	cfg.ReportURI = "/csp-report"
+	h = do(cfg, "/")
+	if _, ok := h[CSP_HEADER]; ok {
+		t.Errorf("report only: enforced CSP set")
+	}
+	if want := "script-src 'nonce-" + gotNonce + "'; report-uri /csp-report; report-to csp"; h.Get(CSP_REPORT_ONLY_HEADER) != want {
+		t.Errorf("report only: CSP = %q, want %q", h.Get(CSP_REPORT_ONLY_HEADER), want)
+	}
+	if h.Get("Reporting-Endpoints") != `csp="/csp-report"` {
+		t.Errorf("Reporting-Endpoints = %q", h.Get("Reporting-Endpoints"))
+	}
+}
+
+func TestParseCSPReports(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		want        []CSPReport
+		wantErr     bool
+	}{
+		{
+			"report-uri",
+			"application/csp-report",
+			`{"csp-report":{"document-uri":"https://lettr.example/","blocked-uri":"inline","violated-directive":"script-src-elem","disposition":"report","line-number":3}}`,
+			[]CSPReport{{DocumentURI: "https://lettr.example/", BlockedURI: "inline", EffectiveDirective: "script-src-elem", Disposition: "report", LineNumber: 3}},
+			false,
+		},
+		{
+			"reporting api",
+			"application/reports+json; charset=utf-8",
+			`[{"type":"csp-violation","body":{"documentURL":"https://lettr.example/","blockedURL":"https://evil.example/x.js","effectiveDirective":"script-src-elem","disposition":"enforce","sourceFile":"https://lettr.example/","lineNumber":7}},{"type":"deprecation","body":{}}]`,
+			[]CSPReport{{DocumentURI: "https://lettr.example/", BlockedURI: "https://evil.example/x.js", EffectiveDirective: "script-src-elem", Disposition: "enforce", SourceFile: "https://lettr.example/", LineNumber: 7}},
+			false,
+		},
+		{"invalid", "application/csp-report", `{"csp-report":`, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseCSPReports(tt.contentType, strings.NewReader(tt.body))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseCSPReports() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseCSPReports() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCSPReportHandler(t *testing.T) {
+	var got []CSPReport
+	h := NewCSPReportHandler(func(r *http.Request, report CSPReport) { got = append(got, report) })
+
+	r := httptest.NewRequest(http.MethodPost, "/csp-report", strings.NewReader(`{"csp-report":{"blocked-uri":"eval"}}`))
+	r.Header.Set("Content-Type", "application/csp-report")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusNoContent || len(got) != 1 || got[0].BlockedURI != "eval" {
+		t.Errorf("got %d %+v, want 204 and the report", w.Code, got)
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/csp-report", strings.NewReader("nope")))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("invalid body: got %d, want 400", w.Code)
+	}
+}
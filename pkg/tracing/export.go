@@ -0,0 +1,229 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The OTLP/JSON encoding of spans, see
+// https://opentelemetry.io/docs/specs/otlp/#json-protobuf-encoding.
+// Trace and span ids are hex, 64 bit integers are strings.
+
+type otlpValue struct {
+	StringValue *string  `json:"stringValue,omitempty"`
+	IntValue    *string  `json:"intValue,omitempty"`
+	DoubleValue *float64 `json:"doubleValue,omitempty"`
+	BoolValue   *bool    `json:"boolValue,omitempty"`
+}
+
+type otlpAttribute struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+type otlpEvent struct {
+	TimeUnixNano string          `json:"timeUnixNano"`
+	Name         string          `json:"name"`
+	Attributes   []otlpAttribute `json:"attributes,omitempty"`
+}
+
+type otlpStatus struct {
+	Code    StatusCode `json:"code,omitempty"`
+	Message string     `json:"message,omitempty"`
+}
+
+type otlpSpan struct {
+	TraceID           string          `json:"traceId"`
+	SpanID            string          `json:"spanId"`
+	ParentSpanID      string          `json:"parentSpanId,omitempty"`
+	Name              string          `json:"name"`
+	Kind              SpanKind        `json:"kind"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+	Attributes        []otlpAttribute `json:"attributes,omitempty"`
+	Events            []otlpEvent     `json:"events,omitempty"`
+	Status            otlpStatus      `json:"status"`
+}
+
+type otlpScopeSpans struct {
+	Scope struct {
+		Name string `json:"name"`
+	} `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpResourceSpans struct {
+	Resource struct {
+		Attributes []otlpAttribute `json:"attributes"`
+	} `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpTraces struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+func toOTLPAttributes(attrs []Attribute) []otlpAttribute {
+	out := make([]otlpAttribute, 0, len(attrs))
+	for _, a := range attrs {
+		v := otlpValue{}
+		switch val := a.Value.(type) {
+		case string:
+			v.StringValue = &val
+		case int64:
+			s := strconv.FormatInt(val, 10)
+			v.IntValue = &s
+		case float64:
+			v.DoubleValue = &val
+		case bool:
+			v.BoolValue = &val
+		default:
+			s := fmt.Sprint(val)
+			v.StringValue = &s
+		}
+		out = append(out, otlpAttribute{Key: a.Key, Value: v})
+	}
+
+	return out
+}
+
+func unixNano(t time.Time) string {
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
+
+func toOTLPSpan(d SpanData) otlpSpan {
+	s := otlpSpan{
+		TraceID:           d.SpanContext.TraceID.String(),
+		SpanID:            d.SpanContext.SpanID.String(),
+		Name:              d.Name,
+		Kind:              d.Kind,
+		StartTimeUnixNano: unixNano(d.Start),
+		EndTimeUnixNano:   unixNano(d.End),
+		Attributes:        toOTLPAttributes(d.Attributes),
+		Status:            otlpStatus{Code: d.Status, Message: d.StatusMessage},
+	}
+	if d.Parent.IsValid() {
+		s.ParentSpanID = d.Parent.String()
+	}
+	for _, e := range d.Events {
+		s.Events = append(s.Events, otlpEvent{TimeUnixNano: unixNano(e.Time), Name: e.Name, Attributes: toOTLPAttributes(e.Attributes)})
+	}
+
+	return s
+}
+
+// Resource describes the service the spans come from.
+type Resource struct {
+	ServiceName    string
+	ServiceVersion string
+}
+
+func encodeOTLP(res Resource, spans []SpanData) otlpTraces {
+	rs := otlpResourceSpans{}
+	rs.Resource.Attributes = toOTLPAttributes([]Attribute{
+		String("service.name", res.ServiceName),
+		String("service.version", res.ServiceVersion),
+	})
+
+	ss := otlpScopeSpans{Spans: make([]otlpSpan, 0, len(spans))}
+	ss.Scope.Name = res.ServiceName
+	for _, d := range spans {
+		ss.Spans = append(ss.Spans, toOTLPSpan(d))
+	}
+	rs.ScopeSpans = []otlpScopeSpans{ss}
+
+	return otlpTraces{ResourceSpans: []otlpResourceSpans{rs}}
+}
+
+// OTLPExporter posts spans to an OTLP/HTTP endpoint (e.g. a collector on
+// "http://localhost:4318/v1/traces"), JSON encoded.
+type OTLPExporter struct {
+	url      string
+	headers  map[string]string
+	resource Resource
+	client   *http.Client
+}
+
+func NewOTLPExporter(url string, headers map[string]string, res Resource) *OTLPExporter {
+	return &OTLPExporter{url: url, headers: headers, resource: res, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+func (e *OTLPExporter) Export(ctx context.Context, spans []SpanData) error {
+	body, err := json.Marshal(encodeOTLP(e.resource, spans))
+	if err != nil {
+		return fmt.Errorf("otlp exporter failed encoding spans: %s", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("otlp exporter failed creating request: %s", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range e.headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("otlp exporter failed sending spans: %s", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("otlp exporter got unexpected status: %s", resp.Status)
+	}
+
+	return nil
+}
+
+// ParseHeaders parses the OTEL_EXPORTER_OTLP_HEADERS format, "k1=v1,k2=v2".
+func ParseHeaders(s string) (map[string]string, error) {
+	headers := make(map[string]string)
+	for _, pair := range strings.Split(s, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+
+		k, v, ok := strings.Cut(pair, "=")
+		if !ok || strings.TrimSpace(k) == "" {
+			return nil, fmt.Errorf("invalid header '%s', expected key=value", pair)
+		}
+		headers[strings.TrimSpace(k)] = strings.TrimSpace(v)
+	}
+
+	return headers, nil
+}
+
+// StdoutExporter writes every span as a line of OTLP/JSON, for local use.
+type StdoutExporter struct {
+	mu       sync.Mutex
+	w        io.Writer
+	resource Resource
+}
+
+func NewStdoutExporter(w io.Writer, res Resource) *StdoutExporter {
+	return &StdoutExporter{w: w, resource: res}
+}
+
+func (e *StdoutExporter) Export(ctx context.Context, spans []SpanData) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	enc := json.NewEncoder(e.w)
+	for _, d := range spans {
+		if err := enc.Encode(encodeOTLP(e.resource, []SpanData{d})); err != nil {
+			return fmt.Errorf("stdout exporter failed writing span: %s", err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,420 @@
+// Package tracing is a small tracer following the OpenTelemetry data model.
+// Spans are propagated with the W3C trace context header and exported in
+// batches, see NewOTLPExporter and NewStdoutExporter.
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const TRACEPARENT_HEADER = "traceparent"
+
+type TraceID [16]byte
+
+func (id TraceID) IsValid() bool  { return id != TraceID{} }
+func (id TraceID) String() string { return hex.EncodeToString(id[:]) }
+
+type SpanID [8]byte
+
+func (id SpanID) IsValid() bool  { return id != SpanID{} }
+func (id SpanID) String() string { return hex.EncodeToString(id[:]) }
+
+// SpanContext identifies a span across process boundaries.
+type SpanContext struct {
+	TraceID TraceID
+	SpanID  SpanID
+	Sampled bool
+}
+
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID.IsValid() && sc.SpanID.IsValid()
+}
+
+type SpanKind int
+
+// values as defined by OTLP
+const (
+	KIND_INTERNAL SpanKind = 1
+	KIND_SERVER   SpanKind = 2
+	KIND_CLIENT   SpanKind = 3
+)
+
+type StatusCode int
+
+// values as defined by OTLP
+const (
+	STATUS_UNSET StatusCode = 0
+	STATUS_OK    StatusCode = 1
+	STATUS_ERROR StatusCode = 2
+)
+
+type Attribute struct {
+	Key   string
+	Value any // string, int64, float64 or bool
+}
+
+func String(k string, v string) Attribute { return Attribute{k, v} }
+func Int(k string, v int) Attribute       { return Attribute{k, int64(v)} }
+func Float(k string, v float64) Attribute { return Attribute{k, v} }
+func Bool(k string, v bool) Attribute     { return Attribute{k, v} }
+
+type Event struct {
+	Name       string
+	Time       time.Time
+	Attributes []Attribute
+}
+
+// SpanData is a finished span as handed to exporters.
+type SpanData struct {
+	Name          string
+	Kind          SpanKind
+	SpanContext   SpanContext
+	Parent        SpanID
+	Start         time.Time
+	End           time.Time
+	Attributes    []Attribute
+	Events        []Event
+	Status        StatusCode
+	StatusMessage string
+}
+
+// Span records a single operation. A nil span is valid and records nothing,
+// that's what Start returns while tracing is disabled. Changes after End are
+// ignored.
+type Span struct {
+	tracer *Tracer
+
+	mu    sync.Mutex
+	data  SpanData
+	ended bool
+}
+
+func (s *Span) SpanContext() SpanContext {
+	if s == nil {
+		return SpanContext{}
+	}
+
+	return s.data.SpanContext
+}
+
+func (s *Span) SetAttributes(attrs ...Attribute) {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.ended {
+		return
+	}
+
+	s.data.Attributes = append(s.data.Attributes, attrs...)
+}
+
+func (s *Span) SetStatus(code StatusCode, msg string) {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.ended {
+		return
+	}
+
+	s.data.Status = code
+	s.data.StatusMessage = msg
+}
+
+// RecordError adds an exception event and marks the span as failed.
+func (s *Span) RecordError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.ended {
+		return
+	}
+
+	s.data.Events = append(s.data.Events, Event{
+		Name:       "exception",
+		Time:       time.Now(),
+		Attributes: []Attribute{String("exception.message", err.Error())},
+	})
+	s.data.Status = STATUS_ERROR
+	s.data.StatusMessage = err.Error()
+}
+
+// End hands the span to the exporter, calls after the first one are ignored.
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.data.End = time.Now()
+	data := s.data
+	s.mu.Unlock()
+
+	if data.SpanContext.Sampled {
+		s.tracer.enqueue(data)
+	}
+}
+
+type spanKey struct{}
+type remoteKey struct{}
+
+// SpanFromContext returns the active span, nil if there is none.
+func SpanFromContext(ctx context.Context) *Span {
+	s, _ := ctx.Value(spanKey{}).(*Span)
+	return s
+}
+
+// ContextWithSpan makes s the parent of spans started from the returned context.
+func ContextWithSpan(ctx context.Context, s *Span) context.Context {
+	return context.WithValue(ctx, spanKey{}, s)
+}
+
+// Extract reads a W3C traceparent header, a valid one becomes the remote
+// parent of the next span started from the returned context.
+func Extract(ctx context.Context, h http.Header) context.Context {
+	sc, ok := parseTraceparent(h.Get(TRACEPARENT_HEADER))
+	if !ok {
+		return ctx
+	}
+
+	return context.WithValue(ctx, remoteKey{}, sc)
+}
+
+// Inject writes the active span of ctx as W3C traceparent header, e.g. for
+// outgoing requests.
+func Inject(ctx context.Context, h http.Header) {
+	sc := SpanFromContext(ctx).SpanContext()
+	if !sc.IsValid() {
+		return
+	}
+
+	flags := "00"
+	if sc.Sampled {
+		flags = "01"
+	}
+	h.Set(TRACEPARENT_HEADER, fmt.Sprintf("00-%s-%s-%s", sc.TraceID, sc.SpanID, flags))
+}
+
+// parseTraceparent parses version 00 headers, "00-<trace id>-<span id>-<flags>".
+func parseTraceparent(v string) (SpanContext, bool) {
+	parts := strings.Split(v, "-")
+	if len(parts) != 4 || parts[0] != "00" || len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return SpanContext{}, false
+	}
+
+	sc := SpanContext{}
+	if _, err := hex.Decode(sc.TraceID[:], []byte(parts[1])); err != nil {
+		return SpanContext{}, false
+	}
+	if _, err := hex.Decode(sc.SpanID[:], []byte(parts[2])); err != nil {
+		return SpanContext{}, false
+	}
+	flags, err := hex.DecodeString(parts[3])
+	if err != nil {
+		return SpanContext{}, false
+	}
+	sc.Sampled = flags[0]&0x01 == 1
+
+	return sc, sc.IsValid()
+}
+
+// Exporter sends finished spans somewhere, it's only called by a single
+// goroutine at a time.
+type Exporter interface {
+	Export(ctx context.Context, spans []SpanData) error
+}
+
+const (
+	MAX_QUEUE_SIZE        = 2048 // spans ending while the queue is full get dropped
+	MAX_EXPORT_BATCH_SIZE = 512
+	EXPORT_INTERVAL       = 5 * time.Second
+)
+
+// Tracer starts spans and exports them in batches from a background goroutine.
+type Tracer struct {
+	exporter Exporter
+	onError  func(err error)
+
+	queue    chan SpanData
+	flush    chan chan struct{}
+	done     chan struct{}
+	shutdown sync.Once
+}
+
+// New starts the export loop, Shutdown stops it. onError is called with
+// failed exports, it may be nil.
+func New(exporter Exporter, onError func(err error)) *Tracer {
+	t := &Tracer{
+		exporter: exporter,
+		onError:  onError,
+		queue:    make(chan SpanData, MAX_QUEUE_SIZE),
+		flush:    make(chan chan struct{}),
+		done:     make(chan struct{}),
+	}
+	go t.run()
+
+	return t
+}
+
+// Start starts a span as child of the active or remote span of ctx, or as
+// root of a new trace. A nil tracer returns a nil span.
+func (t *Tracer) Start(ctx context.Context, name string, kind SpanKind, attrs ...Attribute) (context.Context, *Span) {
+	if t == nil {
+		return ctx, nil
+	}
+
+	data := SpanData{Name: name, Kind: kind, Start: time.Now(), Attributes: slices.Clone(attrs)}
+
+	parent := SpanFromContext(ctx).SpanContext()
+	if !parent.IsValid() {
+		parent, _ = ctx.Value(remoteKey{}).(SpanContext)
+	}
+
+	if parent.IsValid() {
+		data.SpanContext.TraceID = parent.TraceID
+		data.SpanContext.Sampled = parent.Sampled
+		data.Parent = parent.SpanID
+	} else {
+		rand.Read(data.SpanContext.TraceID[:])
+		data.SpanContext.Sampled = true
+	}
+	rand.Read(data.SpanContext.SpanID[:])
+
+	s := &Span{tracer: t, data: data}
+
+	return ContextWithSpan(ctx, s), s
+}
+
+func (t *Tracer) enqueue(data SpanData) {
+	select {
+	case t.queue <- data:
+	default:
+	}
+}
+
+func (t *Tracer) run() {
+	ticker := time.NewTicker(EXPORT_INTERVAL)
+	defer ticker.Stop()
+
+	batch := make([]SpanData, 0, MAX_EXPORT_BATCH_SIZE)
+	export := func() {
+		if len(batch) == 0 {
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		if err := t.exporter.Export(ctx, batch); err != nil && t.onError != nil {
+			t.onError(err)
+		}
+		batch = make([]SpanData, 0, MAX_EXPORT_BATCH_SIZE)
+	}
+	add := func(data SpanData) {
+		batch = append(batch, data)
+		if len(batch) == MAX_EXPORT_BATCH_SIZE {
+			export()
+		}
+	}
+	drain := func() {
+		for {
+			select {
+			case data := <-t.queue:
+				add(data)
+			default:
+				export()
+				return
+			}
+		}
+	}
+
+	for {
+		select {
+		case data := <-t.queue:
+			add(data)
+		case <-ticker.C:
+			export()
+		case flushed := <-t.flush:
+			drain()
+			close(flushed)
+		case <-t.done:
+			drain()
+			return
+		}
+	}
+}
+
+// Flush exports all ended spans right away.
+func (t *Tracer) Flush() {
+	if t == nil {
+		return
+	}
+
+	flushed := make(chan struct{})
+	select {
+	case t.flush <- flushed:
+		<-flushed
+	case <-t.done:
+	}
+}
+
+// Shutdown exports the remaining spans and stops the export loop.
+func (t *Tracer) Shutdown() {
+	if t == nil {
+		return
+	}
+
+	t.shutdown.Do(func() {
+		t.Flush()
+		close(t.done)
+	})
+}
+
+var (
+	defaultMu     sync.RWMutex
+	defaultTracer *Tracer
+)
+
+// SetDefault sets the tracer used by Start, nil disables tracing.
+func SetDefault(t *Tracer) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+
+	defaultTracer = t
+}
+
+func Default() *Tracer {
+	defaultMu.RLock()
+	defer defaultMu.RUnlock()
+
+	return defaultTracer
+}
+
+// Start starts an internal span with the default tracer.
+func Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, *Span) {
+	return Default().Start(ctx, name, KIND_INTERNAL, attrs...)
+}
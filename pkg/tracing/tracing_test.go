@@ -0,0 +1,167 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+type recordingExporter struct {
+	mu    sync.Mutex
+	spans []SpanData
+}
+
+func (e *recordingExporter) Export(ctx context.Context, spans []SpanData) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.spans = append(e.spans, spans...)
+	return nil
+}
+
+func TestTracer_Start(t *testing.T) {
+	e := &recordingExporter{}
+	tr := New(e, nil)
+	defer tr.Shutdown()
+
+	ctx, root := tr.Start(context.Background(), "root", KIND_SERVER, String("a", "b"))
+	_, child := tr.Start(ctx, "child", KIND_INTERNAL)
+	child.RecordError(errors.New("boom"))
+	child.End()
+	root.SetAttributes(Int("status", 200))
+	root.End()
+	root.SetAttributes(Int("ignored", 1)) // after End
+	root.End()
+
+	tr.Flush()
+
+	if len(e.spans) != 2 {
+		t.Fatalf("exported %d spans, want 2", len(e.spans))
+	}
+
+	c, r := e.spans[0], e.spans[1]
+	if c.SpanContext.TraceID != r.SpanContext.TraceID || c.Parent != r.SpanContext.SpanID {
+		t.Errorf("child %v isn't part of root %v", c.SpanContext, r.SpanContext)
+	}
+	if r.Parent.IsValid() {
+		t.Errorf("root has parent %s", r.Parent)
+	}
+	if c.Status != STATUS_ERROR || c.StatusMessage != "boom" || len(c.Events) != 1 {
+		t.Errorf("child status = %v %q events %d, want error boom with an event", c.Status, c.StatusMessage, len(c.Events))
+	}
+	if want := []Attribute{String("a", "b"), Int("status", 200)}; !reflect.DeepEqual(r.Attributes, want) {
+		t.Errorf("root attributes = %v, want %v", r.Attributes, want)
+	}
+}
+
+func TestTracer_nil(t *testing.T) {
+	var tr *Tracer
+
+	ctx, s := tr.Start(context.Background(), "x", KIND_INTERNAL)
+	s.SetAttributes(String("a", "b"))
+	s.RecordError(errors.New("boom"))
+	s.End()
+	tr.Flush()
+	tr.Shutdown()
+
+	if SpanFromContext(ctx) != nil || s.SpanContext().IsValid() {
+		t.Errorf("nil tracer started a span")
+	}
+}
+
+func TestExtractInject(t *testing.T) {
+	e := &recordingExporter{}
+	tr := New(e, nil)
+	defer tr.Shutdown()
+
+	tests := []struct {
+		name        string
+		traceparent string
+		wantParent  bool
+		wantSampled bool
+	}{
+		{"sampled parent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", true, true},
+		{"unsampled parent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00", true, false},
+		{"invalid version", "01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", false, true},
+		{"zero trace id", "00-00000000000000000000000000000000-00f067aa0ba902b7-01", false, true},
+		{"garbage", "nope", false, true},
+		{"missing", "", false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := http.Header{}
+			h.Set(TRACEPARENT_HEADER, tt.traceparent)
+
+			ctx, s := tr.Start(Extract(context.Background(), h), "x", KIND_SERVER)
+			sc := s.SpanContext()
+
+			if got := sc.TraceID.String() == "4bf92f3577b34da6a3ce929d0e0e4736"; got != tt.wantParent {
+				t.Errorf("trace id = %s, want parent's %v", sc.TraceID, tt.wantParent)
+			}
+			if sc.Sampled != tt.wantSampled {
+				t.Errorf("sampled = %v, want %v", sc.Sampled, tt.wantSampled)
+			}
+
+			out := http.Header{}
+			Inject(ctx, out)
+			if got, ok := parseTraceparent(out.Get(TRACEPARENT_HEADER)); !ok || got != sc {
+				t.Errorf("Inject() = %s, want %v", out.Get(TRACEPARENT_HEADER), sc)
+			}
+		})
+	}
+}
+
+func TestOTLPExporter(t *testing.T) {
+	var got map[string]any
+	var auth string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		b, _ := io.ReadAll(r.Body)
+		json.Unmarshal(b, &got)
+	}))
+	defer srv.Close()
+
+	tr := New(NewOTLPExporter(srv.URL+"/v1/traces", map[string]string{"Authorization": "Bearer x"}, Resource{ServiceName: "test"}), func(err error) {
+		t.Errorf("export failed: %s", err)
+	})
+	_, s := tr.Start(context.Background(), "GET /", KIND_SERVER, Int("http.status_code", 200), Bool("b", true))
+	s.End()
+	tr.Shutdown()
+
+	if auth != "Bearer x" {
+		t.Errorf("Authorization = %q, want %q", auth, "Bearer x")
+	}
+
+	span := got["resourceSpans"].([]any)[0].(map[string]any)["scopeSpans"].([]any)[0].(map[string]any)["spans"].([]any)[0].(map[string]any)
+	if span["name"] != "GET /" || span["kind"] != float64(KIND_SERVER) || span["traceId"] != s.SpanContext().TraceID.String() {
+		t.Errorf("exported span = %v", span)
+	}
+	if v := span["attributes"].([]any)[0].(map[string]any)["value"].(map[string]any)["intValue"]; v != "200" {
+		t.Errorf("exported int attribute = %v, want \"200\"", v)
+	}
+}
+
+func TestParseHeaders(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    map[string]string
+		wantErr bool
+	}{
+		{"", map[string]string{}, false},
+		{"a=1, b = 2", map[string]string{"a": "1", "b": "2"}, false},
+		{"Authorization=Basic abc==", map[string]string{"Authorization": "Basic abc=="}, false},
+		{"novalue", nil, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseHeaders(tt.in)
+		if (err != nil) != tt.wantErr || (!tt.wantErr && !reflect.DeepEqual(got, tt.want)) {
+			t.Errorf("ParseHeaders(%q) = %v, %v; want %v, %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,281 @@
+// Package wordlist builds word lists in the format of configs/*.txt
+// (metadata line + one word per line) from raw sources.
+package wordlist
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/pandorasNox/lettr/pkg/wordset"
+)
+
+type UmlautPolicy string
+
+const (
+	UMLAUTS_KEEP          UmlautPolicy = "keep"          // keep words with umlauts as they are
+	UMLAUTS_TRANSLITERATE UmlautPolicy = "transliterate" // ä -> ae, ö -> oe, ü -> ue, ß -> ss
+	UMLAUTS_DROP          UmlautPolicy = "drop"          // skip words containing umlauts
+)
+
+func NewUmlautPolicy(maybePolicy string) (UmlautPolicy, error) {
+	switch UmlautPolicy(maybePolicy) {
+	case UMLAUTS_KEEP, UMLAUTS_TRANSLITERATE, UMLAUTS_DROP:
+		return UmlautPolicy(maybePolicy), nil
+	default:
+		return UMLAUTS_DROP, fmt.Errorf("unknown umlaut policy: '%s'", maybePolicy)
+	}
+}
+
+// Transliterations are the replacements used by UMLAUTS_TRANSLITERATE.
+var Transliterations = map[rune]string{'ä': "ae", 'ö': "oe", 'ü': "ue", 'ß': "ss"}
+
+// Normalise lower cases w and applies the umlaut policy. It reports false
+// if the word has to be dropped under the policy.
+func Normalise(w string, p UmlautPolicy) (string, bool) {
+	w = strings.ToLower(strings.TrimSpace(w))
+
+	var sb strings.Builder
+	for _, r := range w {
+		t, isUmlaut := Transliterations[r]
+		switch {
+		case !isUmlaut, p == UMLAUTS_KEEP:
+			sb.WriteRune(r)
+		case p == UMLAUTS_TRANSLITERATE:
+			sb.WriteString(t)
+		default:
+			return "", false
+		}
+	}
+
+	return sb.String(), true
+}
+
+type Filter struct {
+	Length    int
+	Alphabet  string
+	Umlauts   UmlautPolicy
+	Dedupe    bool
+	Blocklist []string
+}
+
+func (f Filter) String() string {
+	return fmt.Sprintf("length=%d, alphabet=%s, umlauts=%s, dedupe=%t, blocklist=%d", f.Length, f.Alphabet, f.Umlauts, f.Dedupe, len(f.Blocklist))
+}
+
+// Apply normalises (lower case + umlaut policy) and filters candidates.
+// The result is sorted to keep builds reproducible.
+func (f Filter) Apply(candidates []string) []string {
+	blocked := make(map[string]bool, len(f.Blocklist))
+	for _, b := range f.Blocklist {
+		blocked[strings.ToLower(strings.TrimSpace(b))] = true
+	}
+
+	out := []string{}
+	for _, c := range candidates {
+		w, ok := Normalise(c, f.Umlauts)
+		if !ok || blocked[w] {
+			continue
+		}
+
+		if utf8.RuneCountInString(w) != f.Length {
+			continue
+		}
+
+		if !f.inAlphabet(w) {
+			continue
+		}
+
+		out = append(out, w)
+	}
+
+	slices.Sort(out)
+	if f.Dedupe {
+		out = slices.Compact(out)
+	}
+
+	return out
+}
+
+func (f Filter) inAlphabet(w string) bool {
+	return InAlphabet(w, f.Alphabet, f.Umlauts)
+}
+
+// InAlphabet reports whether every letter of w is part of alphabet, umlauts
+// are fine as well if they are kept.
+func InAlphabet(w string, alphabet string, p UmlautPolicy) bool {
+	for _, r := range w {
+		_, isUmlaut := Transliterations[r]
+		if isUmlaut && p == UMLAUTS_KEEP {
+			continue
+		}
+
+		if !strings.ContainsRune(alphabet, r) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Load reads candidates from a local file, a directory (all *.txt and *.json
+// files) or a http(s) url. Json sources are expected to be wordset data files,
+// everything else is read as one word per line, ignoring "//" comment lines
+// and further tab separated columns.
+func Load(ctx context.Context, client *http.Client, source string) ([]string, error) {
+	if strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://") {
+		return loadURL(ctx, client, source)
+	}
+
+	fi, err := os.Stat(source)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't stat source: %s", err)
+	}
+
+	if !fi.IsDir() {
+		return loadFile(source)
+	}
+
+	entries, err := os.ReadDir(source)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't read source dir: %s", err)
+	}
+
+	out := []string{}
+	for _, e := range entries {
+		ext := filepath.Ext(e.Name())
+		if e.IsDir() || (ext != ".txt" && ext != ".json") {
+			continue
+		}
+
+		ws, err := loadFile(filepath.Join(source, e.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		out = append(out, ws...)
+	}
+
+	return out, nil
+}
+
+func loadFile(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't open source: %s", err)
+	}
+	defer f.Close()
+
+	ws, err := parse(f, filepath.Ext(path) == ".json")
+	if err != nil {
+		return nil, fmt.Errorf("couldn't parse source '%s': %s", path, err)
+	}
+
+	return ws, nil
+}
+
+func loadURL(ctx context.Context, client *http.Client, url string) ([]string, error) {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't create request: %s", err)
+	}
+	req.Header.Set("User-Agent", "lettr-wordlist-build")
+
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request to '%s' failed: %s", url, err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("request to '%s' failed with status: %d", url, res.StatusCode)
+	}
+
+	ws, err := parse(res.Body, strings.HasSuffix(url, ".json"))
+	if err != nil {
+		return nil, fmt.Errorf("couldn't parse source '%s': %s", url, err)
+	}
+
+	return ws, nil
+}
+
+func parse(r io.Reader, isWordset bool) ([]string, error) {
+	if isWordset {
+		es, err := wordset.ParseEntries(r)
+		if err != nil {
+			return nil, err
+		}
+
+		out := make([]string, 0, len(es))
+		for _, e := range es {
+			out = append(out, e.Word)
+		}
+
+		return out, nil
+	}
+
+	out := []string{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "//") {
+			continue
+		}
+
+		w, _, _ := strings.Cut(line, "\t") // e.g. corpora exports carry a freq column
+		out = append(out, w)
+	}
+
+	return out, scanner.Err()
+}
+
+// Write emits the list in the project's format, the first line being the metadata header.
+func Write(w io.Writer, header string, words []string) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "// %s\n", header)
+	for _, word := range words {
+		fmt.Fprintln(bw, word)
+	}
+
+	return bw.Flush()
+}
+
+// Diff compares two lists, returning the sorted words only found in next (added)
+// and only found in prev (removed).
+func Diff(prev []string, next []string) (added []string, removed []string) {
+	inPrev := make(map[string]bool, len(prev))
+	for _, w := range prev {
+		inPrev[w] = true
+	}
+
+	inNext := make(map[string]bool, len(next))
+	for _, w := range next {
+		inNext[w] = true
+		if !inPrev[w] {
+			added = append(added, w)
+		}
+	}
+
+	for _, w := range prev {
+		if !inNext[w] {
+			removed = append(removed, w)
+		}
+	}
+
+	slices.Sort(added)
+	slices.Sort(removed)
+
+	return slices.Compact(added), slices.Compact(removed)
+}
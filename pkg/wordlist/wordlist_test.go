@@ -0,0 +1,117 @@
+package wordlist
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"testing"
+)
+
+const latin = "abcdefghijklmnopqrstuvwxyz"
+
+func TestFilter_Apply(t *testing.T) {
+	candidates, err := Load(context.Background(), nil, "testdata/plain.txt")
+	if err != nil {
+		t.Fatalf("Load() failed: %s", err)
+	}
+
+	tests := []struct {
+		name   string
+		filter Filter
+		want   []string
+	}{
+		{
+			"drop umlauts",
+			Filter{Length: 5, Alphabet: latin, Umlauts: UMLAUTS_DROP, Dedupe: true},
+			[]string{"apfel", "tisch"},
+		},
+		{
+			"transliterate umlauts",
+			Filter{Length: 5, Alphabet: latin, Umlauts: UMLAUTS_TRANSLITERATE, Dedupe: true},
+			[]string{"apfel", "guete", "spass", "tisch"},
+		},
+		{
+			"keep umlauts",
+			Filter{Length: 5, Alphabet: latin, Umlauts: UMLAUTS_KEEP, Dedupe: true},
+			[]string{"apfel", "bäume", "größe", "tisch", "zwölf"},
+		},
+		{
+			"without dedupe",
+			Filter{Length: 5, Alphabet: latin, Umlauts: UMLAUTS_DROP, Dedupe: false},
+			[]string{"apfel", "apfel", "tisch"},
+		},
+		{
+			"blocklist",
+			Filter{Length: 5, Alphabet: latin, Umlauts: UMLAUTS_DROP, Dedupe: true, Blocklist: []string{"Tisch"}},
+			[]string{"apfel"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.filter.Apply(candidates); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Filter.Apply() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	fixture, err := os.ReadFile("testdata/wordset-b.json")
+	if err != nil {
+		t.Fatalf("reading fixture failed: %s", err)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(fixture)
+	}))
+	defer srv.Close()
+
+	filter := Filter{Length: 5, Alphabet: latin, Umlauts: UMLAUTS_DROP, Dedupe: true}
+	want := []string{"bread", "break", "bride"}
+
+	for _, source := range []string{"testdata/wordset-b.json", srv.URL + "/b.json"} {
+		got, err := Load(context.Background(), srv.Client(), source)
+		if err != nil {
+			t.Fatalf("Load(%s) failed: %s", source, err)
+		}
+
+		if got := filter.Apply(got); !reflect.DeepEqual(got, want) {
+			t.Errorf("Load(%s) = %v, want %v", source, got, want)
+		}
+	}
+
+	dirWords, err := Load(context.Background(), nil, "testdata")
+	if err != nil {
+		t.Fatalf("Load(testdata) failed: %s", err)
+	}
+	if len(dirWords) != 16 {
+		t.Errorf("Load(testdata) returned %d candidates, want 16", len(dirWords))
+	}
+}
+
+func TestDiff(t *testing.T) {
+	added, removed := Diff([]string{"apfel", "brot", "tisch"}, []string{"tisch", "apfel", "zange", "zange"})
+
+	if !reflect.DeepEqual(added, []string{"zange"}) {
+		t.Errorf("Diff() added = %v, want %v", added, []string{"zange"})
+	}
+	if !reflect.DeepEqual(removed, []string{"brot"}) {
+		t.Errorf("Diff() removed = %v, want %v", removed, []string{"brot"})
+	}
+}
+
+func TestWrite(t *testing.T) {
+	var buf bytes.Buffer
+	err := Write(&buf, "generated for a test", []string{"apfel", "tisch"})
+	if err != nil {
+		t.Fatalf("Write() failed: %s", err)
+	}
+
+	want := "// generated for a test\napfel\ntisch\n"
+	if buf.String() != want {
+		t.Errorf("Write() = %q, want %q", buf.String(), want)
+	}
+}
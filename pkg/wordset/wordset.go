@@ -0,0 +1,91 @@
+// Package wordset handles the data files of the wordset-dictionary,
+// see https://github.com/wordset/wordset-dictionary
+package wordset
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// DataURLFormat points to the per letter data files, e.g. fmt.Sprintf(DataURLFormat, "a").
+const DataURLFormat = "https://raw.githubusercontent.com/wordset/wordset-dictionary/master/data/%s.json"
+
+// DataSetNames are the names of all per letter data files.
+var DataSetNames = []string{"a", "b", "c", "d", "e", "f", "g", "h", "i", "j", "k", "l", "m", "misc", "n", "o", "p", "q", "r", "s", "t", "u", "v", "w", "x", "y", "z"}
+
+// {
+//     "largely": {
+//         "word": "largely",
+//         "wordset_id": "54bd55df7265742391cf0000",
+//         "meanings": [{
+//             "id": "54bd55df7265742391d10000",
+//             "def": "in large part",
+//             "speech_part": "adverb",
+//             "synonyms": ["mostly", "for the most part"]
+//         }, {
+//             "id": "54bd55df7265742391d20000",
+//             "def": "on a large scale",
+//             "example": "the sketch was so largely drawn that you could see it from the back row",
+//             "speech_part": "adverb"
+//         }]
+//     }
+// }
+
+type Entries map[string]Entry
+
+type Entry struct {
+	Word     string    `json:"word"`
+	Meanings []Meaning `json:"meanings"`
+}
+
+type Meaning struct {
+	Def        string   `json:"def"`
+	Example    string   `json:"example,omitempty"`
+	SpeechPart string   `json:"speech_part"`
+	Synonyms   []string `json:"synonyms,omitempty"`
+}
+
+// Dataset is the converted, pre-fetched definition dataset as consumed by the
+// lettr server. Keys of Entries are lower case words.
+type Dataset struct {
+	Source  string               `json:"source"`
+	Entries map[string][]Meaning `json:"entries"`
+}
+
+func ParseEntries(r io.Reader) (Entries, error) {
+	es := Entries{}
+	err := json.NewDecoder(r).Decode(&es)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't parse wordset entries: %s", err)
+	}
+
+	return es, nil
+}
+
+func ParseDataset(r io.Reader) (Dataset, error) {
+	ds := Dataset{}
+	err := json.NewDecoder(r).Decode(&ds)
+	if err != nil {
+		return Dataset{}, fmt.Errorf("couldn't parse definition dataset: %s", err)
+	}
+
+	return ds, nil
+}
+
+// Add copies the meanings of all entries accepted by keep into the dataset.
+func (ds *Dataset) Add(es Entries, keep func(word string) bool) {
+	if ds.Entries == nil {
+		ds.Entries = make(map[string][]Meaning)
+	}
+
+	for _, e := range es {
+		if len(e.Meanings) == 0 || !keep(e.Word) {
+			continue
+		}
+
+		w := strings.ToLower(e.Word)
+		ds.Entries[w] = append(ds.Entries[w], e.Meanings...)
+	}
+}
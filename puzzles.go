@@ -0,0 +1,249 @@
+package main
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var ErrInvalidPuzzleToken = errors.New("invalid puzzle token")
+
+const (
+	MAX_CREATED_PUZZLES        = 20  // per session, the oldest get dropped
+	MAX_CUSTOM_PUZZLE_RESULTS  = 100 // per puzzle
+	customPuzzleAdditionalData = "lettr custom puzzle v1"
+)
+
+// customPuzzle is a solution picked by a player. It is only ever handed out
+// sealed into a token, so the word can't be read from (or changed in) a link.
+type customPuzzle struct {
+	ID       string   `json:"i"`
+	Language language `json:"l"`
+	Solution word     `json:"w"`
+}
+
+// puzzleSealer encrypts custom puzzles with AES-GCM, which also authenticates them.
+type puzzleSealer struct {
+	aead cipher.AEAD
+}
+
+// newPuzzleSealer derives the key from secret. Without a secret a random key
+// is used, links then stop working with a restart.
+func newPuzzleSealer(secret string) (puzzleSealer, error) {
+	key := make([]byte, 32)
+	if secret != "" {
+		sum := sha256.Sum256([]byte(secret))
+		key = sum[:]
+	} else if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return puzzleSealer{}, fmt.Errorf("puzzle sealer failed generating key: %s", err)
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return puzzleSealer{}, fmt.Errorf("puzzle sealer failed creating cipher: %s", err)
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return puzzleSealer{}, fmt.Errorf("puzzle sealer failed creating gcm: %s", err)
+	}
+
+	return puzzleSealer{aead: aead}, nil
+}
+
+func (ps puzzleSealer) Seal(cp customPuzzle) (string, error) {
+	plain, err := json.Marshal(cp)
+	if err != nil {
+		return "", fmt.Errorf("puzzle sealer failed encoding puzzle: %s", err)
+	}
+
+	nonce := make([]byte, ps.aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", fmt.Errorf("puzzle sealer failed generating nonce: %s", err)
+	}
+
+	sealed := ps.aead.Seal(nonce, nonce, plain, []byte(customPuzzleAdditionalData))
+
+	return base64.RawURLEncoding.EncodeToString(sealed), nil
+}
+
+func (ps puzzleSealer) Open(token string) (customPuzzle, error) {
+	sealed, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil || len(sealed) < ps.aead.NonceSize() {
+		return customPuzzle{}, ErrInvalidPuzzleToken
+	}
+
+	nonce, ciphertext := sealed[:ps.aead.NonceSize()], sealed[ps.aead.NonceSize():]
+	plain, err := ps.aead.Open(nil, nonce, ciphertext, []byte(customPuzzleAdditionalData))
+	if err != nil {
+		return customPuzzle{}, ErrInvalidPuzzleToken
+	}
+
+	cp := customPuzzle{}
+	if err := json.Unmarshal(plain, &cp); err != nil {
+		return customPuzzle{}, ErrInvalidPuzzleToken
+	}
+
+	if _, err := NewLang(string(cp.Language)); err != nil {
+		return customPuzzle{}, ErrInvalidPuzzleToken
+	}
+
+	return cp, nil
+}
+
+// createdPuzzle is a custom puzzle as remembered by the session which created it.
+type createdPuzzle struct {
+	customPuzzle
+	Token     string
+	CreatedAt time.Time
+}
+
+func (cp createdPuzzle) LanguageConfig() languageConfig {
+	return languages.config(cp.Language)
+}
+
+// createPuzzle validates w against the word database of l and remembers the
+// sealed puzzle in the session of its creator.
+func (s *session) createPuzzle(l language, w word, wdb wordDatabase, ps puzzleSealer) (createdPuzzle, error) {
+	if !wdb.Exists(l, w) {
+		return createdPuzzle{}, ErrNotInWordList
+	}
+
+	cp := customPuzzle{ID: uuid.NewString(), Language: l, Solution: w.ToLower()}
+	token, err := ps.Seal(cp)
+	if err != nil {
+		return createdPuzzle{}, err
+	}
+
+	created := createdPuzzle{customPuzzle: cp, Token: token, CreatedAt: time.Now()}
+
+	cs := append(slices.Clone(s.createdPuzzles), created)
+	if len(cs) > MAX_CREATED_PUZZLES {
+		cs = cs[len(cs)-MAX_CREATED_PUZZLES:]
+	}
+	s.createdPuzzles = cs
+
+	return created, nil
+}
+
+// CreatedPuzzles returns the puzzles created by the session, newest first.
+func (s session) CreatedPuzzles() []createdPuzzle {
+	cs := slices.Clone(s.createdPuzzles)
+	slices.Reverse(cs)
+
+	return cs
+}
+
+// startCustomPuzzle replaces the active game with the custom puzzle. Custom
+// solutions never enter the past words, the regular rotation stays as is.
+func (s *session) startCustomPuzzle(cp customPuzzle, wdb wordDatabase) {
+	s.switchLanguage(cp.Language, wdb)
+	s.retireGame()
+
+	s.mode = MODE_CLASSIC
+	s.speedrun = speedrun{}
+	s.boards = nil
+
+	s.startGame(cp.Solution)
+	s.customPuzzleID = cp.ID
+}
+
+type customPuzzleResult struct {
+	Outcome    gameOutcome
+	Guesses    [6]wordGuess
+	FinishedAt time.Time
+}
+
+func (r customPuzzleResult) Attempts() int {
+	return int(puzzle{Guesses: r.Guesses}.activeRow())
+}
+
+// customPuzzleResults collects the results of custom puzzles for their
+// creators, one per player session and puzzle. They are kept in memory only.
+type customPuzzleResults struct {
+	mu      sync.Mutex
+	results map[string]map[string]customPuzzleResult // puzzle id -> session id -> result
+}
+
+func newCustomPuzzleResults() *customPuzzleResults {
+	return &customPuzzleResults{results: make(map[string]map[string]customPuzzleResult)}
+}
+
+// Report keeps the first result of a session, replaying a link doesn't count.
+func (cpr *customPuzzleResults) Report(puzzleID string, sessionID string, r customPuzzleResult) {
+	cpr.mu.Lock()
+	defer cpr.mu.Unlock()
+
+	bySession, ok := cpr.results[puzzleID]
+	if !ok {
+		bySession = make(map[string]customPuzzleResult)
+		cpr.results[puzzleID] = bySession
+	}
+
+	if _, ok := bySession[sessionID]; ok || len(bySession) >= MAX_CUSTOM_PUZZLE_RESULTS {
+		return
+	}
+
+	bySession[sessionID] = r
+}
+
+// Results returns the results of a puzzle, oldest first.
+func (cpr *customPuzzleResults) Results(puzzleID string) []customPuzzleResult {
+	cpr.mu.Lock()
+	defer cpr.mu.Unlock()
+
+	rs := make([]customPuzzleResult, 0, len(cpr.results[puzzleID]))
+	for _, r := range cpr.results[puzzleID] {
+		rs = append(rs, r)
+	}
+
+	slices.SortFunc(rs, func(a, b customPuzzleResult) int {
+		return a.FinishedAt.Compare(b.FinishedAt)
+	})
+
+	return rs
+}
+
+type createdPuzzleData struct {
+	createdPuzzle
+	URL     string
+	Results []customPuzzleResult
+}
+
+type puzzlesData struct {
+	Locale    string
+	Error     string
+	Created   string // url of a just created puzzle
+	Puzzles   []createdPuzzleData
+	Language  languageConfig
+	WordInput string
+}
+
+func newPuzzlesData(baseURL string, s session, cpr *customPuzzleResults) puzzlesData {
+	data := puzzlesData{Locale: s.uiLocale, Language: languages.config(s.language)}
+	for _, cp := range s.CreatedPuzzles() {
+		data.Puzzles = append(data.Puzzles, createdPuzzleData{
+			createdPuzzle: cp,
+			URL:           puzzleURL(baseURL, cp.Token),
+			Results:       cpr.Results(cp.ID),
+		})
+	}
+
+	return data
+}
+
+// puzzleURL builds the absolute link of a puzzle token, see publicBaseURL.
+func puzzleURL(baseURL string, token string) string {
+	return fmt.Sprintf("%s/p/%s", baseURL, token)
+}
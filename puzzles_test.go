@@ -0,0 +1,113 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_puzzleSealer(t *testing.T) {
+	ps, err := newPuzzleSealer("secret")
+	if err != nil {
+		t.Fatalf("newPuzzleSealer() failed: %s", err)
+	}
+
+	cp := customPuzzle{ID: "id", Language: LANG_DE, Solution: word{'h', 'a', 'u', 's', 'e'}}
+	token, err := ps.Seal(cp)
+	if err != nil {
+		t.Fatalf("Seal() failed: %s", err)
+	}
+
+	if strings.Contains(strings.ToLower(token), "haus") {
+		t.Errorf("Seal() = %s, must not reveal the solution", token)
+	}
+
+	got, err := ps.Open(token)
+	if err != nil || got != cp {
+		t.Errorf("Open() = %v, %v, want %v", got, err, cp)
+	}
+
+	other, _ := newPuzzleSealer("other secret")
+	random, _ := newPuzzleSealer("")
+	tampered := []byte(token)
+	tampered[len(tampered)/2] ^= 1
+
+	tests := []struct {
+		name   string
+		sealer puzzleSealer
+		token  string
+	}{
+		{"other secret", other, token},
+		{"random key", random, token},
+		{"tampered", ps, string(tampered)},
+		{"truncated", ps, token[:10]},
+		{"not base64", ps, "!!!"},
+		{"empty", ps, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tt.sealer.Open(tt.token); !errors.Is(err, ErrInvalidPuzzleToken) {
+				t.Errorf("Open() error = %v, want %v", err, ErrInvalidPuzzleToken)
+			}
+		})
+	}
+}
+
+func Test_session_customPuzzle(t *testing.T) {
+	wdb := wordDatabase{}
+	wdb.Init(nil, nil)
+	wdb.Replace(LANG_EN, WC_ALL, []word{{'m', 'a', 't', 'c', 'h'}, {'h', 'o', 'u', 's', 'e'}})
+
+	ps, _ := newPuzzleSealer("secret")
+
+	creator := session{language: LANG_EN}
+	if _, err := creator.createPuzzle(LANG_EN, word{'g', 'a', 'm', 'e', 'r'}, wdb, ps); !errors.Is(err, ErrNotInWordList) {
+		t.Errorf("createPuzzle() error = %v, want %v", err, ErrNotInWordList)
+	}
+
+	created, err := creator.createPuzzle(LANG_EN, word{'H', 'O', 'U', 'S', 'E'}, wdb, ps)
+	if err != nil {
+		t.Fatalf("createPuzzle() failed: %s", err)
+	}
+	if len(creator.CreatedPuzzles()) != 1 || created.Solution != (word{'h', 'o', 'u', 's', 'e'}) {
+		t.Errorf("createPuzzle() = %v, created puzzles %v", created, creator.CreatedPuzzles())
+	}
+
+	cp, _ := ps.Open(created.Token)
+	player := session{language: LANG_EN}
+	player.startGame(word{'m', 'a', 't', 'c', 'h'})
+	player.startCustomPuzzle(cp, wdb)
+
+	if player.activeSolutionWord != cp.Solution || player.customPuzzleID != cp.ID || player.Mode() != MODE_CLASSIC {
+		t.Errorf("startCustomPuzzle() should start the custom puzzle, got %v %s", player.activeSolutionWord, player.customPuzzleID)
+	}
+
+	player.newGame(wdb)
+	if player.customPuzzleID != "" {
+		t.Errorf("newGame() should leave the custom puzzle")
+	}
+	for _, w := range player.pastWords {
+		if w == cp.Solution {
+			t.Errorf("custom solution %v must not enter the past words %v", cp.Solution, player.pastWords)
+		}
+	}
+}
+
+func Test_customPuzzleResults_Report(t *testing.T) {
+	cpr := newCustomPuzzleResults()
+	now := time.Now()
+
+	cpr.Report("puzzle", "b", customPuzzleResult{Outcome: OUTCOME_LOST, FinishedAt: now.Add(time.Minute)})
+	cpr.Report("puzzle", "a", customPuzzleResult{Outcome: OUTCOME_SOLVED, FinishedAt: now})
+	cpr.Report("puzzle", "a", customPuzzleResult{Outcome: OUTCOME_LOST, FinishedAt: now.Add(2 * time.Minute)})
+	cpr.Report("other", "a", customPuzzleResult{Outcome: OUTCOME_SOLVED, FinishedAt: now})
+
+	rs := cpr.Results("puzzle")
+	if len(rs) != 2 || rs[0].Outcome != OUTCOME_SOLVED || rs[1].Outcome != OUTCOME_LOST {
+		t.Errorf("Results() = %v, want the first result per session, oldest first", rs)
+	}
+	if len(cpr.Results("unknown")) != 0 {
+		t.Errorf("Results() of an unknown puzzle should be empty")
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"maps"
+	"net/http"
+
+	"github.com/pandorasNox/lettr/pkg/middleware"
+)
+
+// defaultRateLimits cover the routes worth hammering: guesses (brute forcing
+// solutions), new games and sessions, room codes and login mails.
+var defaultRateLimits = map[string]middleware.Limit{
+	"POST /lettr":              {Rate: 1, Burst: 10},
+	"POST /boards":             {Rate: 1, Burst: 10},
+	"POST /rooms/{code}/guess": {Rate: 1, Burst: 10},
+	"POST /new":                {Rate: 0.2, Burst: 5},
+	"POST /puzzles":            {Rate: 0.1, Burst: 5},
+	"POST /suggest":            {Rate: 0.1, Burst: 5},
+	"POST /rooms":              {Rate: 0.1, Burst: 5},
+	"POST /rooms/join":         {Rate: 0.5, Burst: 10},
+	"POST /leaderboard/groups": {Rate: 0.2, Burst: 5},
+	"POST /account/login":      {Rate: 1.0 / 60, Burst: 3},
+	"POST " + CSP_REPORT_ROUTE: {Rate: 1, Burst: 20},
+}
+
+// rateLimits merges the RATE_LIMITS overrides into defaultRateLimits.
+func rateLimits(overrides string) (map[string]middleware.Limit, error) {
+	if overrides == "off" {
+		return map[string]middleware.Limit{}, nil
+	}
+
+	parsed, err := middleware.ParseLimits(overrides)
+	if err != nil {
+		return nil, err
+	}
+
+	limits := maps.Clone(defaultRateLimits)
+	maps.Copy(limits, parsed)
+
+	return limits, nil
+}
+
+// rateLimitKeys gives every client ip and every session its own buckets.
+func rateLimitKeys(r *http.Request, clientIPHeader string) []string {
+	keys := []string{"ip:" + middleware.ClientIP(r, clientIPHeader)}
+	if c, err := r.Cookie(SESSION_COOKIE_NAME); err == nil && c.Value != "" {
+		keys = append(keys, "session:"+c.Value)
+	}
+
+	return keys
+}
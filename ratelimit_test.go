@@ -0,0 +1,56 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/pandorasNox/lettr/pkg/middleware"
+)
+
+func Test_rateLimits(t *testing.T) {
+	tests := []struct {
+		name      string
+		overrides string
+		wantLen   int
+		wantLettr middleware.Limit
+		wantErr   bool
+	}{
+		{"defaults", "", len(defaultRateLimits), defaultRateLimits["POST /lettr"], false},
+		{"override", "POST /lettr=5:50", len(defaultRateLimits), middleware.Limit{Rate: 5, Burst: 50}, false},
+		{"additional route", "POST /hint=1:3", len(defaultRateLimits) + 1, defaultRateLimits["POST /lettr"], false},
+		{"off", "off", 0, middleware.Limit{}, false},
+		{"invalid", "POST /lettr=fast", 0, middleware.Limit{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := rateLimits(tt.overrides)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("rateLimits() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(got) != tt.wantLen || got["POST /lettr"] != tt.wantLettr {
+				t.Errorf("rateLimits() = %v, want %d limits and POST /lettr %v", got, tt.wantLen, tt.wantLettr)
+			}
+		})
+	}
+
+	if defaultRateLimits["POST /lettr"] != (middleware.Limit{Rate: 1, Burst: 10}) {
+		t.Errorf("rateLimits() changed the defaults")
+	}
+}
+
+func Test_rateLimitKeys(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/lettr", nil)
+	r.RemoteAddr = "10.0.0.1:1234"
+	r.Header.Set("Fly-Client-IP", "9.9.9.9")
+
+	if got, want := rateLimitKeys(r, "Fly-Client-IP"), []string{"ip:9.9.9.9"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("rateLimitKeys() = %v, want %v", got, want)
+	}
+
+	r.AddCookie(&http.Cookie{Name: SESSION_COOKIE_NAME, Value: "abc"})
+	if got, want := rateLimitKeys(r, ""), []string{"ip:10.0.0.1", "session:abc"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("rateLimitKeys() = %v, want %v", got, want)
+	}
+}
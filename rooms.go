@@ -0,0 +1,651 @@
+package main
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"html/template"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrRoomNotFound = errors.New("room not found")
+	ErrRoomFull     = errors.New("room is full")
+	ErrNotInRoom    = errors.New("not a player of the room")
+	ErrRoundOver    = errors.New("round is over")
+)
+
+const (
+	ROOM_CODE_ALPHABET = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789" // without look-alikes like 0/O and 1/I
+	ROOM_CODE_LENGTH   = 5
+	MAX_ROOM_PLAYERS   = 2
+	ROOM_TTL           = 2 * time.Hour // rooms without any activity get dropped
+)
+
+type roomPlayer struct {
+	sessionID string
+	Name      string
+	Attempt   puzzle
+	Wins      int
+	Rematch   bool // wants another round
+}
+
+// room is a head-to-head race, all players guess the same solution.
+type room struct {
+	mu          sync.Mutex
+	code        string
+	language    language
+	solution    word
+	round       int
+	players     []*roomPlayer
+	winner      string // session id, empty while running or if nobody solved it
+	isOver      bool
+	updatedAt   time.Time
+	subscribers map[chan struct{}]bool
+}
+
+// roomHub manages all rooms, every room guards its own state.
+type roomHub struct {
+	mu    sync.Mutex
+	rooms map[string]*room
+	wdb   wordDatabase
+}
+
+func newRoomHub(wdb wordDatabase) *roomHub {
+	return &roomHub{rooms: make(map[string]*room), wdb: wdb}
+}
+
+func newRoomCode() (string, error) {
+	var sb strings.Builder
+	for range ROOM_CODE_LENGTH {
+		i, err := rand.Int(rand.Reader, big.NewInt(int64(len(ROOM_CODE_ALPHABET))))
+		if err != nil {
+			return "", fmt.Errorf("room code generation failed: %s", err)
+		}
+		sb.WriteByte(ROOM_CODE_ALPHABET[i.Int64()])
+	}
+
+	return sb.String(), nil
+}
+
+// NormaliseRoomCode makes typed in codes comparable, e.g. " abc12 " gives "ABC12".
+func NormaliseRoomCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// Create opens a room for l with the creating session as first player.
+func (h *roomHub) Create(sessionID string, name string, l language) (*room, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.dropStale(time.Now())
+
+	code, err := newRoomCode()
+	if err != nil {
+		return nil, err
+	}
+	for h.rooms[code] != nil {
+		if code, err = newRoomCode(); err != nil {
+			return nil, err
+		}
+	}
+
+	r := &room{
+		code:        code,
+		language:    l,
+		solution:    h.wdb.RandomPickWithFallback(l, []word{}, 0),
+		round:       1,
+		players:     []*roomPlayer{{sessionID: sessionID, Name: name}},
+		updatedAt:   time.Now(),
+		subscribers: make(map[chan struct{}]bool),
+	}
+	h.rooms[code] = r
+
+	return r, nil
+}
+
+func (h *roomHub) Get(code string) (*room, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	r, ok := h.rooms[NormaliseRoomCode(code)]
+	if !ok {
+		return nil, ErrRoomNotFound
+	}
+
+	return r, nil
+}
+
+// Join adds a player, joining a room twice just returns it.
+func (h *roomHub) Join(code string, sessionID string, name string) (*room, error) {
+	r, err := h.Get(code)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.player(sessionID) != nil {
+		return r, nil
+	}
+
+	if len(r.players) >= MAX_ROOM_PLAYERS {
+		return nil, ErrRoomFull
+	}
+
+	r.players = append(r.players, &roomPlayer{sessionID: sessionID, Name: name})
+	r.changed()
+
+	return r, nil
+}
+
+// Leave removes a player, the last one leaving closes the room.
+func (h *roomHub) Leave(code string, sessionID string) error {
+	r, err := h.Get(code)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	r.players = slices.DeleteFunc(r.players, func(p *roomPlayer) bool {
+		return p.sessionID == sessionID
+	})
+	empty := len(r.players) == 0
+	r.changed()
+	r.mu.Unlock()
+
+	if empty {
+		h.mu.Lock()
+		delete(h.rooms, r.code)
+		h.mu.Unlock()
+	}
+
+	return nil
+}
+
+// dropStale removes inactive rooms, h.mu must be held.
+func (h *roomHub) dropStale(now time.Time) {
+	for code, r := range h.rooms {
+		r.mu.Lock()
+		stale := now.Sub(r.updatedAt) > ROOM_TTL
+		r.mu.Unlock()
+
+		if stale {
+			delete(h.rooms, code)
+		}
+	}
+}
+
+func (r *room) Code() string {
+	return r.code
+}
+
+// player returns the player of the session, r.mu must be held.
+func (r *room) player(sessionID string) *roomPlayer {
+	i := slices.IndexFunc(r.players, func(p *roomPlayer) bool {
+		return p.sessionID == sessionID
+	})
+	if i == -1 {
+		return nil
+	}
+
+	return r.players[i]
+}
+
+func (r *room) Has(sessionID string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.player(sessionID) != nil
+}
+
+// Guess evaluates the next row of the player. The first player solving the
+// puzzle wins the round, it is over without a winner once all players lost.
+func (r *room) Guess(sessionID string, w word) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	p := r.player(sessionID)
+	if p == nil {
+		return ErrNotInRoom
+	}
+
+	if r.isOver || p.Attempt.isLoose() {
+		return ErrRoundOver
+	}
+
+	p.Attempt.Guesses[p.Attempt.activeRow()] = evaluateGuessedWord(languages.config(r.language).normaliser(), w, r.solution)
+
+	if p.Attempt.isSolved() {
+		r.winner = p.sessionID
+		r.isOver = true
+		p.Wins++
+	} else if !slices.ContainsFunc(r.players, func(p *roomPlayer) bool { return !p.Attempt.isLoose() }) {
+		r.isOver = true
+	}
+
+	r.changed()
+
+	return nil
+}
+
+// Rematch marks the player ready for another round, which starts as soon as
+// all players are ready.
+func (r *room) Rematch(sessionID string, wdb wordDatabase) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	p := r.player(sessionID)
+	if p == nil {
+		return ErrNotInRoom
+	}
+
+	if !r.isOver {
+		return nil
+	}
+
+	p.Rematch = true
+	if slices.ContainsFunc(r.players, func(p *roomPlayer) bool { return !p.Rematch }) {
+		r.changed()
+		return nil
+	}
+
+	r.solution = wdb.RandomPickWithFallback(r.language, []word{r.solution}, 0)
+	r.round++
+	r.winner = ""
+	r.isOver = false
+	for _, p := range r.players {
+		p.Attempt = puzzle{}
+		p.Rematch = false
+	}
+	r.changed()
+
+	return nil
+}
+
+// Subscribe registers for change notifications, cancel must be called once
+// the subscriber is gone.
+func (r *room) Subscribe() (updates <-chan struct{}, cancel func()) {
+	ch := make(chan struct{}, 1)
+
+	r.mu.Lock()
+	r.subscribers[ch] = true
+	r.mu.Unlock()
+
+	return ch, func() {
+		r.mu.Lock()
+		delete(r.subscribers, ch)
+		r.mu.Unlock()
+	}
+}
+
+// changed notifies all subscribers, r.mu must be held. Notifications never
+// block, a pending one already covers the change.
+func (r *room) changed() {
+	r.updatedAt = time.Now()
+
+	for ch := range r.subscribers {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// roomPlayerView is what a player sees of someone, opponents only show
+// the colours of their guesses, never the letters.
+type roomPlayerView struct {
+	Name     string
+	Attempt  puzzle
+	Attempts int
+	Wins     int
+	Rematch  bool
+	IsSolved bool
+	IsLoose  bool
+}
+
+func newRoomPlayerView(p *roomPlayer, withLetters bool) roomPlayerView {
+	v := roomPlayerView{
+		Name:     p.Name,
+		Attempt:  p.Attempt,
+		Attempts: int(p.Attempt.activeRow()),
+		Wins:     p.Wins,
+		Rematch:  p.Rematch,
+		IsSolved: p.Attempt.isSolved(),
+		IsLoose:  p.Attempt.isLoose(),
+	}
+
+	if !withLetters {
+		for ri := range v.Attempt.Guesses {
+			for li := range v.Attempt.Guesses[ri] {
+				v.Attempt.Guesses[ri][li].Letter = 0
+			}
+		}
+	}
+
+	return v
+}
+
+type roomView struct {
+	Code           string
+	Locale         string
+	LanguageConfig languageConfig
+	Round          int
+	Me             roomPlayerView
+	Opponents      []roomPlayerView
+	IsOver         bool
+	IsWinner       bool
+	Winner         string // name, empty if nobody solved it
+	Solution       word   // only revealed once the round is over
+	MaxPlayers     int
+}
+
+// View returns the room as seen by the player of the session.
+func (r *room) View(sessionID string, locale string) (roomView, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	me := r.player(sessionID)
+	if me == nil {
+		return roomView{}, ErrNotInRoom
+	}
+
+	v := roomView{
+		Code:           r.code,
+		Locale:         locale,
+		LanguageConfig: languages.config(r.language),
+		Round:          r.round,
+		Me:             newRoomPlayerView(me, true),
+		IsOver:         r.isOver,
+		IsWinner:       r.winner == sessionID,
+		MaxPlayers:     MAX_ROOM_PLAYERS,
+	}
+
+	for _, p := range r.players {
+		if p.sessionID == r.winner {
+			v.Winner = p.Name
+		}
+		if p != me {
+			v.Opponents = append(v.Opponents, newRoomPlayerView(p, false))
+		}
+	}
+
+	if r.isOver {
+		v.Solution = r.solution
+	}
+
+	return v, nil
+}
+
+func (v roomView) CodeLength() int {
+	return ROOM_CODE_LENGTH
+}
+
+// roomState identifies a round and whether it is over, clients reload the
+// whole room when it changes.
+func (v roomView) roomState() string {
+	return fmt.Sprintf("%d-%t", v.Round, v.IsOver)
+}
+
+func roomPlayerName(r *http.Request, s session, fallbackNumber int) string {
+	name := strings.TrimSpace(r.FormValue("name"))
+	if name == "" {
+		return tr(s.uiLocale, "room.player", fallbackNumber)
+	}
+
+	// keep names short, they are shown in small boxes only
+	if rs := []rune(name); len(rs) > 20 {
+		name = string(rs[:20])
+	}
+
+	return name
+}
+
+// registerRoomRoutes wires the multiplayer routes. sessionFor resolves the
+// session of a request, the same way as for all other routes.
+func registerRoomRoutes(mux *http.ServeMux, t *template.Template, hub *roomHub, sessionFor func(w http.ResponseWriter, r *http.Request) session) {
+	renderRoom := func(w http.ResponseWriter, r *http.Request, rm *room, s session, route string) {
+		v, err := rm.View(s.id, s.uiLocale)
+		if err != nil {
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(tr(s.uiLocale, "room.not_joined")))
+			return
+		}
+
+		err = t.ExecuteTemplate(w, "room", v)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "executing template failed", "route", route, "err", err)
+		}
+	}
+
+	roomOf := func(w http.ResponseWriter, r *http.Request, s session) (*room, bool) {
+		rm, err := hub.Get(r.PathValue("code"))
+		if err != nil {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(tr(s.uiLocale, "room.not_found")))
+			return nil, false
+		}
+
+		if !rm.Has(s.id) {
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(tr(s.uiLocale, "room.not_joined")))
+			return nil, false
+		}
+
+		return rm, true
+	}
+
+	mux.HandleFunc("GET /rooms", func(w http.ResponseWriter, r *http.Request) {
+		s := sessionFor(w, r)
+
+		err := t.ExecuteTemplate(w, "room-lobby", roomView{Locale: s.uiLocale})
+		if err != nil {
+			slog.ErrorContext(r.Context(), "executing template failed", "route", "/rooms", "err", err)
+		}
+	})
+
+	mux.HandleFunc("POST /rooms", func(w http.ResponseWriter, r *http.Request) {
+		s := sessionFor(w, r)
+
+		rm, err := hub.Create(s.id, roomPlayerName(r, s, 1), s.language)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "creating room failed", "err", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		renderRoom(w, r, rm, s, "/rooms")
+	})
+
+	mux.HandleFunc("POST /rooms/join", func(w http.ResponseWriter, r *http.Request) {
+		s := sessionFor(w, r)
+
+		rm, err := hub.Join(r.FormValue("code"), s.id, roomPlayerName(r, s, 2))
+		if errors.Is(err, ErrRoomNotFound) {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(tr(s.uiLocale, "room.not_found")))
+			return
+		}
+		if errors.Is(err, ErrRoomFull) {
+			w.WriteHeader(http.StatusConflict)
+			w.Write([]byte(tr(s.uiLocale, "room.full")))
+			return
+		}
+		if err != nil {
+			slog.ErrorContext(r.Context(), "joining room failed", "err", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		renderRoom(w, r, rm, s, "/rooms/join")
+	})
+
+	mux.HandleFunc("GET /rooms/{code}", func(w http.ResponseWriter, r *http.Request) {
+		s := sessionFor(w, r)
+
+		rm, ok := roomOf(w, r, s)
+		if !ok {
+			return
+		}
+
+		renderRoom(w, r, rm, s, "/rooms/{code}")
+	})
+
+	mux.HandleFunc("POST /rooms/{code}/guess", func(w http.ResponseWriter, r *http.Request) {
+		s := sessionFor(w, r)
+
+		rm, ok := roomOf(w, r, s)
+		if !ok {
+			return
+		}
+
+		err := r.ParseForm()
+		if err != nil {
+			slog.WarnContext(r.Context(), "parsing form failed", "err", err)
+		}
+
+		v, _ := rm.View(s.id, s.uiLocale)
+		n := v.LanguageConfig.normaliser()
+
+		// guards against replayed or doubled submits, like the row check of '/lettr'
+		if r.PostForm.Get("row") != strconv.Itoa(v.Me.Attempts) {
+			appMetrics.guessRejected(REJECT_FAKED_ROWS)
+			w.WriteHeader(422)
+			w.Write([]byte("faked rows"))
+			return
+		}
+
+		guessedWord, err := sliceToWord(r.PostForm["g"], n)
+		if err != nil {
+			appMetrics.guessRejected(REJECT_INVALID_GUESS)
+			w.WriteHeader(422)
+			w.Write([]byte("invalid guess"))
+			return
+		}
+
+		if !hub.wdb.Exists(v.LanguageConfig.Code, guessedWord) {
+			appMetrics.guessRejected(REJECT_NOT_IN_WORD_LIST)
+			w.WriteHeader(422)
+
+			err = t.ExecuteTemplate(w, "not-in-word-list", notInWordListData{guessedWord, s.uiLocale})
+			if err != nil {
+				slog.ErrorContext(r.Context(), "executing template failed", "route", "/rooms/{code}/guess", "err", err)
+			}
+			return
+		}
+
+		err = rm.Guess(s.id, guessedWord)
+		if err != nil && !errors.Is(err, ErrRoundOver) {
+			slog.ErrorContext(r.Context(), "guessing in room failed", "err", err)
+		}
+
+		renderRoom(w, r, rm, s, "/rooms/{code}/guess")
+	})
+
+	mux.HandleFunc("POST /rooms/{code}/rematch", func(w http.ResponseWriter, r *http.Request) {
+		s := sessionFor(w, r)
+
+		rm, ok := roomOf(w, r, s)
+		if !ok {
+			return
+		}
+
+		err := rm.Rematch(s.id, hub.wdb)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "requesting rematch failed", "err", err)
+		}
+
+		renderRoom(w, r, rm, s, "/rooms/{code}/rematch")
+	})
+
+	mux.HandleFunc("POST /rooms/{code}/leave", func(w http.ResponseWriter, r *http.Request) {
+		s := sessionFor(w, r)
+
+		err := hub.Leave(r.PathValue("code"), s.id)
+		if err != nil && !errors.Is(err, ErrRoomNotFound) {
+			slog.ErrorContext(r.Context(), "leaving room failed", "err", err)
+		}
+
+		// back to the solo game
+		w.Header().Set("HX-Redirect", "/")
+		w.WriteHeader(204)
+	})
+
+	mux.HandleFunc("GET /rooms/{code}/events", func(w http.ResponseWriter, r *http.Request) {
+		s := sessionFor(w, r)
+
+		rm, ok := roomOf(w, r, s)
+		if !ok {
+			return
+		}
+
+		streamRoomEvents(w, r, t, rm, s)
+	})
+}
+
+// streamRoomEvents pushes the opponents' progress as server-sent events until
+// the client disconnects. "update" carries the rendered "room-opponents"
+// fragment, "reload" tells the client to fetch the whole room again, which
+// happens whenever a round ends or a rematch starts.
+func streamRoomEvents(w http.ResponseWriter, r *http.Request, t *template.Template, rm *room, s session) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	updates, cancel := rm.Subscribe()
+	defer cancel()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	lastState := ""
+	for {
+		v, err := rm.View(s.id, s.uiLocale)
+		if err != nil {
+			// left the room, e.g. from another tab
+			return
+		}
+
+		if lastState != "" && lastState != v.roomState() {
+			fmt.Fprintf(w, "event: reload\ndata: %s\n\n", v.roomState())
+		}
+		lastState = v.roomState()
+
+		var sb strings.Builder
+		err = t.ExecuteTemplate(&sb, "room-opponents", v)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "executing template failed", "route", "/rooms/{code}/events", "err", err)
+			return
+		}
+		writeSSE(w, "update", sb.String())
+		flusher.Flush()
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-updates:
+		}
+	}
+}
+
+// writeSSE writes one event, every line of data needs its own "data:" field.
+func writeSSE(w http.ResponseWriter, event string, data string) {
+	fmt.Fprintf(w, "event: %s\n", event)
+	for _, line := range strings.Split(data, "\n") {
+		fmt.Fprintf(w, "data: %s\n", line)
+	}
+	fmt.Fprint(w, "\n")
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestRoomHub(t *testing.T) *roomHub {
+	t.Helper()
+
+	wdb := wordDatabase{}
+	wdb.Init(nil, nil)
+	wdb.Replace(LANG_EN, WC_ALL, []word{{'m', 'a', 't', 'c', 'h'}})
+
+	return newRoomHub(wdb)
+}
+
+func Test_roomHub_joinAndLeave(t *testing.T) {
+	hub := newTestRoomHub(t)
+
+	rm, err := hub.Create("host", "Host", LANG_EN)
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if len(rm.Code()) != ROOM_CODE_LENGTH {
+		t.Errorf("Create() code = %q", rm.Code())
+	}
+
+	if _, err := hub.Join(strings.ToLower(rm.Code())+" ", "guest", "Guest"); err != nil {
+		t.Errorf("Join() with lower case code error = %v", err)
+	}
+	if _, err := hub.Join(rm.Code(), "guest", "Guest"); err != nil {
+		t.Errorf("Join() twice error = %v", err)
+	}
+	if _, err := hub.Join(rm.Code(), "third", "Third"); !errors.Is(err, ErrRoomFull) {
+		t.Errorf("Join() of a full room error = %v, want %v", err, ErrRoomFull)
+	}
+	if _, err := hub.Join("NOPE1", "guest", "Guest"); !errors.Is(err, ErrRoomNotFound) {
+		t.Errorf("Join() of an unknown room error = %v, want %v", err, ErrRoomNotFound)
+	}
+
+	hub.Leave(rm.Code(), "guest")
+	if rm.Has("guest") {
+		t.Errorf("Leave() should remove the player")
+	}
+
+	hub.Leave(rm.Code(), "host")
+	if _, err := hub.Get(rm.Code()); !errors.Is(err, ErrRoomNotFound) {
+		t.Errorf("Leave() of the last player should close the room, got %v", err)
+	}
+}
+
+func Test_room_raceAndRematch(t *testing.T) {
+	hub := newTestRoomHub(t)
+	rm, _ := hub.Create("host", "Host", LANG_EN)
+	hub.Join(rm.Code(), "guest", "Guest")
+
+	if err := rm.Guess("guest", word{'h', 'a', 't', 'c', 'h'}); err != nil {
+		t.Fatalf("Guess() error = %v", err)
+	}
+
+	v, _ := rm.View("host", "en")
+	opponent := v.Opponents[0].Attempt.Guesses[0]
+	for _, lg := range opponent {
+		if lg.Letter != 0 {
+			t.Fatalf("View() must not reveal the letters of opponents, got %v", opponent)
+		}
+	}
+	if opponent[1].Match != MatchExact || opponent[0].Match != MatchNone || v.Opponents[0].Attempts != 1 {
+		t.Errorf("View() should keep the colours of opponents, got %v", opponent)
+	}
+	if v.Solution != (word{}) {
+		t.Errorf("View() must not reveal the solution of a running round")
+	}
+
+	rm.Guess("host", word{'m', 'a', 't', 'c', 'h'})
+	if err := rm.Guess("guest", word{'m', 'a', 't', 'c', 'h'}); !errors.Is(err, ErrRoundOver) {
+		t.Errorf("Guess() after the round is over error = %v, want %v", err, ErrRoundOver)
+	}
+
+	v, _ = rm.View("guest", "en")
+	if !v.IsOver || v.IsWinner || v.Winner != "Host" || v.Solution != (word{'m', 'a', 't', 'c', 'h'}) {
+		t.Errorf("View() after the win = %+v", v)
+	}
+
+	rm.Rematch("host", hub.wdb)
+	if v, _ := rm.View("host", "en"); v.Round != 1 || !v.Me.Rematch {
+		t.Errorf("Rematch() should wait for all players, got round %d", v.Round)
+	}
+
+	rm.Rematch("guest", hub.wdb)
+	v, _ = rm.View("host", "en")
+	if v.Round != 2 || v.IsOver || v.Me.Attempts != 0 || v.Me.Wins != 1 {
+		t.Errorf("Rematch() of all players should start the next round, got %+v", v)
+	}
+}
+
+func Test_streamRoomEvents(t *testing.T) {
+	hub := newTestRoomHub(t)
+	rm, _ := hub.Create("host", "Host", LANG_EN)
+
+	tmpl := template.Must(template.New("rooms").Funcs(funcMap).Funcs(translateFuncs(translations, true)).ParseFS(fs, templateFiles...))
+
+	mux := http.NewServeMux()
+	registerRoomRoutes(mux, tmpl, hub, func(w http.ResponseWriter, r *http.Request) session {
+		return session{id: r.Header.Get("X-Session"), uiLocale: "en"}
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/rooms/"+rm.Code()+"/events", nil)
+	req.Header.Set("X-Session", "host")
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("connecting to the event stream failed: %s", err)
+	}
+	defer res.Body.Close()
+
+	if ct := res.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Content-Type = %q", ct)
+	}
+
+	events := bufio.NewScanner(res.Body)
+	next := func() (event string, data string) {
+		for events.Scan() {
+			line := events.Text()
+			switch {
+			case line == "":
+				return event, data
+			case strings.HasPrefix(line, "event: "):
+				event = strings.TrimPrefix(line, "event: ")
+			case strings.HasPrefix(line, "data: "):
+				data += strings.TrimPrefix(line, "data: ") + "\n"
+			}
+		}
+		t.Fatalf("event stream ended: %v", events.Err())
+		return "", ""
+	}
+
+	if event, data := next(); event != "update" || !strings.Contains(data, "waiting for an opponent") {
+		t.Errorf("first event = %q, %q", event, data)
+	}
+
+	hub.Join(rm.Code(), "guest", "Guest")
+	if event, data := next(); event != "update" || !strings.Contains(data, "Guest") {
+		t.Errorf("event after join = %q, %q", event, data)
+	}
+
+	rm.Guess("guest", word{'m', 'a', 't', 'c', 'h'})
+	if event, _ := next(); event != "reload" {
+		t.Errorf("event after the win = %q, want reload", event)
+	}
+	if event, data := next(); event != "update" || strings.Contains(strings.ToLower(data), ">m<") {
+		t.Errorf("update after the win must not reveal letters, got %q, %q", event, data)
+	}
+
+	forbidden, _ := http.NewRequest(http.MethodGet, srv.URL+"/rooms/"+rm.Code()+"/events", nil)
+	forbidden.Header.Set("X-Session", "stranger")
+	res2, err := http.DefaultClient.Do(forbidden)
+	if err != nil {
+		t.Fatalf("request failed: %s", err)
+	}
+	res2.Body.Close()
+	if res2.StatusCode != http.StatusForbidden {
+		t.Errorf("stream of a stranger status = %d, want %d", res2.StatusCode, http.StatusForbidden)
+	}
+}
@@ -16,17 +16,9 @@ fi
 
 # -----------------------------------------------------------------------------
 
-docker compose down -t 1
+DATASETS_DIR=./tmp/datasets
 
-docker compose build
+mkdir -p "${DATASETS_DIR}"
+./container-images/corpora/db/datasets_download.sh "${DATASETS_DIR}"
 
-docker compose up -d
-
-# ensure db connection
-docker compose exec -T mariadb bash -c "MYSQL_HOST=mariadb MYSQL_USER=root MYSQL_PASSWORD=example MYSQL_PORT=3306 /scripts/check_db_con.sh"
-
-#...
-docker compose exec -T mariadb bash -c "/scripts/import.sh /datasets"
-docker compose exec -T mariadb bash -c "/scripts/export.sh /datasets /tmp/dumps"
-
-docker compose down -t 1
+go run . corpora export -datasets "${DATASETS_DIR}" -out ./configs
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/pandorasNox/lettr/pkg/middleware"
+)
+
+const CSP_REPORT_ROUTE = "/csp-report"
+
+// contentSecurityPolicy allows scripts and styles of the own origin, inline
+// ones need the nonce of the request (see FormData.CSPNonce).
+const contentSecurityPolicy = "default-src 'self'; " +
+	"script-src 'self' 'nonce-" + middleware.CSP_NONCE_PLACEHOLDER + "'; " +
+	"style-src 'self' 'nonce-" + middleware.CSP_NONCE_PLACEHOLDER + "'; " +
+	"img-src 'self' data:; " +
+	"connect-src 'self'; " +
+	"object-src 'none'; " +
+	"base-uri 'self'; " +
+	"form-action 'self'; " +
+	"frame-ancestors 'none'"
+
+// jsonRoutePolicy is for routes which never render html.
+const jsonRoutePolicy = "default-src 'none'; frame-ancestors 'none'"
+
+func securityHeadersConfig(reportOnly bool, mux *http.ServeMux) middleware.SecurityHeadersConfig {
+	return middleware.SecurityHeadersConfig{
+		CSP:        contentSecurityPolicy,
+		ReportOnly: reportOnly,
+		ReportURI:  CSP_REPORT_ROUTE,
+		Headers: map[string]string{
+			"Strict-Transport-Security": "max-age=63072000; includeSubDomains",
+			"X-Content-Type-Options":    "nosniff",
+			"Referrer-Policy":           "strict-origin-when-cross-origin",
+			"X-Frame-Options":           "DENY", // for browsers without frame-ancestors
+			"Permissions-Policy":        "camera=(), microphone=(), geolocation=(), payment=(), usb=()",
+		},
+		Overrides: map[string]map[string]string{
+			"GET /healthz": {middleware.CSP_HEADER: jsonRoutePolicy},
+			"GET /readyz":  {middleware.CSP_HEADER: jsonRoutePolicy},
+			"GET /version": {middleware.CSP_HEADER: jsonRoutePolicy},
+			"GET /metrics": {middleware.CSP_HEADER: jsonRoutePolicy},
+		},
+		Route: func(r *http.Request) string {
+			_, route := mux.Handler(r)
+			return route
+		},
+	}
+}
+
+// csrfSessionID ties csrf tokens to the session cookie.
+func csrfSessionID(r *http.Request) string {
+	c, err := r.Cookie(SESSION_COOKIE_NAME)
+	if err != nil {
+		return ""
+	}
+
+	return c.Value
+}
+
+// csrfExempt skips csrf checks for violation reports, browsers send them
+// without a token. The only bearer authenticated route, GET /metrics, is safe
+// and never checked.
+func csrfExempt(r *http.Request, mux *http.ServeMux) bool {
+	_, route := mux.Handler(r)
+	return route == "POST "+CSP_REPORT_ROUTE
+}
+
+func registerSecurityRoutes(mux *http.ServeMux) {
+	mux.Handle("POST "+CSP_REPORT_ROUTE, middleware.NewCSPReportHandler(func(r *http.Request, report middleware.CSPReport) {
+		slog.WarnContext(r.Context(), "csp violation",
+			"document", report.DocumentURI,
+			"blocked", report.BlockedURI,
+			"directive", report.EffectiveDirective,
+			"disposition", report.Disposition,
+			"source", report.SourceFile,
+			"line", report.LineNumber,
+		)
+	}))
+}
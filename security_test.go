@@ -0,0 +1,102 @@
+package main
+
+import (
+	"html/template"
+	iofs "io/fs"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/pandorasNox/lettr/pkg/middleware"
+)
+
+// inline event handlers can't carry a nonce, the policy blocks them
+func Test_templates_noInlineHandlers(t *testing.T) {
+	handlerRe := regexp.MustCompile(`\son[a-z]+\s*=`)
+
+	for _, f := range templateFiles {
+		b, err := iofs.ReadFile(fs, f)
+		if err != nil {
+			t.Fatalf("reading template failed: %s", err)
+		}
+
+		if m := handlerRe.FindString(string(b)); m != "" {
+			t.Errorf("template '%s' has inline event handler '%s'", f, strings.TrimSpace(m))
+		}
+	}
+}
+
+func Test_securityHeaders_index(t *testing.T) {
+	tmpl, err := template.New("index.html.tmpl").Funcs(funcMap).Funcs(translateFuncs(translations, false)).ParseFS(fs, templateFiles...)
+	if err != nil {
+		t.Fatalf("parsing templates failed: %s", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", func(w http.ResponseWriter, r *http.Request) {
+		fData := FormData{}.New(session{language: LANG_EN, uiLocale: translations.DefaultLocale()}, puzzle{})
+		fData.CSPNonce = middleware.CSPNonce(r.Context())
+		fData.CSRFToken = "csrf-token"
+		if err := tmpl.ExecuteTemplate(w, "index.html.tmpl", fData); err != nil {
+			t.Errorf("executing template failed: %s", err)
+		}
+	})
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {})
+	h := middleware.NewSecurityHeaders(mux, securityHeadersConfig(false, mux))
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	nonce := regexp.MustCompile(`'nonce-([^']+)'`).FindStringSubmatch(w.Header().Get(middleware.CSP_HEADER))
+	if nonce == nil {
+		t.Fatalf("CSP %q has no nonce", w.Header().Get(middleware.CSP_HEADER))
+	}
+	body := w.Body.String()
+	if scripts, withNonce := strings.Count(body, "<script "), strings.Count(body, `<script nonce="`+nonce[1]+`"`); scripts == 0 || scripts != withNonce {
+		t.Errorf("%d of %d scripts have the nonce %q", withNonce, scripts, nonce[1])
+	}
+	if !strings.Contains(body, `hx-headers='{"X-CSRF-Token": "csrf-token"}'`) {
+		t.Errorf("body misses the csrf token in hx-headers")
+	}
+	if w.Header().Get("Strict-Transport-Security") == "" || w.Header().Get("X-Content-Type-Options") != "nosniff" {
+		t.Errorf("headers = %v, want HSTS and nosniff", w.Header())
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if got := w.Header().Get(middleware.CSP_HEADER); !strings.HasPrefix(got, jsonRoutePolicy) {
+		t.Errorf("/healthz CSP = %q, want %q", got, jsonRoutePolicy)
+	}
+}
+
+func Test_csrfExempt(t *testing.T) {
+	mux := http.NewServeMux()
+	registerSecurityRoutes(mux)
+	mux.HandleFunc("POST /lettr", func(w http.ResponseWriter, r *http.Request) {})
+
+	tests := []struct {
+		name          string
+		path          string
+		authorization string
+		want          bool
+	}{
+		{"game", "/lettr", "", false},
+		{"basic auth", "/lettr", "Basic YWRtaW46YWRtaW4=", false},
+		{"bearer token", "/lettr", "Bearer token", false},
+		{"csp report", CSP_REPORT_ROUTE, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, tt.path, nil)
+			if tt.authorization != "" {
+				r.Header.Set("Authorization", tt.authorization)
+			}
+
+			if got := csrfExempt(r, mux); got != tt.want {
+				t.Errorf("csrfExempt() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
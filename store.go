@@ -0,0 +1,77 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// jsonFile persists a whole state as one json file in dir. Without a dir
+// nothing is read or written, the state lives in memory only.
+type jsonFile struct {
+	dir  string
+	name string
+}
+
+// Load decodes the file into v, a missing file leaves v as it is.
+func (jf jsonFile) Load(v any) error {
+	if jf.dir == "" {
+		return nil
+	}
+
+	b, err := os.ReadFile(filepath.Join(jf.dir, jf.name))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed reading file: %s", err)
+	}
+
+	if err := json.Unmarshal(b, v); err != nil {
+		return fmt.Errorf("failed decoding file: %s", err)
+	}
+
+	return nil
+}
+
+// Save encodes v and replaces the file, see writeFileAtomic.
+func (jf jsonFile) Save(v any) error {
+	if jf.dir == "" {
+		return nil
+	}
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed encoding state: %s", err)
+	}
+
+	return writeFileAtomic(jf.dir, jf.name, b)
+}
+
+// writeFileAtomic writes to a temp file first and renames it afterwards, so
+// readers never see partial files.
+func writeFileAtomic(dir string, name string, data []byte) error {
+	tmp, err := os.CreateTemp(dir, name+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed creating temp file: %s", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed writing temp file: %s", err)
+	}
+
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed closing temp file: %s", err)
+	}
+
+	path := filepath.Join(dir, name)
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed renaming temp file: path='%s', err=%s", path, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func Test_jsonFile(t *testing.T) {
+	dir := t.TempDir()
+	jf := jsonFile{dir, "state.json"}
+
+	got := map[string]int{"kept": 1}
+	if err := jf.Load(&got); err != nil || !reflect.DeepEqual(got, map[string]int{"kept": 1}) {
+		t.Fatalf("Load() of missing file = %v, %v, want the state untouched", got, err)
+	}
+
+	if err := jf.Save(map[string]int{"a": 1, "b": 2}); err != nil {
+		t.Fatalf("Save() failed: %s", err)
+	}
+
+	got = map[string]int{}
+	if err := jf.Load(&got); err != nil || !reflect.DeepEqual(got, map[string]int{"a": 1, "b": 2}) {
+		t.Errorf("Load() = %v, %v, want the saved state", got, err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "state.json"), []byte("{"), 0o644); err != nil {
+		t.Fatalf("writing fixture failed: %s", err)
+	}
+	if err := jf.Load(&got); err == nil {
+		t.Errorf("Load() of a broken file should fail")
+	}
+
+	if err := (jsonFile{}).Save(got); err != nil {
+		t.Errorf("Save() without a dir should be a no-op, got %s", err)
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/pandorasNox/lettr/pkg/tracing"
+)
+
+// newTracer builds the tracer from OTEL_TRACES_EXPORTER, nil while tracing is
+// disabled (the default).
+func newTracer(envCfg env) (*tracing.Tracer, error) {
+	res := tracing.Resource{ServiceName: cmp.Or(envCfg.otelServiceName, "lettr"), ServiceVersion: Revision}
+
+	var exporter tracing.Exporter
+	switch envCfg.tracesExporter {
+	case "", "none":
+		return nil, nil
+	case "console", "stdout":
+		exporter = tracing.NewStdoutExporter(os.Stdout, res)
+	case "otlp":
+		headers, err := tracing.ParseHeaders(envCfg.otlpHeaders)
+		if err != nil {
+			return nil, fmt.Errorf("invalid OTEL_EXPORTER_OTLP_HEADERS: %s", err)
+		}
+		exporter = tracing.NewOTLPExporter(otlpTracesURL(envCfg), headers, res)
+	default:
+		return nil, fmt.Errorf("invalid traces exporter '%s'", envCfg.tracesExporter)
+	}
+
+	return tracing.New(exporter, func(err error) {
+		slog.Warn("exporting spans failed", "err", err)
+	}), nil
+}
+
+// otlpTracesURL follows the OTel conventions: the traces endpoint is used as
+// is, the generic one gets "/v1/traces" appended.
+func otlpTracesURL(envCfg env) string {
+	if envCfg.otlpTracesEndpoint != "" {
+		return envCfg.otlpTracesEndpoint
+	}
+
+	return strings.TrimSuffix(cmp.Or(envCfg.otlpEndpoint, "http://localhost:4318"), "/") + "/v1/traces"
+}
+
+// traceRequests starts a server span per request, named by the route pattern
+// and continuing the trace of an incoming traceparent header.
+func traceRequests(h http.Handler, mux *http.ServeMux) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, route := mux.Handler(r)
+
+		ctx, span := tracing.Default().Start(tracing.Extract(r.Context(), r.Header), cmp.Or(route, r.Method), tracing.KIND_SERVER,
+			tracing.String("http.request.method", r.Method),
+			tracing.String("http.route", route),
+			tracing.String("url.path", r.URL.Path),
+		)
+		defer span.End()
+
+		sr := &statusRecorder{ResponseWriter: w}
+		h.ServeHTTP(sr, r.WithContext(ctx))
+
+		span.SetAttributes(tracing.Int("http.response.status_code", sr.status()))
+		if sr.status() >= 500 {
+			span.SetStatus(tracing.STATUS_ERROR, http.StatusText(sr.status()))
+		}
+	})
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/pandorasNox/lettr/pkg/tracing"
+)
+
+type recordingExporter struct {
+	mu    sync.Mutex
+	spans []tracing.SpanData
+}
+
+func (e *recordingExporter) Export(ctx context.Context, spans []tracing.SpanData) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.spans = append(e.spans, spans...)
+	return nil
+}
+
+func Test_newTracer(t *testing.T) {
+	tests := []struct {
+		name    string
+		envCfg  env
+		wantNil bool
+		wantErr bool
+	}{
+		{"disabled by default", env{}, true, false},
+		{"none", env{tracesExporter: "none"}, true, false},
+		{"console", env{tracesExporter: "console"}, false, false},
+		{"otlp", env{tracesExporter: "otlp", otlpHeaders: "Authorization=Bearer x"}, false, false},
+		{"otlp invalid headers", env{tracesExporter: "otlp", otlpHeaders: "nope"}, true, true},
+		{"unknown exporter", env{tracesExporter: "jaeger"}, true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := newTracer(tt.envCfg)
+			defer got.Shutdown()
+
+			if (err != nil) != tt.wantErr || (got == nil) != tt.wantNil {
+				t.Errorf("newTracer() = %v, %v; want nil %v, wantErr %v", got, err, tt.wantNil, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_otlpTracesURL(t *testing.T) {
+	tests := []struct {
+		envCfg env
+		want   string
+	}{
+		{env{}, "http://localhost:4318/v1/traces"},
+		{env{otlpEndpoint: "https://otel.example.com/"}, "https://otel.example.com/v1/traces"},
+		{env{otlpEndpoint: "https://otel.example.com", otlpTracesEndpoint: "https://traces.example.com/in"}, "https://traces.example.com/in"},
+	}
+	for _, tt := range tests {
+		if got := otlpTracesURL(tt.envCfg); got != tt.want {
+			t.Errorf("otlpTracesURL(%+v) = %v, want %v", tt.envCfg, got, tt.want)
+		}
+	}
+}
+
+func Test_traceRequests(t *testing.T) {
+	e := &recordingExporter{}
+	tracer := tracing.New(e, nil)
+	defer tracer.Shutdown()
+	defer tracing.SetDefault(tracing.Default())
+	tracing.SetDefault(tracer)
+
+	buf := &bytes.Buffer{}
+	l, err := newLogger(buf, "", "json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /history/{id}", func(w http.ResponseWriter, r *http.Request) {
+		_, span := tracing.Start(r.Context(), "inner")
+		span.End()
+
+		l.InfoContext(r.Context(), "in handler")
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	r := httptest.NewRequest(http.MethodGet, "/history/abc", nil)
+	r.Header.Set(tracing.TRACEPARENT_HEADER, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	traceRequests(mux, mux).ServeHTTP(httptest.NewRecorder(), r)
+	tracer.Flush()
+
+	if len(e.spans) != 2 {
+		t.Fatalf("exported %d spans, want 2", len(e.spans))
+	}
+
+	inner, server := e.spans[0], e.spans[1]
+	if server.Name != "GET /history/{id}" || server.Kind != tracing.KIND_SERVER || server.Status != tracing.STATUS_ERROR {
+		t.Errorf("server span = %s kind %d status %d, want route, server kind and error status", server.Name, server.Kind, server.Status)
+	}
+	if server.SpanContext.TraceID.String() != "4bf92f3577b34da6a3ce929d0e0e4736" || server.Parent.String() != "00f067aa0ba902b7" {
+		t.Errorf("server span doesn't continue the incoming trace: %v parent %s", server.SpanContext, server.Parent)
+	}
+	if inner.Parent != server.SpanContext.SpanID {
+		t.Errorf("inner span parent = %s, want %s", inner.Parent, server.SpanContext.SpanID)
+	}
+
+	line := logLine(t, buf)
+	if line["trace_id"] != server.SpanContext.TraceID.String() || line["span_id"] != server.SpanContext.SpanID.String() {
+		t.Errorf("log line trace_id/span_id = %v/%v, want %s/%s", line["trace_id"], line["span_id"], server.SpanContext.TraceID, server.SpanContext.SpanID)
+	}
+}
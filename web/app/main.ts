@@ -24,6 +24,8 @@ interface CustomHtmxEvent<T = any> extends Event {
 
             themeButtonToggleHandler();
             initKeyListener(state);
+            initDataHandlers();
+            initCountdown();
             document.addEventListener('htmx:afterSettle', (event: CustomHtmxEvent) => {reset(state, event)}, false);
             document.addEventListener('htmx:afterSettle', (event: CustomHtmxEvent) => {onErrorMsg(event)}, false);
         }, false);
@@ -53,10 +55,12 @@ interface CustomHtmxEvent<T = any> extends Event {
             return
         }
 
+        // give players time to hit the suggest button for unknown words
+        const hasSuggestion = errorsElem.querySelector(".suggest-word") !== null
         const intervalID = setInterval(function() {
             errorsElem.innerHTML = "";
             clearInterval(intervalID);
-        }, 2000);
+        }, hasSuggestion ? 6000 : 2000);
     }
 
     function initalThemeHandler() {
@@ -127,6 +131,13 @@ interface CustomHtmxEvent<T = any> extends Event {
                 updateInput(state);
             }
 
+            // e.g. german "ä" is played as "ae", the server expects the expanded letters
+            const expansion = isSingleKey ? letterExpansions().get(e.key.toLowerCase()) : undefined
+            if (expansion !== undefined && state.letters.length + expansion.length <= state.inputs.length) {
+                state.letters.push(...expansion.split(''));
+                updateInput(state);
+            }
+
             if (e.key === "Backspace" || e.key === "Delete") {
                 state.letters.pop();
                 updateInput(state);
@@ -139,7 +150,7 @@ interface CustomHtmxEvent<T = any> extends Event {
                     return
                 }
 
-                const form = <HTMLFormElement|null>document.querySelector("#lettr-container form")
+                const form = <HTMLFormElement|null>document.querySelector("#lettr-container form[name=lettr]")
                 if (form === null) {
                     return
                 }
@@ -149,11 +160,71 @@ interface CustomHtmxEvent<T = any> extends Event {
                     return
                 }
 
-                htmx.trigger("#lettr-container form", "submit");
+                htmx.trigger("#lettr-container form[name=lettr]", "submit");
+            }
+        });
+    }
+
+    // initDataHandlers replaces inline event handlers (onclick etc.), which the
+    // content security policy blocks. Delegated, so swapped in content works too.
+    function initDataHandlers(): void {
+        document.addEventListener('click', (e: MouseEvent) => {
+            const target = e.target as HTMLElement
+
+            const selectable = target.closest<HTMLInputElement>("input[data-select-on-click]")
+            if (selectable !== null) {
+                selectable.select();
+            }
+
+            const key = target.closest<HTMLElement>("[data-key]")
+            if (key !== null) {
+                document.dispatchEvent(new KeyboardEvent('keyup', {'key': key.dataset.key}));
+            }
+        });
+
+        document.addEventListener('submit', (e: SubmitEvent) => {
+            if ((e.target as HTMLElement).matches("form[data-prevent-submit]")) {
+                e.preventDefault();
             }
         });
     }
 
+    // initCountdown only displays the time left, the server decides when time is up.
+    // Once the countdown runs out the form is reloaded to show the server side result.
+    function initCountdown(): void {
+        setInterval(function() {
+            document.querySelectorAll<HTMLElement>(".countdown[data-remaining]").forEach((elem: HTMLElement) => {
+                // relative to the local clock, client and server clocks may differ
+                if (elem.dataset.deadline === undefined) {
+                    elem.dataset.deadline = String(Date.now() + Number(elem.dataset.remaining));
+                }
+
+                const remaining = Math.max(0, Number(elem.dataset.deadline) - Date.now());
+                const secs = Math.ceil(remaining / 1000);
+                elem.textContent = Math.floor(secs / 60) + ":" + (secs % 60 < 10 ? "0" : "") + (secs % 60);
+
+                if (remaining === 0 && elem.dataset.expired === undefined) {
+                    elem.dataset.expired = "true";
+                    htmx.ajax("GET", "/lettr", {target: "#lettr-container"});
+                }
+            });
+        }, 1000);
+    }
+
+    // letterExpansions reads the "ä:ae ß:ss" style mapping of the current language from the form
+    function letterExpansions(): Map<string, string> {
+        const expansions = new Map<string, string>();
+
+        const form = <HTMLFormElement|null>document.querySelector("#lettr-container form[name=lettr]")
+        const raw = form?.dataset.expand ?? ""
+        raw.split(" ").filter((pair) => pair !== "").forEach((pair: string) => {
+            const [letter, replacement] = pair.split(":");
+            expansions.set(letter, replacement);
+        });
+
+        return expansions;
+    }
+
     function updateInput(state: State): void {
         state.inputs.forEach((input: HTMLInputElement, index: number) => {
             state.inputs[index].value = state.letters[index] ?? '';
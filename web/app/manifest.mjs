@@ -0,0 +1,56 @@
+// Fingerprints the files of static/generated and writes
+// static/generated/manifest.json ({"main.js": "main.1a2b3c4d.js"}, like
+// webpack-assets-manifest), which the server reads to link them. Scripts and
+// styles of static/generated and static/npm get precompressed gzip and
+// brotli variants, served by Accept-Encoding.
+//
+// Runs after tailwindcss, tsc and `npm run copy-npm-assets`: `npm run manifest`.
+
+import { createHash } from 'node:crypto';
+import { existsSync, readdirSync, readFileSync, unlinkSync, writeFileSync } from 'node:fs';
+import { extname, join } from 'node:path';
+import { constants, brotliCompressSync, gzipSync } from 'node:zlib';
+
+const generatedDir = 'static/generated';
+const compressDirs = [generatedDir, 'static/npm'];
+const compressExts = ['.js', '.css'];
+
+// see npmAssets in assets.go, the server doesn't start without them
+const npmAssets = ['htmx.min.js', 'response-targets.js', 'sse.js'];
+
+const isFingerprinted = (name) => /\.[0-9a-f]{8}\.[a-z]+$/.test(name);
+const isOutput = (name) => name === 'manifest.json' || name.endsWith('.gz') || name.endsWith('.br') || isFingerprinted(name);
+
+const missing = npmAssets.filter((name) => !existsSync(join('static/npm', name)));
+if (missing.length > 0) {
+    console.error(`npm assets missing in static/npm: ${missing.join(', ')}, run \`npm run copy-npm-assets\``);
+    process.exit(1);
+}
+
+// outputs of earlier builds
+for (const dir of compressDirs) {
+    readdirSync(dir).filter(isOutput).forEach((name) => unlinkSync(join(dir, name)));
+}
+
+const manifest = {};
+for (const name of readdirSync(generatedDir).sort()) {
+    const content = readFileSync(join(generatedDir, name));
+    // the same hash the server falls back to without a manifest
+    const hash = createHash('sha256').update(content).digest('hex').slice(0, 8);
+    const ext = extname(name);
+    const fingerprinted = name.slice(0, name.length - ext.length) + '.' + hash + ext;
+
+    writeFileSync(join(generatedDir, fingerprinted), content);
+    manifest[name] = fingerprinted;
+}
+writeFileSync(join(generatedDir, 'manifest.json'), JSON.stringify(manifest, null, 2) + '\n');
+
+for (const dir of compressDirs) {
+    for (const name of readdirSync(dir).filter((name) => compressExts.includes(extname(name)))) {
+        const content = readFileSync(join(dir, name));
+        writeFileSync(join(dir, name + '.gz'), gzipSync(content, { level: 9 }));
+        writeFileSync(join(dir, name + '.br'), brotliCompressSync(content, {
+            params: { [constants.BROTLI_PARAM_QUALITY]: constants.BROTLI_MAX_QUALITY },
+        }));
+    }
+}